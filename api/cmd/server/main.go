@@ -19,11 +19,16 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
-	"github.com/gin-gonic/gin"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -33,6 +38,8 @@ import (
 	"github.com/valu/vemeet-admin-api/internal/auth"
 	"github.com/valu/vemeet-admin-api/internal/config"
 	"github.com/valu/vemeet-admin-api/internal/data"
+	"github.com/valu/vemeet-admin-api/internal/notifier"
+	"github.com/valu/vemeet-admin-api/internal/oidc"
 	"github.com/valu/vemeet-admin-api/internal/services"
 )
 
@@ -40,13 +47,16 @@ func main() {
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 	log.Logger = log.With().Caller().Logger()
 
-	r := gin.Default()
-
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to load config")
 	}
 
+	r, err := routes.NewEngine(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize the router")
+	}
+
 	db, err := initDatabase(cfg.DbUrl)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to initialize database")
@@ -57,25 +67,123 @@ func main() {
 	adminData := data.NewAdminRepository(db)
 	userData := data.NewUserRepository(db)
 	blockedData := data.NewBlockedRepository(db)
+	inviteData := data.NewInviteRepository(db)
+	sessionData := data.NewSessionRepository(db)
+	mfaData := data.NewMfaRepository(db)
+	loginAttemptData := data.NewLoginAttemptRepository(db)
+	passwordData := data.NewPasswordRepository(db)
+	oidcStateData := data.NewOidcStateRepository(db)
+	apiKeyData := data.NewApiKeyRepository(db)
+	auditData := data.NewAuditRepository(db)
+	banReasonData := data.NewBanReasonRepository(db)
+	escalationData := data.NewEscalationRepository(db)
+	appealData := data.NewAppealRepository(db)
+	reportData := data.NewReportRepository(db)
+	restrictionData := data.NewRestrictionRepository(db)
+	moderationJobData := data.NewModerationJobRepository(db)
+	unitOfWork := data.NewUnitOfWork(db)
+
+	keyring, err := initKeyring(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load PASETO keys")
+	}
+	tokenManager, err := auth.NewTokenManager(keyring, auth.TokenConfig{
+		Mode:     cfg.TokenMode,
+		Issuer:   cfg.TokenIssuer,
+		Audience: cfg.TokenAudience,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize token manager")
+	}
+
+	passwordPolicy, err := services.NewPasswordPolicy(cfg.PasswordMinLength, cfg.PasswordHistorySize, cfg.PasswordDenylistFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load password policy")
+	}
+
+	notify, err := notifier.New(cfg.NotifierKind, cfg.NotifierFilePath)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize notifier")
+	}
+
+	sessionService := services.NewSessionService(sessionData)
+	adminService := services.NewAdminService(adminData, sessionService, passwordPolicy)
+	inviteService := services.NewInviteService(inviteData, adminData, passwordPolicy)
+	loginGuardService := services.NewLoginGuardService(loginAttemptData, adminData, services.LoginGuardConfig{
+		MaxFailedAttempts:      cfg.LoginMaxFailedAttempts,
+		MaxFailedAttemptsPerIP: cfg.LoginMaxFailedAttemptsPerIP,
+		LockoutDuration:        cfg.LoginLockoutDuration,
+		MaxResetsPerEmail:      cfg.PasswordResetMaxPerEmail,
+		MaxResetsPerIP:         cfg.PasswordResetMaxPerIP,
+		ResetWindow:            cfg.PasswordResetWindow,
+	})
+	passwordService := services.NewPasswordService(passwordData, adminData, sessionService, loginGuardService, notify, passwordPolicy)
+	mfaService := services.NewMfaService(mfaData, adminData)
 
-	tokenManager := auth.NewTokenManager(cfg.PasetoSecret)
-	adminService := services.NewAdminService(adminData)
+	bootstrapAdmin(cfg, adminService)
 
-	authService := services.NewAuthService(adminData, *tokenManager)
-	userService := services.NewUserService(userData)
-	blockedService := services.NewBlockedService(blockedData)
+	authService := services.NewAuthService(adminData, sessionData, sessionService, mfaService, loginGuardService, *tokenManager)
+	oidcService, err := initOidc(cfg, oidcStateData, adminData, authService)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize SSO login")
+	}
+	apiKeyService := services.NewApiKeyService(apiKeyData, adminData)
+	auditService := services.NewAuditService(auditData)
+	userService := services.NewUserService(userData, blockedData, restrictionData)
+	blockedService := services.NewBlockedService(blockedData, banReasonData, escalationData, unitOfWork)
+	banReasonService := services.NewBanReasonService(banReasonData, escalationData)
+	appealService := services.NewAppealService(appealData, blockedData, adminData, unitOfWork)
+	restrictionService := services.NewRestrictionService(restrictionData, banReasonData, unitOfWork)
+	reportService := services.NewReportService(reportData, userData, blockedService, unitOfWork)
+	bulkModerationService := services.NewBulkModerationService(userData, blockedData, banReasonData, moderationJobData, blockedService, restrictionService, auditService, services.BulkModerationConfig{
+		BatchSize: cfg.BulkModerationBatchSize,
+		SyncLimit: cfg.BulkModerationSyncLimit,
+		MaxItems:  cfg.BulkModerationMaxItems,
+	})
 
-	adminHandler := handlers.NewAdminHandler(adminService)
-	authHandler := handlers.NewAuthHandler(authService)
+	adminHandler := handlers.NewAdminHandler(adminService, inviteService, sessionService, loginGuardService, auditService)
+	authHandler := handlers.NewAuthHandler(authService, sessionService, mfaService, passwordService, oidcService, auditService)
 	userHandler := handlers.NewUserHandler(userService)
-	blockedHandler := handlers.NewBlockedHandler(blockedService, userService)
+	blockedHandler := handlers.NewBlockedHandler(blockedService, banReasonService, restrictionService, auditService)
+	keysHandler := handlers.NewKeysHandler(tokenManager)
+	apiKeyHandler := handlers.NewApiKeyHandler(apiKeyService, auditService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	banReasonHandler := handlers.NewBanReasonHandler(banReasonService, auditService)
+	appealHandler := handlers.NewAppealHandler(appealService, auditService)
+	reportHandler := handlers.NewReportHandler(reportService, adminService, auditService)
+	bulkModerationHandler := handlers.NewBulkModerationHandler(bulkModerationService)
 
-	router := routes.NewRouter(r, adminHandler, authHandler, userHandler, blockedHandler, tokenManager)
+	router := routes.NewRouter(r, adminHandler, authHandler, userHandler, blockedHandler, keysHandler, apiKeyHandler, auditHandler, banReasonHandler, appealHandler, reportHandler, bulkModerationHandler, tokenManager, adminService, sessionService, apiKeyService)
 
 	router.Router()
 
-	if err := r.Run(":9001"); err != nil {
-		log.Fatal().Err(err).Msg("Failed to start the server")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	suspensionScheduler := services.NewSuspensionScheduler(blockedData, restrictionData, auditService, cfg.SuspensionSweepInterval)
+	go suspensionScheduler.Run(ctx)
+	go bulkModerationService.RunJobRecovery(ctx)
+
+	server := &http.Server{Addr: ":9001", Handler: r}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal().Err(err).Msg("Failed to start the server")
+		}
+	}()
+
+	<-ctx.Done()
+	log.Info().Msg("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Failed to stop the server cleanly")
+	}
+	// Background moderation jobs stop after their current batch and are
+	// resumed by the next process to recover jobs.
+	if err := bulkModerationService.Shutdown(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Moderation jobs did not stop in time")
 	}
 }
 
@@ -89,3 +197,115 @@ func initDatabase(dbUrl string) (*sql.DB, error) {
 	}
 	return db, nil
 }
+
+// initKeyring assembles the PASETO keyring from PASETO_KEYS_DIR and
+// PASETO_KEYS. PASETO_SECRET_KEY is kept as the legacy key so tokens issued
+// before rotation stay valid until they expire. The v4.public signing seeds
+// come from the key files, PASETO_SIGNING_KEYS and, for the legacy key,
+// PASETO_SIGNING_SEED.
+func initKeyring(cfg *config.Config) (*auth.Keyring, error) {
+	keys := make([]auth.Key, 0)
+
+	if cfg.PasetoKeysDir != "" {
+		dirKeys, err := auth.LoadKeyDir(cfg.PasetoKeysDir)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, dirKeys...)
+	}
+
+	listKeys, err := auth.ParseKeyList(cfg.PasetoKeys)
+	if err != nil {
+		return nil, err
+	}
+	keys = append(keys, listKeys...)
+
+	if cfg.PasetoSecret != "" {
+		keys = append(keys, auth.Key{ID: auth.LegacyKeyID, Secret: []byte(cfg.PasetoSecret)})
+	}
+
+	seeds, err := auth.ParseSigningSeeds(cfg.PasetoSigningKeys)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.PasetoSigningSeed != "" {
+		seed, err := auth.ParseSigningSeed(cfg.PasetoSigningSeed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PASETO_SIGNING_SEED: %w", err)
+		}
+		seeds[auth.LegacyKeyID] = seed
+	}
+	for i := range keys {
+		if seed, ok := seeds[keys[i].ID]; ok {
+			keys[i].SigningSeed = seed
+			delete(seeds, keys[i].ID)
+		}
+	}
+	for id := range seeds {
+		return nil, fmt.Errorf("signing seed given for unknown paseto key %q", id)
+	}
+
+	activeID := cfg.PasetoActiveKeyID
+	if activeID == "" {
+		activeID = auth.LegacyKeyID
+	}
+
+	keyring, err := auth.NewKeyring(activeID, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range keyring.Retired() {
+		log.Warn().Str("key_id", id).Msg("PASETO key is retired and can be removed")
+	}
+
+	return keyring, nil
+}
+
+// initOidc returns nil when OIDC_ISSUER_URL is unset, which leaves SSO login
+// disabled and password login as the only option.
+func initOidc(
+	cfg *config.Config,
+	stateRepo data.OidcStateRepositoryInterface,
+	adminRepo data.AdminRepositoryInterface,
+	authService services.AuthServiceInterface,
+) (services.OidcServiceInterface, error) {
+	if cfg.OidcIssuerURL == "" {
+		return nil, nil
+	}
+	if cfg.OidcClientID == "" || cfg.OidcRedirectURL == "" {
+		return nil, fmt.Errorf("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required when OIDC_ISSUER_URL is set")
+	}
+
+	rules, err := services.ParseOidcRoleRules(cfg.OidcRoleMapping)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := oidc.NewProvider(oidc.Config{
+		IssuerURL:    cfg.OidcIssuerURL,
+		ClientID:     cfg.OidcClientID,
+		ClientSecret: cfg.OidcClientSecret,
+		RedirectURL:  cfg.OidcRedirectURL,
+	})
+
+	return services.NewOidcService(provider, stateRepo, adminRepo, authService, services.OidcServiceConfig{
+		Rules:         rules,
+		GroupsClaim:   cfg.OidcGroupsClaim,
+		AutoProvision: cfg.OidcAutoProvision,
+	}), nil
+}
+
+func bootstrapAdmin(cfg *config.Config, adminService services.AdminServiceInterface) {
+	if cfg.BootstrapAdminEmail == "" {
+		return
+	}
+
+	created, err := adminService.BootstrapAdmin(cfg.BootstrapAdminEmail, cfg.BootstrapAdminPassword, cfg.BootstrapAdminName)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to bootstrap admin")
+	}
+	if created {
+		log.Info().Str("email", cfg.BootstrapAdminEmail).Msg("Bootstrapped first superadmin")
+	}
+}
@@ -1,8 +1,8 @@
 package handlers
 
 import (
-	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/valu/vemeet-admin-api/internal/data"
@@ -12,31 +12,318 @@ import (
 )
 
 type AdminHandler struct {
-	adminService services.AdminServiceInterface
+	adminService      services.AdminServiceInterface
+	inviteService     services.InviteServiceInterface
+	sessionService    services.SessionServiceInterface
+	loginGuardService services.LoginGuardServiceInterface
+	auditService      services.AuditServiceInterface
 }
 
-func NewAdminHandler(adminService services.AdminServiceInterface) *AdminHandler {
-	return &AdminHandler{adminService}
+func NewAdminHandler(
+	adminService services.AdminServiceInterface,
+	inviteService services.InviteServiceInterface,
+	sessionService services.SessionServiceInterface,
+	loginGuardService services.LoginGuardServiceInterface,
+	auditService services.AuditServiceInterface,
+) *AdminHandler {
+	return &AdminHandler{
+		adminService:      adminService,
+		inviteService:     inviteService,
+		sessionService:    sessionService,
+		loginGuardService: loginGuardService,
+		auditService:      auditService,
+	}
+}
+
+func (h *AdminHandler) GetAdmins(c *gin.Context) {
+	page, limit, err := parsePagination(c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	sort := c.DefaultQuery("sort", "id")
+	order := c.DefaultQuery("order", "asc")
+	search := c.DefaultQuery("search", "")
+
+	admins, err := h.adminService.FindAllAdmins(page, limit, sort, order, search)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, admins)
+}
+
+func (h *AdminHandler) GetAdminById(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid admin id"))
+		return
+	}
+
+	admin, err := h.adminService.FindAdminById(id)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, admin)
+}
+
+func (h *AdminHandler) UpdateAdmin(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid admin id"))
+		return
+	}
+
+	var input models.UpdateAdminRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid admin data"))
+		return
+	}
+
+	before, err := h.adminService.FindAdminById(id)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	admin, err := h.adminService.UpdateProfile(id, input.Name, input.Email)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	h.auditService.Record(auditActor(c), services.AuditActionAdminUpdate, services.AuditTargetAdmin,
+		strconv.FormatInt(id, 10), before, admin)
+
+	c.JSON(http.StatusOK, admin)
 }
 
-func (h *AdminHandler) CreateAdmin(c *gin.Context) {
-	var input models.CreateAdminRequest
+func (h *AdminHandler) ChangeAdminRole(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid admin id"))
+		return
+	}
+
+	var input models.ChangeAdminRoleRequest
 	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("role is required"))
+		return
+	}
+
+	actorID, err := currentAdminID(c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	before, err := h.adminService.FindAdminById(id)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	admin, err := h.adminService.ChangeRole(actorID, id, input.Role)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	h.auditService.Record(auditActor(c), services.AuditActionAdminRoleChange, services.AuditTargetAdmin,
+		strconv.FormatInt(id, 10), before, admin)
+
+	c.JSON(http.StatusOK, admin)
+}
+
+func (h *AdminHandler) VerifyAdmin(c *gin.Context) {
+	h.setAdminState(c, services.AuditActionAdminVerify, h.adminService.SetVerified, true)
+}
+
+func (h *AdminHandler) UnverifyAdmin(c *gin.Context) {
+	h.setAdminState(c, services.AuditActionAdminUnverify, h.adminService.SetVerified, false)
+}
+
+func (h *AdminHandler) DeactivateAdmin(c *gin.Context) {
+	h.setAdminState(c, services.AuditActionAdminDeactivate, h.adminService.SetActive, false)
+}
+
+func (h *AdminHandler) ReactivateAdmin(c *gin.Context) {
+	h.setAdminState(c, services.AuditActionAdminReactivate, h.adminService.SetActive, true)
+}
+
+// setAdminState handles the verify/unverify and deactivate/reactivate
+// endpoints, which only differ in the service call and audit action.
+func (h *AdminHandler) setAdminState(
+	c *gin.Context,
+	action string,
+	apply func(actorID, id int64, value bool) (*data.Admin, error),
+	value bool,
+) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid admin id"))
+		return
+	}
+
+	actorID, err := currentAdminID(c)
+	if err != nil {
 		errors.HandleError(c, err)
 		return
 	}
 
-	admin := data.Admin{
-		Email:    input.Email,
-		Password: input.Password,
-		Name:     input.Name,
+	before, err := h.adminService.FindAdminById(id)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
 	}
-	fmt.Println(admin)
-	err := h.adminService.InsertAdmin(&admin)
+
+	admin, err := apply(actorID, id, value)
 	if err != nil {
 		errors.HandleError(c, err)
 		return
 	}
 
+	h.auditService.Record(auditActor(c), action, services.AuditTargetAdmin,
+		strconv.FormatInt(id, 10), before, admin)
+
+	c.JSON(http.StatusOK, admin)
+}
+
+func (h *AdminHandler) CreateInvite(c *gin.Context) {
+	var input models.CreateInviteRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid invite data"))
+		return
+	}
+
+	adminID, err := currentAdminID(c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	invite, token, err := h.inviteService.CreateInvite(input.Email, input.Role, adminID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	h.auditService.Record(auditActor(c), services.AuditActionInviteCreate, services.AuditTargetInvite,
+		strconv.FormatInt(invite.ID, 10), nil, invite)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"invite": invite,
+		"token":  token,
+	})
+}
+
+func (h *AdminHandler) GetInvites(c *gin.Context) {
+	invites, err := h.inviteService.ListInvites()
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invites)
+}
+
+func (h *AdminHandler) RevokeInvite(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid invite id"))
+		return
+	}
+
+	if err := h.inviteService.RevokeInvite(id); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	h.auditService.Record(auditActor(c), services.AuditActionInviteRevoke, services.AuditTargetInvite,
+		strconv.FormatInt(id, 10), nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"revoked": true})
+}
+
+func (h *AdminHandler) RedeemInvite(c *gin.Context) {
+	var input models.RedeemInviteRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid invite data"))
+		return
+	}
+
+	admin, err := h.inviteService.RedeemInvite(input.Token, input.Name, input.Password, c.ClientIP())
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	actor := auditActor(c)
+	actor.AdminID = &admin.ID
+	h.auditService.Record(actor, services.AuditActionInviteRedeem, services.AuditTargetAdmin,
+		strconv.FormatInt(admin.ID, 10), nil, admin)
+
 	c.JSON(http.StatusCreated, admin)
 }
+
+func (h *AdminHandler) ForceLogout(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid admin id"))
+		return
+	}
+
+	if _, err := h.adminService.FindAdminById(id); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	revoked, err := h.sessionService.RevokeAllSessions(id, data.SessionRevokedReasonForced)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	h.auditService.Record(auditActor(c), services.AuditActionAdminForceLogout, services.AuditTargetAdmin,
+		strconv.FormatInt(id, 10), nil, gin.H{"revoked_sessions": revoked})
+
+	c.JSON(http.StatusOK, gin.H{"revoked": revoked})
+}
+
+func (h *AdminHandler) UnlockAdmin(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid admin id"))
+		return
+	}
+
+	if err := h.loginGuardService.Unlock(id); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	h.auditService.Record(auditActor(c), services.AuditActionAdminUnlock, services.AuditTargetAdmin,
+		strconv.FormatInt(id, 10), nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"unlocked": true})
+}
+
+func (h *AdminHandler) GetLoginAttempts(c *gin.Context) {
+	page, limit, err := parsePagination(c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	attempts, err := h.loginGuardService.ListAttempts(page, limit, c.Query("email"), c.Query("ip"))
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, attempts)
+}
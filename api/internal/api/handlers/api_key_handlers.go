@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/valu/vemeet-admin-api/internal/errors"
+	"github.com/valu/vemeet-admin-api/internal/models"
+	"github.com/valu/vemeet-admin-api/internal/services"
+)
+
+type ApiKeyHandler struct {
+	apiKeyService services.ApiKeyServiceInterface
+	auditService  services.AuditServiceInterface
+}
+
+func NewApiKeyHandler(
+	apiKeyService services.ApiKeyServiceInterface,
+	auditService services.AuditServiceInterface,
+) *ApiKeyHandler {
+	return &ApiKeyHandler{
+		apiKeyService: apiKeyService,
+		auditService:  auditService,
+	}
+}
+
+// CreateApiKey returns the raw key once; only its hash is kept.
+func (h *ApiKeyHandler) CreateApiKey(c *gin.Context) {
+	var input models.CreateApiKeyRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid api key data"))
+		return
+	}
+
+	adminID, err := currentAdminID(c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	key, rawKey, err := h.apiKeyService.CreateApiKey(adminID, input.Name, input.Scopes, input.ExpiresAt, input.AllowedIPs)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	h.auditService.Record(auditActor(c), services.AuditActionApiKeyCreate, services.AuditTargetApiKey,
+		strconv.FormatInt(key.ID, 10), nil, key)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"api_key": key,
+		"key":     rawKey,
+	})
+}
+
+func (h *ApiKeyHandler) GetApiKeys(c *gin.Context) {
+	adminID, err := currentAdminID(c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	keys, err := h.apiKeyService.ListApiKeys(adminID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+func (h *ApiKeyHandler) RevokeApiKey(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid api key id"))
+		return
+	}
+
+	adminID, err := currentAdminID(c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	if err := h.apiKeyService.RevokeApiKey(adminID, id); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	h.auditService.Record(auditActor(c), services.AuditActionApiKeyRevoke, services.AuditTargetApiKey,
+		strconv.FormatInt(id, 10), nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"revoked": true})
+}
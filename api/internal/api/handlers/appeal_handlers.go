@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/valu/vemeet-admin-api/internal/data"
+	"github.com/valu/vemeet-admin-api/internal/errors"
+	"github.com/valu/vemeet-admin-api/internal/models"
+	"github.com/valu/vemeet-admin-api/internal/services"
+)
+
+type AppealHandler struct {
+	appealService services.AppealServiceInterface
+	auditService  services.AuditServiceInterface
+}
+
+func NewAppealHandler(
+	appealService services.AppealServiceInterface,
+	auditService services.AuditServiceInterface,
+) *AppealHandler {
+	return &AppealHandler{
+		appealService: appealService,
+		auditService:  auditService,
+	}
+}
+
+// appealSource tells appeals relayed by an internal service, which call
+// with an API key, apart from ones an admin files from the admin tool.
+func appealSource(c *gin.Context) string {
+	if c.GetString("api_key_id") != "" {
+		return data.AppealSourceUser
+	}
+	return data.AppealSourceAdmin
+}
+
+func (h *AppealHandler) GetAppeals(c *gin.Context) {
+	page, limit, err := parsePagination(c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	sort := c.DefaultQuery("sort", "id")
+	order := c.DefaultQuery("order", "asc")
+
+	filter := data.AppealFilter{Status: c.Query("status")}
+	for param, target := range map[string]**int64{
+		"reviewer_id": &filter.ReviewerID,
+		"user_id":     &filter.UserID,
+		"blocked_id":  &filter.BlockedID,
+	} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			errors.HandleError(c, errors.NewValidationError("invalid "+param))
+			return
+		}
+		*target = &id
+	}
+
+	appeals, err := h.appealService.GetAppeals(page, limit, sort, order, filter)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, appeals)
+}
+
+func (h *AppealHandler) GetAppealById(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid appeal id"))
+		return
+	}
+
+	appeal, err := h.appealService.GetAppeal(id)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, appeal)
+}
+
+func (h *AppealHandler) SubmitAppeal(c *gin.Context) {
+	var input models.SubmitAppealRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("blocked_id and statement are required"))
+		return
+	}
+
+	actor := auditActor(c)
+	appeal, err := h.appealService.SubmitAppeal(services.AppealInput{
+		BlockedID:   input.BlockedID,
+		Statement:   input.Statement,
+		Source:      appealSource(c),
+		SubmittedBy: actor.AdminID,
+		ApiKeyID:    actor.ApiKeyID,
+	})
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	h.auditService.Record(actor, services.AuditActionAppealSubmit, services.AuditTargetAppeal,
+		strconv.FormatInt(appeal.ID, 10), nil, appeal)
+
+	c.JSON(http.StatusCreated, appeal)
+}
+
+func (h *AppealHandler) AssignReviewer(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid appeal id"))
+		return
+	}
+
+	var input models.AssignAppealRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("reviewer_id is required"))
+		return
+	}
+
+	appeal, err := h.appealService.AssignReviewer(id, input.ReviewerID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	h.auditService.Record(auditActor(c), services.AuditActionAppealAssign, services.AuditTargetAppeal,
+		strconv.FormatInt(id, 10), nil, appeal)
+
+	c.JSON(http.StatusOK, appeal)
+}
+
+func (h *AppealHandler) AddComment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid appeal id"))
+		return
+	}
+
+	var input models.AppealCommentRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("body is required"))
+		return
+	}
+
+	actor := auditActor(c)
+	source := appealSource(c)
+	var adminID *int64
+	if source == data.AppealSourceAdmin {
+		adminID = actor.AdminID
+	}
+
+	comment, err := h.appealService.AddComment(id, source, adminID, input.Body)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	h.auditService.Record(actor, services.AuditActionAppealComment, services.AuditTargetAppeal,
+		strconv.FormatInt(id, 10), nil, comment)
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// DecideAppeal upholds or overturns an appeal. Overturning lifts the ban,
+// which is audited as a lift of its own.
+func (h *AppealHandler) DecideAppeal(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid appeal id"))
+		return
+	}
+
+	var input models.DecideAppealRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("decision and note are required"))
+		return
+	}
+
+	actor := auditActor(c)
+	result, err := h.appealService.DecideAppeal(id, input.Decision, actor.AdminID, input.Note)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	h.auditService.Record(actor, services.AuditActionAppealDecide, services.AuditTargetAppeal,
+		strconv.FormatInt(id, 10), nil, result.Appeal)
+
+	if result.Applied {
+		before := *result.Lifted
+		before.LiftedAt = nil
+		before.LiftedBy = nil
+		before.LiftReason = nil
+		h.auditService.Record(actor, services.AuditActionBlockedLift, services.AuditTargetBlocked,
+			strconv.FormatInt(result.Lifted.ID, 10), before, result.Lifted)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"appeal": result.Appeal,
+		"lifted": result.Lifted,
+	})
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/valu/vemeet-admin-api/internal/data"
+	"github.com/valu/vemeet-admin-api/internal/errors"
+	"github.com/valu/vemeet-admin-api/internal/services"
+)
+
+type AuditHandler struct {
+	auditService services.AuditServiceInterface
+}
+
+func NewAuditHandler(auditService services.AuditServiceInterface) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+func (h *AuditHandler) GetAuditLog(c *gin.Context) {
+	page, limit, err := parsePagination(c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	filter := data.AuditFilter{
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+		Action:     c.Query("action"),
+	}
+
+	if adminID := c.Query("admin_id"); adminID != "" {
+		id, err := strconv.ParseInt(adminID, 10, 64)
+		if err != nil {
+			errors.HandleError(c, errors.NewValidationError("invalid admin_id"))
+			return
+		}
+		filter.AdminID = &id
+	}
+
+	if filter.From, err = parseTimeQuery(c, "from"); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+	if filter.To, err = parseTimeQuery(c, "to"); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	entries, err := h.auditService.ListEntries(page, limit, filter)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+func (h *AuditHandler) VerifyAuditLog(c *gin.Context) {
+	result, err := h.auditService.VerifyChain()
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
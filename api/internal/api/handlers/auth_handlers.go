@@ -5,18 +5,36 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/valu/vemeet-admin-api/internal/auth"
 	"github.com/valu/vemeet-admin-api/internal/errors"
 	"github.com/valu/vemeet-admin-api/internal/models"
 	"github.com/valu/vemeet-admin-api/internal/services"
 )
 
 type AuthHandler struct {
-	authService services.AuthServiceInterface
+	authService     services.AuthServiceInterface
+	sessionService  services.SessionServiceInterface
+	mfaService      services.MfaServiceInterface
+	passwordService services.PasswordServiceInterface
+	oidcService     services.OidcServiceInterface
+	auditService    services.AuditServiceInterface
 }
 
-func NewAuthHandler(authService services.AuthServiceInterface) *AuthHandler {
+func NewAuthHandler(
+	authService services.AuthServiceInterface,
+	sessionService services.SessionServiceInterface,
+	mfaService services.MfaServiceInterface,
+	passwordService services.PasswordServiceInterface,
+	oidcService services.OidcServiceInterface,
+	auditService services.AuditServiceInterface,
+) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
+		authService:     authService,
+		sessionService:  sessionService,
+		mfaService:      mfaService,
+		passwordService: passwordService,
+		oidcService:     oidcService,
+		auditService:    auditService,
 	}
 }
 
@@ -27,15 +45,101 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	tokens, admin, err := h.authService.LoginUser(input.Email, input.Password)
+	result, err := h.authService.LoginUser(input.Email, input.Password, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
 		errors.HandleError(c, err)
 		return
 	}
 
+	writeLoginResult(c, result)
+}
+
+func (h *AuthHandler) LoginMFA(c *gin.Context) {
+	var input models.MFALoginRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid mfa data"))
+		return
+	}
+
+	result, err := h.authService.CompleteMFALogin(input.MFAToken, input.Code, input.RecoveryCode, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	writeLoginResult(c, result)
+}
+
+// OidcStart begins an SSO login. The client redirects the browser to the
+// returned URL and later posts the code and state it receives back from the
+// same browser, which must still hold the state cookie set here.
+func (h *AuthHandler) OidcStart(c *gin.Context) {
+	if h.oidcService == nil {
+		errors.HandleError(c, errors.NewNotFoundError("sso login is not configured"))
+		return
+	}
+
+	authURL, state, err := h.oidcService.Start()
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	setOidcStateCookie(c, state, int(services.OidcStateTTL.Seconds()))
+
 	c.JSON(http.StatusOK, gin.H{
-		"tokens": tokens,
-		"admin":  admin,
+		"authorization_url": authURL,
+	})
+}
+
+func (h *AuthHandler) OidcCallback(c *gin.Context) {
+	if h.oidcService == nil {
+		errors.HandleError(c, errors.NewNotFoundError("sso login is not configured"))
+		return
+	}
+
+	var input models.OidcCallbackRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("code and state are required"))
+		return
+	}
+
+	browserState, _ := c.Cookie(oidcStateCookie)
+	setOidcStateCookie(c, "", -1)
+
+	result, err := h.oidcService.Callback(input.Code, input.State, browserState, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	writeLoginResult(c, result)
+}
+
+// oidcStateCookie ties an SSO login to the browser that started it.
+const oidcStateCookie = "oidc_state"
+
+// setOidcStateCookie sets the state cookie, or clears it when maxAge is
+// negative. It is only sent back to the SSO endpoints.
+func setOidcStateCookie(c *gin.Context, state string, maxAge int) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, maxAge, "/v1/auth/oidc", "", secure, true)
+}
+
+func writeLoginResult(c *gin.Context, result *services.LoginResult) {
+	if result.MFAToken != "" {
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required":     true,
+			"mfa_token":        result.MFAToken,
+			"mfa_token_expiry": result.MFATokenExpiry,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": result.Tokens,
+		"admin":  result.Admin,
 	})
 }
 
@@ -77,6 +181,174 @@ func (h *AuthHandler) Session(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"admin": admin,
+		"admin":       admin,
+		"permissions": auth.PermissionsForRole(auth.Role(admin.Role)),
+	})
+}
+
+func (h *AuthHandler) GetSessions(c *gin.Context) {
+	adminID, err := currentAdminID(c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	sessions, err := h.sessionService.ListSessions(adminID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions":   sessions,
+		"current_id": c.GetString("session_id"),
+	})
+}
+
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	adminID, err := currentAdminID(c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	if err := h.sessionService.RevokeSession(adminID, c.Param("id")); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	h.auditService.Record(auditActor(c), services.AuditActionSessionRevoke, services.AuditTargetSession,
+		c.Param("id"), nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"revoked": 1})
+}
+
+func (h *AuthHandler) RevokeOtherSessions(c *gin.Context) {
+	adminID, err := currentAdminID(c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	revoked, err := h.sessionService.RevokeOtherSessions(adminID, c.GetString("session_id"))
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	h.auditService.Record(auditActor(c), services.AuditActionSessionRevoke, services.AuditTargetAdmin,
+		strconv.FormatInt(adminID, 10), nil, gin.H{"revoked_sessions": revoked})
+
+	c.JSON(http.StatusOK, gin.H{"revoked": revoked})
+}
+
+func (h *AuthHandler) SetupTOTP(c *gin.Context) {
+	adminID, err := currentAdminID(c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	secret, uri, err := h.mfaService.SetupTOTP(adminID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": uri,
+	})
+}
+
+func (h *AuthHandler) ConfirmTOTP(c *gin.Context) {
+	var input models.ConfirmTOTPRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid totp data"))
+		return
+	}
+
+	adminID, err := currentAdminID(c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	codes, err := h.mfaService.ConfirmTOTP(adminID, input.Code)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	h.auditService.Record(auditActor(c), services.AuditActionTOTPEnable, services.AuditTargetAdmin,
+		strconv.FormatInt(adminID, 10), nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"recovery_codes": codes,
 	})
 }
+
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var input models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid password data"))
+		return
+	}
+
+	adminID, err := currentAdminID(c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	err = h.passwordService.ChangePassword(adminID, c.GetString("session_id"), input.CurrentPassword, input.NewPassword)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	h.auditService.Record(auditActor(c), services.AuditActionPasswordChange, services.AuditTargetAdmin,
+		strconv.FormatInt(adminID, 10), nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"changed": true})
+}
+
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var input models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid email"))
+		return
+	}
+
+	if err := h.passwordService.RequestReset(input.Email, c.ClientIP()); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "if the account exists, a reset token has been sent",
+	})
+}
+
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var input models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid reset data"))
+		return
+	}
+
+	adminID, err := h.passwordService.ResetPassword(input.Token, input.NewPassword)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	// The reset is unauthenticated, so the admin who held the token is
+	// recorded as the actor.
+	actor := auditActor(c)
+	actor.AdminID = &adminID
+	h.auditService.Record(actor, services.AuditActionPasswordReset, services.AuditTargetAdmin,
+		strconv.FormatInt(adminID, 10), nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"reset": true})
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/valu/vemeet-admin-api/internal/errors"
+	"github.com/valu/vemeet-admin-api/internal/models"
+	"github.com/valu/vemeet-admin-api/internal/services"
+)
+
+type BanReasonHandler struct {
+	banReasonService services.BanReasonServiceInterface
+	auditService     services.AuditServiceInterface
+}
+
+func NewBanReasonHandler(
+	banReasonService services.BanReasonServiceInterface,
+	auditService services.AuditServiceInterface,
+) *BanReasonHandler {
+	return &BanReasonHandler{
+		banReasonService: banReasonService,
+		auditService:     auditService,
+	}
+}
+
+func (h *BanReasonHandler) GetBanReasons(c *gin.Context) {
+	reasons, err := h.banReasonService.ListReasons(c.Query("include_retired") == "true")
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reasons)
+}
+
+func (h *BanReasonHandler) CreateBanReason(c *gin.Context) {
+	var input models.CreateBanReasonRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid ban reason data"))
+		return
+	}
+
+	actor := auditActor(c)
+	reason, err := h.banReasonService.CreateReason(input.Code, input.Label, input.Description,
+		input.Severity, input.DefaultDuration, actor.AdminID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	h.auditService.Record(actor, services.AuditActionBanReasonCreate, services.AuditTargetBanReason,
+		reason.Code, nil, reason)
+
+	c.JSON(http.StatusCreated, reason)
+}
+
+func (h *BanReasonHandler) RetireBanReason(c *gin.Context) {
+	code := c.Param("code")
+
+	if err := h.banReasonService.RetireReason(code); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	h.auditService.Record(auditActor(c), services.AuditActionBanReasonRetire, services.AuditTargetBanReason,
+		code, nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"retired": true})
+}
+
+func (h *BanReasonHandler) GetLadder(c *gin.Context) {
+	steps, err := h.banReasonService.GetLadder(c.Param("code"))
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, steps)
+}
+
+func (h *BanReasonHandler) UpdateLadder(c *gin.Context) {
+	code := c.Param("code")
+
+	var input models.UpdateEscalationLadderRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid escalation ladder"))
+		return
+	}
+
+	before, err := h.banReasonService.GetLadder(code)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	steps := make([]services.LadderStepInput, len(input.Steps))
+	for i, step := range input.Steps {
+		steps[i] = services.LadderStepInput{Action: step.Action, Duration: step.Duration}
+	}
+
+	ladder, err := h.banReasonService.SetLadder(code, steps)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	h.auditService.Record(auditActor(c), services.AuditActionBanReasonLadder, services.AuditTargetBanReason,
+		code, before, ladder)
+
+	c.JSON(http.StatusOK, ladder)
+}
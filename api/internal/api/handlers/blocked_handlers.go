@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/valu/vemeet-admin-api/internal/data"
@@ -11,17 +12,23 @@ import (
 )
 
 type BlockedHandler struct {
-	blockedService services.BlockedServiceInterface
-	userService    services.UserServiceInterface
+	blockedService     services.BlockedServiceInterface
+	banReasonService   services.BanReasonServiceInterface
+	restrictionService services.RestrictionServiceInterface
+	auditService       services.AuditServiceInterface
 }
 
 func NewBlockedHandler(
 	blockedService services.BlockedServiceInterface,
-	userService services.UserServiceInterface,
+	banReasonService services.BanReasonServiceInterface,
+	restrictionService services.RestrictionServiceInterface,
+	auditService services.AuditServiceInterface,
 ) *BlockedHandler {
 	return &BlockedHandler{
-		blockedService: blockedService,
-		userService:    userService,
+		blockedService:     blockedService,
+		banReasonService:   banReasonService,
+		restrictionService: restrictionService,
+		auditService:       auditService,
 	}
 }
 
@@ -41,24 +48,38 @@ func (h *BlockedHandler) GetBlockedById(c *gin.Context) {
 	c.JSON(http.StatusOK, blocked)
 }
 
+// GetBlockeds lists bans. With group_by=reason_code it returns ban counts
+// per reason code instead of a page of bans.
 func (h *BlockedHandler) GetBlockeds(c *gin.Context) {
-	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
-	if err != nil {
-		errors.HandleError(c, errors.NewValidationError("invalid page"))
+	status := c.DefaultQuery("status", "")
+
+	switch c.Query("group_by") {
+	case "":
+	case "reason_code":
+		counts, err := h.banReasonService.CountBansByReason(status)
+		if err != nil {
+			errors.HandleError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, counts)
+		return
+	default:
+		errors.HandleError(c, errors.NewValidationError("group_by must be reason_code"))
 		return
 	}
 
-	limit, err := strconv.ParseInt(c.DefaultQuery("pageSize", "10"), 10, 64)
+	page, limit, err := parsePagination(c)
 	if err != nil {
-		errors.HandleError(c, errors.NewValidationError("invalid limit"))
+		errors.HandleError(c, err)
 		return
 	}
 
 	sort := c.DefaultQuery("sort", "id")
 	order := c.DefaultQuery("order", "asc")
 	search := c.DefaultQuery("search", "")
+	reasonCode := c.DefaultQuery("reason_code", "")
 
-	blockeds, err := h.blockedService.GetBlockeds(page, limit, sort, order, search)
+	blockeds, err := h.blockedService.GetBlockeds(page, limit, sort, order, search, status, reasonCode)
 	if err != nil {
 		errors.HandleError(c, err)
 		return
@@ -67,9 +88,23 @@ func (h *BlockedHandler) GetBlockeds(c *gin.Context) {
 	c.JSON(http.StatusOK, blockeds)
 }
 
+// BlockedCreateRequest bans a user by default. Mode selects a lighter
+// restriction instead: shadow_ban, inbox_lock or swiper_restriction.
+//
+// For bans the enforcement follows the escalation policy unless
+// enforcement, expires_at or permanent is given. A choice that differs from
+// the policy needs override and override_reason. Restrictions take the
+// reason code's default duration unless expires_at or permanent is given.
 type BlockedCreateRequest struct {
-	UserID int64  `json:"user_id"`
-	Reason string `json:"reason"`
+	UserID         int64      `json:"user_id"`
+	Mode           string     `json:"mode"`
+	ReasonCode     string     `json:"reason_code" binding:"required"`
+	Reason         string     `json:"reason"`
+	Enforcement    string     `json:"enforcement"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	Permanent      bool       `json:"permanent"`
+	Override       bool       `json:"override"`
+	OverrideReason string     `json:"override_reason"`
 }
 
 func (h *BlockedHandler) CreateBlocked(c *gin.Context) {
@@ -79,28 +114,79 @@ func (h *BlockedHandler) CreateBlocked(c *gin.Context) {
 		return
 	}
 
-	blockedReq := &data.Blocked{
-		UserID: blocked.UserID,
-		Reason: blocked.Reason,
+	actor := auditActor(c)
+	if blocked.Mode != "" && blocked.Mode != blockedModeBan {
+		h.createRestriction(c, actor, blocked)
+		return
 	}
 
-	createdBlocked, err := h.blockedService.CreateBlocked(blockedReq)
+	result, err := h.blockedService.BlockUser(services.BlockInput{
+		UserID:         blocked.UserID,
+		ReasonCode:     blocked.ReasonCode,
+		Notes:          blocked.Reason,
+		Enforcement:    blocked.Enforcement,
+		ExpiresAt:      blocked.ExpiresAt,
+		Permanent:      blocked.Permanent,
+		Override:       blocked.Override,
+		OverrideReason: blocked.OverrideReason,
+		BlockedBy:      actor.AdminID,
+	})
 	if err != nil {
 		errors.HandleError(c, err)
 		return
 	}
 
-	_, err = h.userService.ToggleBlockUser(blocked.UserID)
+	if !result.Applied {
+		c.JSON(http.StatusOK, result.Blocked)
+		return
+	}
+
+	h.auditService.Record(actor, services.AuditActionBlockedCreate, services.AuditTargetBlocked,
+		strconv.FormatInt(result.Blocked.ID, 10), nil, result.Blocked)
+	if result.Blocked.PolicyOverride {
+		h.auditService.Record(actor, services.AuditActionBlockedOverride, services.AuditTargetBlocked,
+			strconv.FormatInt(result.Blocked.ID, 10), result.Proposal, result.Blocked)
+	}
+
+	c.JSON(http.StatusCreated, result.Blocked)
+}
+
+const blockedModeBan = "ban"
+
+func (h *BlockedHandler) createRestriction(c *gin.Context, actor services.AuditActor, input BlockedCreateRequest) {
+	if input.Enforcement != "" || input.Override {
+		errors.HandleError(c, errors.NewValidationError("enforcement and override only apply to bans"))
+		return
+	}
+
+	result, err := h.restrictionService.Restrict(services.RestrictInput{
+		UserID:     input.UserID,
+		Mode:       input.Mode,
+		ReasonCode: input.ReasonCode,
+		Notes:      input.Reason,
+		ExpiresAt:  input.ExpiresAt,
+		Permanent:  input.Permanent,
+		CreatedBy:  actor.AdminID,
+	})
 	if err != nil {
 		errors.HandleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, createdBlocked)
+	if !result.Applied {
+		c.JSON(http.StatusOK, result.Restriction)
+		return
+	}
+
+	h.auditService.Record(actor, services.AuditActionRestrictionCreate, services.AuditTargetRestriction,
+		strconv.FormatInt(result.Restriction.ID, 10), nil, result.Restriction)
+
+	c.JSON(http.StatusCreated, result.Restriction)
 }
 
 type BlockedUpdateRequest struct {
-	Reason string `json:"reason"`
+	ReasonCode *string `json:"reason_code"`
+	Reason     string  `json:"reason"`
 }
 
 func (h *BlockedHandler) UpdateBlocked(c *gin.Context) {
@@ -116,9 +202,16 @@ func (h *BlockedHandler) UpdateBlocked(c *gin.Context) {
 		return
 	}
 
+	before, err := h.blockedService.GetBlockedById(id)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
 	blockedReq := &data.Blocked{
-		ID:     id,
-		Reason: blocked.Reason,
+		ID:         id,
+		ReasonCode: blocked.ReasonCode,
+		Reason:     blocked.Reason,
 	}
 
 	updatedBlocked, err := h.blockedService.UpdateBlocked(blockedReq)
@@ -127,35 +220,170 @@ func (h *BlockedHandler) UpdateBlocked(c *gin.Context) {
 		return
 	}
 
+	h.auditService.Record(auditActor(c), services.AuditActionBlockedUpdate, services.AuditTargetBlocked,
+		strconv.FormatInt(id, 10), before, updatedBlocked)
+
 	c.JSON(http.StatusOK, updatedBlocked)
 }
 
-func (h *BlockedHandler) DeleteBlocked(c *gin.Context) {
+type BlockedLiftRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// LiftBlocked ends a ban early. The ban is closed rather than deleted so it
+// stays in the user's history.
+func (h *BlockedHandler) LiftBlocked(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		errors.HandleError(c, errors.NewValidationError("invalid blocked id"))
 		return
 	}
 
-	deleted, err := h.blockedService.DeleteBlocked(id)
+	var input BlockedLiftRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("reason is required"))
+		return
+	}
+
+	actor := auditActor(c)
+	result, err := h.blockedService.LiftBlocked(id, actor.AdminID, input.Reason)
 	if err != nil {
 		errors.HandleError(c, err)
 		return
 	}
 
-	user, err := h.userService.GetUserById(id)
+	lifted := result.Lifted[0]
+	if result.Applied {
+		before := *lifted
+		before.LiftedAt = nil
+		before.LiftedBy = nil
+		before.LiftReason = nil
+		h.auditService.Record(actor, services.AuditActionBlockedLift, services.AuditTargetBlocked,
+			strconv.FormatInt(id, 10), before, lifted)
+	}
+
+	c.JSON(http.StatusOK, lifted)
+}
+
+// UnblockUser lifts every ban in force for the user and clears their
+// blocked flag. Unblocking a user who is not blocked changes nothing.
+func (h *BlockedHandler) UnblockUser(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid user id"))
+		return
+	}
+
+	var input BlockedLiftRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("reason is required"))
+		return
+	}
+
+	actor := auditActor(c)
+	result, err := h.blockedService.UnblockUser(userID, actor.AdminID, input.Reason)
 	if err != nil {
 		errors.HandleError(c, err)
 		return
 	}
 
-	if user.Blocked {
-		_, err := h.userService.ToggleBlockUser(id)
-		if err != nil {
-			errors.HandleError(c, err)
-			return
+	if result.Applied {
+		for _, lifted := range result.Lifted {
+			before := *lifted
+			before.LiftedAt = nil
+			before.LiftedBy = nil
+			before.LiftReason = nil
+			h.auditService.Record(actor, services.AuditActionBlockedLift, services.AuditTargetBlocked,
+				strconv.FormatInt(lifted.ID, 10), before, lifted)
 		}
+		h.auditService.Record(actor, services.AuditActionUserUnblock, services.AuditTargetUser,
+			strconv.FormatInt(userID, 10), gin.H{"blocked": true}, gin.H{"blocked": false, "reason": input.Reason})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"lifted":  result.Lifted,
+		"applied": result.Applied,
+	})
+}
+
+// GetBanProposal previews what the escalation policy would apply if the
+// user were banned under the given reason code now.
+func (h *BlockedHandler) GetBanProposal(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid user id"))
+		return
+	}
+
+	proposal, err := h.blockedService.ProposeBlock(userID, c.Query("reason_code"))
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, proposal)
+}
+
+func (h *BlockedHandler) GetUserBans(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid user id"))
+		return
+	}
+
+	bans, err := h.blockedService.GetUserBans(userID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, bans)
+}
+
+func (h *BlockedHandler) LiftRestriction(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid restriction id"))
+		return
+	}
+
+	var input BlockedLiftRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("reason is required"))
+		return
+	}
+
+	actor := auditActor(c)
+	result, err := h.restrictionService.LiftRestriction(id, actor.AdminID, input.Reason)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	if result.Applied {
+		before := *result.Restriction
+		before.LiftedAt = nil
+		before.LiftedBy = nil
+		before.LiftReason = nil
+		h.auditService.Record(actor, services.AuditActionRestrictionLift, services.AuditTargetRestriction,
+			strconv.FormatInt(id, 10), before, result.Restriction)
+	}
+
+	c.JSON(http.StatusOK, result.Restriction)
+}
+
+func (h *BlockedHandler) GetUserRestrictions(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid user id"))
+		return
+	}
+
+	restrictions, err := h.restrictionService.GetUserRestrictions(userID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"deleted": deleted})
+	c.JSON(http.StatusOK, restrictions)
 }
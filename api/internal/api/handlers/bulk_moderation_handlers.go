@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/valu/vemeet-admin-api/internal/data"
+	"github.com/valu/vemeet-admin-api/internal/errors"
+	"github.com/valu/vemeet-admin-api/internal/models"
+	"github.com/valu/vemeet-admin-api/internal/services"
+)
+
+type BulkModerationHandler struct {
+	bulkModerationService services.BulkModerationServiceInterface
+}
+
+func NewBulkModerationHandler(bulkModerationService services.BulkModerationServiceInterface) *BulkModerationHandler {
+	return &BulkModerationHandler{
+		bulkModerationService: bulkModerationService,
+	}
+}
+
+// BulkBlock answers 200 with per-item results when the request ran inline,
+// or 202 with the job to poll when it was queued. Each applied item is
+// audited like a single ban or restriction.
+func (h *BulkModerationHandler) BulkBlock(c *gin.Context) {
+	var input models.BulkBlockRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid bulk moderation data"))
+		return
+	}
+
+	result, err := h.bulkModerationService.BulkBlock(auditActor(c), services.BulkBlockInput{
+		UserIDs:        input.UserIDs,
+		Usernames:      input.Usernames,
+		Mode:           input.Mode,
+		ReasonCode:     input.ReasonCode,
+		Notes:          input.Reason,
+		Enforcement:    input.Enforcement,
+		ExpiresAt:      input.ExpiresAt,
+		Permanent:      input.Permanent,
+		Override:       input.Override,
+		OverrideReason: input.OverrideReason,
+		Async:          input.Async,
+	})
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	if result.Job != nil {
+		c.JSON(http.StatusAccepted, result)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *BulkModerationHandler) GetJob(c *gin.Context) {
+	h.getJob(c, data.ModerationJobKindBulkBlock)
+}
+
+func (h *BulkModerationHandler) GetJobItems(c *gin.Context) {
+	h.getJobItems(c, data.ModerationJobKindBulkBlock)
+}
+
+func (h *BulkModerationHandler) GetBanImport(c *gin.Context) {
+	h.getJob(c, data.ModerationJobKindBanImport)
+}
+
+func (h *BulkModerationHandler) GetBanImportRows(c *gin.Context) {
+	h.getJobItems(c, data.ModerationJobKindBanImport)
+}
+
+func (h *BulkModerationHandler) getJob(c *gin.Context, kind string) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid job id"))
+		return
+	}
+
+	job, err := h.bulkModerationService.GetJob(kind, id)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+func (h *BulkModerationHandler) getJobItems(c *gin.Context, kind string) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid job id"))
+		return
+	}
+
+	page, limit, err := parsePagination(c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	items, err := h.bulkModerationService.GetJobItems(kind, id, page, limit, c.DefaultQuery("status", ""))
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// maxBanImportBytes bounds the size of an uploaded ban list.
+const maxBanImportBytes = 10 << 20
+
+// PreviewBanImport takes a ban list as a multipart upload in the file field
+// and answers with the dry run: a previewed import job counting the rows
+// that would be banned, are already banned, or were rejected.
+func (h *BulkModerationHandler) PreviewBanImport(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBanImportBytes)
+
+	var input models.BanImportRequest
+	if err := c.ShouldBind(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid import data"))
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("file is required and must be at most "+
+			strconv.Itoa(maxBanImportBytes>>20)+" MB"))
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("file could not be read"))
+		return
+	}
+	defer file.Close()
+
+	job, err := h.bulkModerationService.PreviewBanImport(auditActor(c), services.BanImportInput{
+		Data:     file,
+		Filename: header.Filename,
+		Format:   input.Format,
+		Mapping: services.BanImportMapping{
+			UserIDColumn:   input.UserIDColumn,
+			UsernameColumn: input.UsernameColumn,
+			NotesColumn:    input.NotesColumn,
+		},
+		Ban: services.BulkBlockInput{
+			ReasonCode:     input.ReasonCode,
+			Notes:          input.Reason,
+			Enforcement:    input.Enforcement,
+			ExpiresAt:      input.ExpiresAt,
+			Permanent:      input.Permanent,
+			Override:       input.Override,
+			OverrideReason: input.OverrideReason,
+		},
+	})
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, job)
+}
+
+// ConfirmBanImport applies a previewed import. It answers 202 while a large
+// import is still running in the background.
+func (h *BulkModerationHandler) ConfirmBanImport(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid import id"))
+		return
+	}
+
+	job, err := h.bulkModerationService.ConfirmBanImport(auditActor(c), id)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	if job.Status == data.ModerationJobStatusRunning {
+		c.JSON(http.StatusAccepted, job)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+func (h *BulkModerationHandler) GetBanImports(c *gin.Context) {
+	page, limit, err := parsePagination(c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	imports, err := h.bulkModerationService.GetBanImports(page, limit)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, imports)
+}
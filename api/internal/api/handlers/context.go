@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/valu/vemeet-admin-api/internal/errors"
+	"github.com/valu/vemeet-admin-api/internal/services"
+)
+
+func currentAdminID(c *gin.Context) (int64, error) {
+	id := c.GetString("user_id")
+	if id == "" {
+		return 0, errors.NewAuthenticationError("user id not found")
+	}
+
+	userId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, errors.NewAuthenticationError("invalid user id")
+	}
+
+	return userId, nil
+}
+
+// auditActor describes the caller for audit log entries.
+func auditActor(c *gin.Context) services.AuditActor {
+	actor := services.AuditActor{
+		RequestID: c.GetString("request_id"),
+		IPAddress: c.ClientIP(),
+	}
+
+	if id, err := strconv.ParseInt(c.GetString("user_id"), 10, 64); err == nil {
+		actor.AdminID = &id
+	}
+	if id, err := strconv.ParseInt(c.GetString("api_key_id"), 10, 64); err == nil {
+		actor.ApiKeyID = &id
+	}
+
+	return actor
+}
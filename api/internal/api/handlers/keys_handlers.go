@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/valu/vemeet-admin-api/internal/auth"
+)
+
+type KeysHandler struct {
+	tokenManager *auth.TokenManager
+}
+
+func NewKeysHandler(tokenManager *auth.TokenManager) *KeysHandler {
+	return &KeysHandler{tokenManager}
+}
+
+func (h *KeysHandler) GetPublicKeys(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{
+		"issuer":   h.tokenManager.Issuer(),
+		"audience": h.tokenManager.Audience(),
+		"keys":     h.tokenManager.PublicKeys(),
+	})
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/valu/vemeet-admin-api/internal/errors"
+)
+
+// maxPageSize bounds the pageSize of every paginated list.
+const maxPageSize = 100
+
+// parsePagination reads page and pageSize, defaulting to the first page of
+// ten. Pages start at 1.
+func parsePagination(c *gin.Context) (int64, int64, error) {
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil || page < 1 {
+		return 0, 0, errors.NewValidationError("invalid page")
+	}
+
+	limit, err := strconv.ParseInt(c.DefaultQuery("pageSize", "10"), 10, 64)
+	if err != nil || limit < 1 || limit > maxPageSize {
+		return 0, 0, errors.NewValidationError("invalid pageSize, expected 1 to " + strconv.Itoa(maxPageSize))
+	}
+
+	return page, limit, nil
+}
+
+// parseTimeQuery reads an optional RFC 3339 timestamp or YYYY-MM-DD date
+// query parameter. A date stands for midnight UTC at its start.
+func parseTimeQuery(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		parsed, err = time.Parse(time.DateOnly, value)
+	}
+	if err != nil {
+		return nil, errors.NewValidationError("invalid " + name + ", expected RFC 3339 or YYYY-MM-DD")
+	}
+	return &parsed, nil
+}
+
+// parseBoolQuery reads an optional true/false query parameter.
+func parseBoolQuery(c *gin.Context, name string) (*bool, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, errors.NewValidationError("invalid " + name + ", expected true or false")
+	}
+	return &parsed, nil
+}
+
+// parseIntQuery reads an optional whole number query parameter.
+func parseIntQuery(c *gin.Context, name string) (*int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, errors.NewValidationError("invalid " + name + ", expected a whole number")
+	}
+	return &parsed, nil
+}
+
+// parseListQuery reads a query parameter given either as a comma separated
+// list or repeated, dropping empty entries.
+func parseListQuery(c *gin.Context, name string) []string {
+	var values []string
+	for _, raw := range c.QueryArray(name) {
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/valu/vemeet-admin-api/internal/auth"
+	"github.com/valu/vemeet-admin-api/internal/data"
+	"github.com/valu/vemeet-admin-api/internal/errors"
+	"github.com/valu/vemeet-admin-api/internal/models"
+	"github.com/valu/vemeet-admin-api/internal/services"
+)
+
+type ReportHandler struct {
+	reportService services.ReportServiceInterface
+	adminService  services.AdminServiceInterface
+	auditService  services.AuditServiceInterface
+}
+
+func NewReportHandler(
+	reportService services.ReportServiceInterface,
+	adminService services.AdminServiceInterface,
+	auditService services.AuditServiceInterface,
+) *ReportHandler {
+	return &ReportHandler{
+		reportService: reportService,
+		adminService:  adminService,
+		auditService:  auditService,
+	}
+}
+
+// SubmitReport takes a report relayed by the app backend.
+func (h *ReportHandler) SubmitReport(c *gin.Context) {
+	var input models.SubmitReportRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("reporter_id, reported_user_id and category are required"))
+		return
+	}
+
+	report, err := h.reportService.SubmitReport(services.ReportInput{
+		ReporterID:     input.ReporterID,
+		ReportedUserID: input.ReportedUserID,
+		Category:       input.Category,
+		Details:        input.Details,
+		ImageIDs:       input.ImageIDs,
+	})
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, report)
+}
+
+func (h *ReportHandler) GetReports(c *gin.Context) {
+	page, limit, err := parsePagination(c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	sort := c.DefaultQuery("sort", "id")
+	order := c.DefaultQuery("order", "asc")
+
+	filter := data.ReportFilter{Status: c.Query("status"), Category: c.Query("category")}
+	for param, target := range map[string]**int64{
+		"reporter_id":      &filter.ReporterID,
+		"reported_user_id": &filter.ReportedUserID,
+	} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			errors.HandleError(c, errors.NewValidationError("invalid "+param))
+			return
+		}
+		*target = &id
+	}
+
+	reports, err := h.reportService.GetReports(page, limit, sort, order, filter)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reports)
+}
+
+// GetQueue lists pending reports grouped by reported user. It sorts by
+// priority unless told otherwise.
+func (h *ReportHandler) GetQueue(c *gin.Context) {
+	page, limit, err := parsePagination(c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	sort := c.DefaultQuery("sort", "priority")
+	order := c.DefaultQuery("order", "desc")
+
+	queue, err := h.reportService.GetQueue(page, limit, sort, order, c.Query("category"))
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, queue)
+}
+
+func (h *ReportHandler) GetReportById(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid report id"))
+		return
+	}
+
+	report, err := h.reportService.GetReport(id)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *ReportHandler) StartReview(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid report id"))
+		return
+	}
+
+	reviewerID, err := currentAdminID(c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	report, err := h.reportService.StartReview(id, reviewerID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	h.auditService.Record(auditActor(c), services.AuditActionReportReview, services.AuditTargetReport,
+		strconv.FormatInt(id, 10), nil, report)
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ResolveReport closes a report, optionally with its duplicates, and can
+// ban the reported user in the same action.
+func (h *ReportHandler) ResolveReport(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.HandleError(c, errors.NewValidationError("invalid report id"))
+		return
+	}
+
+	var input models.ResolveReportRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.HandleError(c, errors.NewValidationError("outcome and note are required"))
+		return
+	}
+
+	actor := auditActor(c)
+	if input.Block != nil {
+		if actor.AdminID == nil {
+			errors.HandleError(c, errors.NewAuthenticationError("user id not found"))
+			return
+		}
+		allowed, err := h.adminService.HasPermission(*actor.AdminID, auth.PermissionBlockedWrite)
+		if err != nil {
+			errors.HandleError(c, errors.NewInternalError("failed to check permissions"))
+			return
+		}
+		if !allowed {
+			errors.HandleError(c, errors.NewForbiddenError("banning the reported user needs "+string(auth.PermissionBlockedWrite)))
+			return
+		}
+	}
+
+	resolveInput := services.ResolveReportInput{
+		ReportID:          id,
+		Outcome:           input.Outcome,
+		Note:              input.Note,
+		ResolvedBy:        actor.AdminID,
+		IncludeDuplicates: input.IncludeDuplicates,
+	}
+	if input.Block != nil {
+		resolveInput.Block = &services.BlockInput{
+			ReasonCode:     input.Block.ReasonCode,
+			Notes:          input.Block.Reason,
+			Enforcement:    input.Block.Enforcement,
+			ExpiresAt:      input.Block.ExpiresAt,
+			Permanent:      input.Block.Permanent,
+			Override:       input.Block.Override,
+			OverrideReason: input.Block.OverrideReason,
+		}
+	}
+
+	result, err := h.reportService.ResolveReport(resolveInput)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	for _, report := range result.Reports {
+		h.auditService.Record(actor, services.AuditActionReportResolve, services.AuditTargetReport,
+			strconv.FormatInt(report.ID, 10), nil, report)
+	}
+
+	var blocked *data.Blocked
+	if result.Block != nil {
+		blocked = result.Block.Blocked
+		if result.Block.Applied {
+			h.auditService.Record(actor, services.AuditActionBlockedCreate, services.AuditTargetBlocked,
+				strconv.FormatInt(blocked.ID, 10), nil, blocked)
+			if blocked.PolicyOverride {
+				h.auditService.Record(actor, services.AuditActionBlockedOverride, services.AuditTargetBlocked,
+					strconv.FormatInt(blocked.ID, 10), result.Block.Proposal, blocked)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reports": result.Reports,
+		"blocked": blocked,
+	})
+}
@@ -3,8 +3,10 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/valu/vemeet-admin-api/internal/data"
 	"github.com/valu/vemeet-admin-api/internal/errors"
 	"github.com/valu/vemeet-admin-api/internal/services"
 )
@@ -47,13 +49,34 @@ func (h *UserHandler) GetUserById(c *gin.Context) {
 }
 
 func (h *UserHandler) GetUsers(c *gin.Context) {
-	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	page, limit, err := parsePagination(c)
 	if err != nil {
-		errors.HandleError(c, errors.NewValidationError("invalid page"))
+		errors.HandleError(c, err)
+		return
+	}
+
+	sort := c.DefaultQuery("sort", "id")
+	order := c.DefaultQuery("order", "asc")
+
+	filter, err := parseUserFilter(c)
+	if err != nil {
+		errors.HandleError(c, err)
 		return
 	}
 
-	limit, err := strconv.ParseInt(c.DefaultQuery("pageSize", "10"), 10, 64)
+	users, err := h.userService.GetUsers(page, limit, sort, order, filter)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// ExportUsersGeoJSON serves the users matching the list filters as a
+// GeoJSON FeatureCollection. Users without city coordinates are left out.
+func (h *UserHandler) ExportUsersGeoJSON(c *gin.Context) {
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", strconv.Itoa(services.MaxUserGeoExport)), 10, 64)
 	if err != nil {
 		errors.HandleError(c, errors.NewValidationError("invalid limit"))
 		return
@@ -61,13 +84,116 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
 
 	sort := c.DefaultQuery("sort", "id")
 	order := c.DefaultQuery("order", "asc")
-	search := c.DefaultQuery("search", "")
 
-	users, err := h.userService.GetUsers(page, limit, sort, order, search)
+	filter, err := parseUserFilter(c)
 	if err != nil {
 		errors.HandleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, users)
+	collection, err := h.userService.ExportUsersGeoJSON(sort, order, filter, limit)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/geo+json")
+	c.Header("Content-Disposition", `attachment; filename="users.geojson"`)
+	c.JSON(http.StatusOK, collection)
+}
+
+// parseUserFilter reads the user list filters. gender and country_iso_code
+// take comma separated lists; created_from and created_to take RFC 3339
+// timestamps or dates. A radius search takes lat, lng and radius_km
+// together, and bbox is min_lng,min_lat,max_lng,max_lat as in GeoJSON.
+func parseUserFilter(c *gin.Context) (data.UserFilter, error) {
+	filter := data.UserFilter{
+		Search:          c.Query("search"),
+		Genders:         parseListQuery(c, "gender"),
+		CountryIsoCodes: parseListQuery(c, "country_iso_code"),
+		CityName:        c.Query("city_name"),
+	}
+
+	var err error
+	for param, target := range map[string]**bool{
+		"verified":      &filter.Verified,
+		"is_private":    &filter.IsPrivate,
+		"inbox_locked":  &filter.InboxLocked,
+		"swiper_mode":   &filter.SwiperMode,
+		"blocked":       &filter.Blocked,
+		"shadow_banned": &filter.ShadowBanned,
+	} {
+		if *target, err = parseBoolQuery(c, param); err != nil {
+			return filter, err
+		}
+	}
+
+	if filter.MinAge, err = parseIntQuery(c, "min_age"); err != nil {
+		return filter, err
+	}
+	if filter.MaxAge, err = parseIntQuery(c, "max_age"); err != nil {
+		return filter, err
+	}
+
+	if filter.CreatedFrom, err = parseTimeQuery(c, "created_from"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = parseTimeQuery(c, "created_to"); err != nil {
+		return filter, err
+	}
+
+	if filter.Radius, err = parseGeoRadius(c); err != nil {
+		return filter, err
+	}
+	if filter.Box, err = parseGeoBox(c); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+func parseGeoRadius(c *gin.Context) (*data.GeoRadius, error) {
+	lat, lng, km := c.Query("lat"), c.Query("lng"), c.Query("radius_km")
+	if lat == "" && lng == "" && km == "" {
+		return nil, nil
+	}
+	if lat == "" || lng == "" || km == "" {
+		return nil, errors.NewValidationError("lat, lng and radius_km must be given together")
+	}
+
+	var radius data.GeoRadius
+	var err error
+	if radius.Center.Lat, err = strconv.ParseFloat(lat, 64); err != nil {
+		return nil, errors.NewValidationError("invalid lat")
+	}
+	if radius.Center.Lng, err = strconv.ParseFloat(lng, 64); err != nil {
+		return nil, errors.NewValidationError("invalid lng")
+	}
+	if radius.Km, err = strconv.ParseFloat(km, 64); err != nil {
+		return nil, errors.NewValidationError("invalid radius_km")
+	}
+	return &radius, nil
+}
+
+func parseGeoBox(c *gin.Context) (*data.GeoBox, error) {
+	value := c.Query("bbox")
+	if value == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return nil, errors.NewValidationError("invalid bbox, expected min_lng,min_lat,max_lng,max_lat")
+	}
+
+	var coords [4]float64
+	for i, part := range parts {
+		coord, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, errors.NewValidationError("invalid bbox, expected min_lng,min_lat,max_lng,max_lat")
+		}
+		coords[i] = coord
+	}
+
+	return &data.GeoBox{MinLng: coords[0], MinLat: coords[1], MaxLng: coords[2], MaxLat: coords[3]}, nil
 }
@@ -7,10 +7,13 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/valu/vemeet-admin-api/internal/auth"
+	"github.com/valu/vemeet-admin-api/internal/services"
 )
 
 func AuthMiddleware(
 	tokenManager *auth.TokenManager,
+	sessionService services.SessionServiceInterface,
+	apiKeyService services.ApiKeyServiceInterface,
 ) gin.HandlerFunc {
 
 	return func(c *gin.Context) {
@@ -35,14 +38,36 @@ func AuthMiddleware(
 			return
 		}
 
-		userID, err := tokenManager.ValidateToken(tokenParts[1], "access")
+		if strings.HasPrefix(tokenParts[1], services.ApiKeyPrefix) {
+			key, err := apiKeyService.Authenticate(tokenParts[1], c.ClientIP())
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+				c.Abort()
+				return
+			}
+
+			c.Set("user_id", fmt.Sprintf("%v", key.AdminID))
+			c.Set("api_key_id", fmt.Sprintf("%v", key.ID))
+			c.Set("api_key_scopes", key.Scopes)
+			c.Next()
+			return
+		}
+
+		claims, err := tokenManager.ParseToken(tokenParts[1], auth.TokenTypeAccess)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication token"})
 			c.Abort()
 			return
 		}
 
-		c.Set("user_id", fmt.Sprintf("%v", userID))
+		if claims.SessionID == "" || !sessionService.IsSessionActive(claims.SessionID) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", fmt.Sprintf("%v", claims.UserID))
+		c.Set("session_id", claims.SessionID)
 		c.Next()
 	}
 }
@@ -58,3 +83,16 @@ func RequireAuthenticatedUser() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequireSession rejects API keys on endpoints that act on the caller's own
+// login, such as password changes, MFA and key management.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("session_id") == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Interactive session required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/valu/vemeet-admin-api/internal/auth"
+	"github.com/valu/vemeet-admin-api/internal/services"
+)
+
+func RequirePermission(
+	adminService services.AdminServiceInterface,
+	permission auth.Permission,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.ParseInt(c.GetString("user_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			c.Abort()
+			return
+		}
+
+		if scopes, ok := c.Get("api_key_scopes"); ok && !hasScope(scopes.([]string), permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API key is missing scope " + string(permission)})
+			c.Abort()
+			return
+		}
+
+		allowed, err := adminService.HasPermission(userID, permission)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			c.Abort()
+			return
+		}
+
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func hasScope(scopes []string, permission auth.Permission) bool {
+	for _, scope := range scopes {
+		if scope == string(permission) {
+			return true
+		}
+	}
+	return false
+}
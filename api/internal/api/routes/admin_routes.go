@@ -3,8 +3,35 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/valu/vemeet-admin-api/internal/api/handlers"
+	"github.com/valu/vemeet-admin-api/internal/api/middleware"
+	"github.com/valu/vemeet-admin-api/internal/auth"
+	"github.com/valu/vemeet-admin-api/internal/services"
 )
 
-func adminRoutes(r *gin.Engine, adminHandler *handlers.AdminHandler) {
-	r.POST("/v1/admin/create", adminHandler.CreateAdmin)
+func adminRoutes(r *gin.Engine, adminHandler *handlers.AdminHandler, adminService services.AdminServiceInterface) {
+	r.POST("/v1/admin/invites/redeem", adminHandler.RedeemInvite)
+
+	i := r.Group("/v1/admin/invites")
+	i.Use(middleware.RequireAuthenticatedUser())
+	{
+		i.GET("", middleware.RequirePermission(adminService, auth.PermissionAdminsRead), adminHandler.GetInvites)
+		i.POST("", middleware.RequirePermission(adminService, auth.PermissionAdminsWrite), adminHandler.CreateInvite)
+		i.DELETE("/:id", middleware.RequirePermission(adminService, auth.PermissionAdminsWrite), adminHandler.RevokeInvite)
+	}
+
+	a := r.Group("/v1/admins")
+	a.Use(middleware.RequireAuthenticatedUser())
+	{
+		a.GET("", middleware.RequirePermission(adminService, auth.PermissionAdminsRead), adminHandler.GetAdmins)
+		a.GET("/:id", middleware.RequirePermission(adminService, auth.PermissionAdminsRead), adminHandler.GetAdminById)
+		a.PATCH("/:id", middleware.RequirePermission(adminService, auth.PermissionAdminsWrite), adminHandler.UpdateAdmin)
+		a.PUT("/:id/role", middleware.RequirePermission(adminService, auth.PermissionAdminsWrite), adminHandler.ChangeAdminRole)
+		a.POST("/:id/verify", middleware.RequirePermission(adminService, auth.PermissionAdminsWrite), adminHandler.VerifyAdmin)
+		a.POST("/:id/unverify", middleware.RequirePermission(adminService, auth.PermissionAdminsWrite), adminHandler.UnverifyAdmin)
+		a.POST("/:id/deactivate", middleware.RequirePermission(adminService, auth.PermissionAdminsWrite), adminHandler.DeactivateAdmin)
+		a.POST("/:id/reactivate", middleware.RequirePermission(adminService, auth.PermissionAdminsWrite), adminHandler.ReactivateAdmin)
+		a.GET("/login-attempts", middleware.RequirePermission(adminService, auth.PermissionAdminsRead), adminHandler.GetLoginAttempts)
+		a.DELETE("/:id/sessions", middleware.RequirePermission(adminService, auth.PermissionSessionsAny), adminHandler.ForceLogout)
+		a.POST("/:id/unlock", middleware.RequirePermission(adminService, auth.PermissionAdminsWrite), adminHandler.UnlockAdmin)
+	}
 }
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/valu/vemeet-admin-api/internal/api/handlers"
+	"github.com/valu/vemeet-admin-api/internal/api/middleware"
+)
+
+func apiKeyRoutes(r *gin.Engine, apiKeyHandler *handlers.ApiKeyHandler) {
+	k := r.Group("/v1/api-keys")
+	k.Use(middleware.RequireAuthenticatedUser())
+	k.Use(middleware.RequireSession())
+	{
+		k.GET("", apiKeyHandler.GetApiKeys)
+		k.POST("", apiKeyHandler.CreateApiKey)
+		k.DELETE("/:id", apiKeyHandler.RevokeApiKey)
+	}
+}
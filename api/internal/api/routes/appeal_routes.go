@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/valu/vemeet-admin-api/internal/api/handlers"
+	"github.com/valu/vemeet-admin-api/internal/api/middleware"
+	"github.com/valu/vemeet-admin-api/internal/auth"
+	"github.com/valu/vemeet-admin-api/internal/services"
+)
+
+// appealRoutes serves both the admin tool and internal services. Callers
+// holding an API key scoped to appeals:submit file appeals and comments on
+// the banned user's behalf.
+func appealRoutes(r *gin.Engine, appealHandler *handlers.AppealHandler, adminService services.AdminServiceInterface) {
+	a := r.Group("/v1/appeals")
+	a.Use(middleware.RequireAuthenticatedUser())
+	{
+		a.GET("", middleware.RequirePermission(adminService, auth.PermissionAppealsRead), appealHandler.GetAppeals)
+		a.GET("/:id", middleware.RequirePermission(adminService, auth.PermissionAppealsRead), appealHandler.GetAppealById)
+		a.POST("", middleware.RequirePermission(adminService, auth.PermissionAppealsSubmit), appealHandler.SubmitAppeal)
+		a.POST("/:id/comments", middleware.RequirePermission(adminService, auth.PermissionAppealsSubmit), appealHandler.AddComment)
+		a.POST("/:id/assign", middleware.RequirePermission(adminService, auth.PermissionAppealsReview), appealHandler.AssignReviewer)
+		a.POST("/:id/decide", middleware.RequirePermission(adminService, auth.PermissionAppealsReview), appealHandler.DecideAppeal)
+	}
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/valu/vemeet-admin-api/internal/api/handlers"
+	"github.com/valu/vemeet-admin-api/internal/api/middleware"
+	"github.com/valu/vemeet-admin-api/internal/auth"
+	"github.com/valu/vemeet-admin-api/internal/services"
+)
+
+func auditRoutes(r *gin.Engine, auditHandler *handlers.AuditHandler, adminService services.AdminServiceInterface) {
+	a := r.Group("/v1/audit")
+	a.Use(middleware.RequireAuthenticatedUser())
+	a.Use(middleware.RequirePermission(adminService, auth.PermissionAuditRead))
+	{
+		a.GET("", auditHandler.GetAuditLog)
+		a.GET("/verify", auditHandler.VerifyAuditLog)
+	}
+}
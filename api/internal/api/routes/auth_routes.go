@@ -10,6 +10,17 @@ func authRoutes(r *gin.Engine, authHandler *handlers.AuthHandler) {
 	a := r.Group("/v1/auth")
 
 	a.POST("/login", authHandler.Login)
+	a.POST("/login/mfa", authHandler.LoginMFA)
+	a.POST("/oidc/start", authHandler.OidcStart)
+	a.POST("/oidc/callback", authHandler.OidcCallback)
 	a.POST("/refresh-token", authHandler.RefreshToken)
-	a.GET("/", middleware.RequireAuthenticatedUser(), authHandler.Session)
+	a.POST("/password/forgot", authHandler.ForgotPassword)
+	a.POST("/password/reset", authHandler.ResetPassword)
+	a.POST("/password", middleware.RequireAuthenticatedUser(), middleware.RequireSession(), authHandler.ChangePassword)
+	a.GET("/", middleware.RequireAuthenticatedUser(), middleware.RequireSession(), authHandler.Session)
+	a.GET("/sessions", middleware.RequireAuthenticatedUser(), middleware.RequireSession(), authHandler.GetSessions)
+	a.DELETE("/sessions", middleware.RequireAuthenticatedUser(), middleware.RequireSession(), authHandler.RevokeOtherSessions)
+	a.DELETE("/sessions/:id", middleware.RequireAuthenticatedUser(), middleware.RequireSession(), authHandler.RevokeSession)
+	a.POST("/mfa/totp/setup", middleware.RequireAuthenticatedUser(), middleware.RequireSession(), authHandler.SetupTOTP)
+	a.POST("/mfa/totp/confirm", middleware.RequireAuthenticatedUser(), middleware.RequireSession(), authHandler.ConfirmTOTP)
 }
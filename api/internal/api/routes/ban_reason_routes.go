@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/valu/vemeet-admin-api/internal/api/handlers"
+	"github.com/valu/vemeet-admin-api/internal/api/middleware"
+	"github.com/valu/vemeet-admin-api/internal/auth"
+	"github.com/valu/vemeet-admin-api/internal/services"
+)
+
+func banReasonRoutes(r *gin.Engine, banReasonHandler *handlers.BanReasonHandler, adminService services.AdminServiceInterface) {
+	b := r.Group("/v1/ban-reasons")
+	b.Use(middleware.RequireAuthenticatedUser())
+	{
+		b.GET("", middleware.RequirePermission(adminService, auth.PermissionBlockedRead), banReasonHandler.GetBanReasons)
+		b.POST("", middleware.RequirePermission(adminService, auth.PermissionBanReasonsWrite), banReasonHandler.CreateBanReason)
+		b.GET("/:code/ladder", middleware.RequirePermission(adminService, auth.PermissionBlockedRead), banReasonHandler.GetLadder)
+		b.PUT("/:code/ladder", middleware.RequirePermission(adminService, auth.PermissionBanReasonsWrite), banReasonHandler.UpdateLadder)
+		b.POST("/:code/retire", middleware.RequirePermission(adminService, auth.PermissionBanReasonsWrite), banReasonHandler.RetireBanReason)
+	}
+}
@@ -4,15 +4,46 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/valu/vemeet-admin-api/internal/api/handlers"
 	"github.com/valu/vemeet-admin-api/internal/api/middleware"
+	"github.com/valu/vemeet-admin-api/internal/auth"
+	"github.com/valu/vemeet-admin-api/internal/services"
 )
 
-func blockedRoutes(r *gin.Engine, blockedHandlers *handlers.BlockedHandler) {
+func blockedRoutes(r *gin.Engine, blockedHandlers *handlers.BlockedHandler, adminService services.AdminServiceInterface) {
 	b := r.Group("/v1/blocked")
 	b.Use(middleware.RequireAuthenticatedUser())
 	{
-		b.GET("", blockedHandlers.GetBlockeds)
-		b.GET("/:id", blockedHandlers.GetBlockedById)
-		b.PATCH("/:id", blockedHandlers.UpdateBlocked)
-		b.POST("", blockedHandlers.CreateBlocked)
+		b.GET("", middleware.RequirePermission(adminService, auth.PermissionBlockedRead), blockedHandlers.GetBlockeds)
+		b.GET("/:id", middleware.RequirePermission(adminService, auth.PermissionBlockedRead), blockedHandlers.GetBlockedById)
+		b.PATCH("/:id", middleware.RequirePermission(adminService, auth.PermissionBlockedWrite), blockedHandlers.UpdateBlocked)
+		b.POST("", middleware.RequirePermission(adminService, auth.PermissionBlockedWrite), blockedHandlers.CreateBlocked)
+		b.POST("/:id/lift", middleware.RequirePermission(adminService, auth.PermissionBlockedDelete), blockedHandlers.LiftBlocked)
 	}
+
+	r.GET("/v1/users/:id/bans",
+		middleware.RequireAuthenticatedUser(),
+		middleware.RequirePermission(adminService, auth.PermissionUsersRead),
+		middleware.RequirePermission(adminService, auth.PermissionBlockedRead),
+		blockedHandlers.GetUserBans,
+	)
+	r.GET("/v1/users/:id/restrictions",
+		middleware.RequireAuthenticatedUser(),
+		middleware.RequirePermission(adminService, auth.PermissionUsersRead),
+		middleware.RequirePermission(adminService, auth.PermissionBlockedRead),
+		blockedHandlers.GetUserRestrictions,
+	)
+	r.POST("/v1/restrictions/:id/lift",
+		middleware.RequireAuthenticatedUser(),
+		middleware.RequirePermission(adminService, auth.PermissionBlockedDelete),
+		blockedHandlers.LiftRestriction,
+	)
+	r.POST("/v1/users/:id/unblock",
+		middleware.RequireAuthenticatedUser(),
+		middleware.RequirePermission(adminService, auth.PermissionBlockedDelete),
+		blockedHandlers.UnblockUser,
+	)
+	r.GET("/v1/users/:id/ban-proposal",
+		middleware.RequireAuthenticatedUser(),
+		middleware.RequirePermission(adminService, auth.PermissionBlockedWrite),
+		blockedHandlers.GetBanProposal,
+	)
 }
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/valu/vemeet-admin-api/internal/api/handlers"
+	"github.com/valu/vemeet-admin-api/internal/api/middleware"
+	"github.com/valu/vemeet-admin-api/internal/auth"
+	"github.com/valu/vemeet-admin-api/internal/services"
+)
+
+func bulkModerationRoutes(r *gin.Engine, bulkModerationHandler *handlers.BulkModerationHandler, adminService services.AdminServiceInterface) {
+	bm := r.Group("/v1/blocked/bulk")
+	bm.Use(middleware.RequireAuthenticatedUser())
+	bm.Use(middleware.RequirePermission(adminService, auth.PermissionBlockedWrite))
+	{
+		bm.POST("", bulkModerationHandler.BulkBlock)
+		bm.GET("/:id", bulkModerationHandler.GetJob)
+		bm.GET("/:id/items", bulkModerationHandler.GetJobItems)
+	}
+
+	// Import jobs are moderation jobs too, but each group only serves jobs
+	// of its own kind.
+	im := r.Group("/v1/blocked/imports")
+	im.Use(middleware.RequireAuthenticatedUser())
+	{
+		im.GET("", middleware.RequirePermission(adminService, auth.PermissionBlockedRead), bulkModerationHandler.GetBanImports)
+		im.GET("/:id", middleware.RequirePermission(adminService, auth.PermissionBlockedRead), bulkModerationHandler.GetBanImport)
+		im.GET("/:id/rows", middleware.RequirePermission(adminService, auth.PermissionBlockedRead), bulkModerationHandler.GetBanImportRows)
+		im.POST("", middleware.RequirePermission(adminService, auth.PermissionBlockedWrite), bulkModerationHandler.PreviewBanImport)
+		im.POST("/:id/confirm", middleware.RequirePermission(adminService, auth.PermissionBlockedWrite), bulkModerationHandler.ConfirmBanImport)
+	}
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NewEngine returns the engine the API is served from. Only requests
+// arriving from trustedProxies may set the client address through
+// X-Forwarded-For; without any, the client address is always the peer of
+// the connection. API key IP restrictions and login throttling rely on it.
+func NewEngine(trustedProxies []string) (*gin.Engine, error) {
+	r := gin.Default()
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	return r, nil
+}
@@ -0,0 +1,276 @@
+package routes
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/valu/vemeet-admin-api/internal/api/handlers"
+	"github.com/valu/vemeet-admin-api/internal/api/middleware"
+	"github.com/valu/vemeet-admin-api/internal/auth"
+	"github.com/valu/vemeet-admin-api/internal/data"
+	"github.com/valu/vemeet-admin-api/internal/services"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testAdminRepo knows a single active admin and no emails.
+type testAdminRepo struct {
+	data.AdminRepositoryInterface
+	admin *data.Admin
+}
+
+func (r *testAdminRepo) FindById(id int64) (*data.Admin, error) {
+	if r.admin == nil || r.admin.ID != id {
+		return nil, sql.ErrNoRows
+	}
+	return r.admin, nil
+}
+
+func (r *testAdminRepo) FindByEmail(email string) (*data.Admin, error) {
+	return nil, sql.ErrNoRows
+}
+
+type testApiKeyRepo struct {
+	data.ApiKeyRepositoryInterface
+	keys map[string]*data.ApiKey
+}
+
+func (r *testApiKeyRepo) FindByHash(keyHash string) (*data.ApiKey, error) {
+	if key, ok := r.keys[keyHash]; ok {
+		return key, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *testApiKeyRepo) TouchLastUsed(id int64, ip string) error {
+	return nil
+}
+
+// request sends a request from remoteAddr, with X-Forwarded-For set when
+// forwardedFor is not empty.
+func request(r http.Handler, method, path, body, remoteAddr, forwardedFor string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestApiKeyIPRestrictionIgnoresUntrustedForwardedFor(t *testing.T) {
+	const rawKey = services.ApiKeyPrefix + "test-key"
+	apiKeys := services.NewApiKeyService(
+		&testApiKeyRepo{keys: map[string]*data.ApiKey{
+			services.HashOpaqueToken(rawKey): {ID: 1, AdminID: 7, AllowedIPs: []string{"10.0.0.0/8"}},
+		}},
+		&testAdminRepo{admin: &data.Admin{ID: 7, Verified: true}},
+	)
+
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		forwardedFor   string
+		want           int
+	}{
+		{
+			name:       "allowed address",
+			remoteAddr: "10.1.2.3:4000",
+			want:       http.StatusOK,
+		},
+		{
+			name:       "disallowed address",
+			remoteAddr: "203.0.113.5:4000",
+			want:       http.StatusUnauthorized,
+		},
+		{
+			name:         "spoofed forwarded address without a trusted proxy",
+			remoteAddr:   "203.0.113.5:4000",
+			forwardedFor: "10.1.2.3",
+			want:         http.StatusUnauthorized,
+		},
+		{
+			name:           "spoofed forwarded address from an untrusted peer",
+			trustedProxies: []string{"192.0.2.0/24"},
+			remoteAddr:     "203.0.113.5:4000",
+			forwardedFor:   "10.1.2.3",
+			want:           http.StatusUnauthorized,
+		},
+		{
+			name:           "spoofed address prepended before a trusted proxy",
+			trustedProxies: []string{"192.0.2.0/24"},
+			remoteAddr:     "192.0.2.1:4000",
+			forwardedFor:   "10.1.2.3, 203.0.113.5",
+			want:           http.StatusUnauthorized,
+		},
+		{
+			name:           "forwarded address from a trusted proxy",
+			trustedProxies: []string{"192.0.2.0/24"},
+			remoteAddr:     "192.0.2.1:4000",
+			forwardedFor:   "10.1.2.3",
+			want:           http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewEngine(tt.trustedProxies)
+			if err != nil {
+				t.Fatalf("NewEngine: %v", err)
+			}
+			r.Use(middleware.AuthMiddleware(nil, nil, apiKeys))
+			r.GET("/v1/ping", middleware.RequireAuthenticatedUser(), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := request(r, http.MethodGet, "/v1/ping", "", tt.remoteAddr, tt.forwardedFor,
+				http.Header{"Authorization": {"Bearer " + rawKey}})
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewEngineRejectsInvalidProxies(t *testing.T) {
+	if _, err := NewEngine([]string{"not-an-address"}); err == nil {
+		t.Fatal("expected an error for an invalid proxy")
+	}
+}
+
+// testAttemptRepo keeps login attempts and throttles in memory.
+type testAttemptRepo struct {
+	data.LoginAttemptRepositoryInterface
+	attempts  []*data.LoginAttempt
+	throttles map[string]*data.LoginThrottle
+}
+
+func newTestAttemptRepo() *testAttemptRepo {
+	return &testAttemptRepo{throttles: make(map[string]*data.LoginThrottle)}
+}
+
+func (r *testAttemptRepo) Record(attempt *data.LoginAttempt) error {
+	r.attempts = append(r.attempts, attempt)
+	return nil
+}
+
+func (r *testAttemptRepo) GetThrottle(key string) (*data.LoginThrottle, error) {
+	if throttle, ok := r.throttles[key]; ok {
+		copied := *throttle
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (r *testAttemptRepo) RegisterFailure(key string, resetAfter time.Duration) (*data.LoginThrottle, error) {
+	now := time.Now()
+	throttle, ok := r.throttles[key]
+	if !ok {
+		throttle = &data.LoginThrottle{Key: key}
+		r.throttles[key] = throttle
+	}
+	if ok && throttle.LastFailureAt.Before(now.Add(-resetAfter)) {
+		throttle.Failures = 0
+	}
+	throttle.Failures++
+	throttle.LastFailureAt = now
+
+	copied := *throttle
+	return &copied, nil
+}
+
+func (r *testAttemptRepo) Lock(key string, until time.Time) error {
+	if throttle, ok := r.throttles[key]; ok {
+		throttle.LockedUntil = &until
+		throttle.Failures = 0
+	}
+	return nil
+}
+
+func (r *testAttemptRepo) ClearThrottle(key string) error {
+	delete(r.throttles, key)
+	return nil
+}
+
+func TestLoginThrottleIgnoresUntrustedForwardedFor(t *testing.T) {
+	attempts := newTestAttemptRepo()
+	adminRepo := &testAdminRepo{}
+	guard := services.NewLoginGuardService(attempts, adminRepo, services.LoginGuardConfig{
+		MaxFailedAttempts:      5,
+		MaxFailedAttemptsPerIP: 20,
+		LockoutDuration:        15 * time.Minute,
+	})
+	authService := services.NewAuthService(adminRepo, nil, nil, nil, guard, auth.TokenManager{})
+
+	r, err := NewEngine(nil)
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	authRoutes(r, handlers.NewAuthHandler(authService, nil, nil, nil, nil, nil))
+
+	// Every attempt targets another account and claims another address.
+	// The client address stays the peer, so its backoff applies after the
+	// second failure.
+	want := []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}
+	for i, status := range want {
+		body := `{"email": "admin` + strconv.Itoa(i) + `@example.com", "password": "wrong-password"}`
+		w := request(r, http.MethodPost, "/v1/auth/login", body, "203.0.113.5:4000", "10.0.0."+strconv.Itoa(i+1), nil)
+		if w.Code != status {
+			t.Fatalf("attempt %d: status = %d, want %d", i+1, w.Code, status)
+		}
+	}
+
+	for _, attempt := range attempts.attempts {
+		if attempt.IPAddress != "203.0.113.5" {
+			t.Errorf("attempt for %s recorded from %s, want 203.0.113.5", attempt.Email, attempt.IPAddress)
+		}
+	}
+	for key := range attempts.throttles {
+		if strings.HasPrefix(key, "ip:") && key != "ip:203.0.113.5" {
+			t.Errorf("throttle kept for spoofed address %s", key)
+		}
+	}
+}
+
+func TestPasswordResetThrottleIgnoresUntrustedForwardedFor(t *testing.T) {
+	attempts := newTestAttemptRepo()
+	adminRepo := &testAdminRepo{}
+	guard := services.NewLoginGuardService(attempts, adminRepo, services.LoginGuardConfig{
+		MaxResetsPerEmail: 3,
+		MaxResetsPerIP:    2,
+		ResetWindow:       time.Hour,
+	})
+	passwordService := services.NewPasswordService(nil, adminRepo, nil, guard, nil, nil)
+
+	r, err := NewEngine(nil)
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	authRoutes(r, handlers.NewAuthHandler(nil, nil, nil, passwordService, nil, nil))
+
+	// Every request names another email and claims another address, so
+	// only the limit on the peer address can stop the third.
+	want := []int{http.StatusAccepted, http.StatusAccepted, http.StatusTooManyRequests}
+	for i, status := range want {
+		body := `{"email": "admin` + strconv.Itoa(i) + `@example.com"}`
+		w := request(r, http.MethodPost, "/v1/auth/password/forgot", body, "203.0.113.5:4000", "10.0.0."+strconv.Itoa(i+1), nil)
+		if w.Code != status {
+			t.Fatalf("request %d: status = %d, want %d", i+1, w.Code, status)
+		}
+	}
+}
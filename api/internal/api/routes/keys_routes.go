@@ -0,0 +1,10 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/valu/vemeet-admin-api/internal/api/handlers"
+)
+
+func keysRoutes(r *gin.Engine, keysHandler *handlers.KeysHandler) {
+	r.GET("/.well-known/paseto-keys", keysHandler.GetPublicKeys)
+}
@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/valu/vemeet-admin-api/internal/api/handlers"
+	"github.com/valu/vemeet-admin-api/internal/api/middleware"
+	"github.com/valu/vemeet-admin-api/internal/auth"
+	"github.com/valu/vemeet-admin-api/internal/services"
+)
+
+// reportRoutes serves the moderation queue. Reports themselves arrive from
+// the app backend through an API key scoped to reports:submit.
+func reportRoutes(r *gin.Engine, reportHandler *handlers.ReportHandler, adminService services.AdminServiceInterface) {
+	rp := r.Group("/v1/reports")
+	rp.Use(middleware.RequireAuthenticatedUser())
+	{
+		rp.GET("", middleware.RequirePermission(adminService, auth.PermissionReportsRead), reportHandler.GetReports)
+		rp.GET("/queue", middleware.RequirePermission(adminService, auth.PermissionReportsRead), reportHandler.GetQueue)
+		rp.GET("/:id", middleware.RequirePermission(adminService, auth.PermissionReportsRead), reportHandler.GetReportById)
+		rp.POST("", middleware.RequirePermission(adminService, auth.PermissionReportsSubmit), reportHandler.SubmitReport)
+		rp.POST("/:id/review",
+			middleware.RequireSession(),
+			middleware.RequirePermission(adminService, auth.PermissionReportsResolve),
+			reportHandler.StartReview,
+		)
+		// Resolving with a ban also needs blocked:write, which the handler
+		// checks once it knows a ban was asked for.
+		rp.POST("/:id/resolve",
+			middleware.RequireSession(),
+			middleware.RequirePermission(adminService, auth.PermissionReportsResolve),
+			reportHandler.ResolveReport,
+		)
+	}
+}
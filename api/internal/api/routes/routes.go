@@ -10,15 +10,26 @@ import (
 	"github.com/valu/vemeet-admin-api/internal/api/handlers"
 	"github.com/valu/vemeet-admin-api/internal/api/middleware"
 	"github.com/valu/vemeet-admin-api/internal/auth"
+	"github.com/valu/vemeet-admin-api/internal/services"
 )
 
 type Router struct {
-	adminHandler   *handlers.AdminHandler
-	authHandler    *handlers.AuthHandler
-	userHandler    *handlers.UserHandler
-	blockedHandler *handlers.BlockedHandler
-	tokenManager   *auth.TokenManager
-	router         *gin.Engine
+	adminHandler     *handlers.AdminHandler
+	authHandler      *handlers.AuthHandler
+	userHandler      *handlers.UserHandler
+	blockedHandler   *handlers.BlockedHandler
+	keysHandler      *handlers.KeysHandler
+	apiKeyHandler    *handlers.ApiKeyHandler
+	auditHandler     *handlers.AuditHandler
+	banReasonHandler *handlers.BanReasonHandler
+	appealHandler    *handlers.AppealHandler
+	reportHandler    *handlers.ReportHandler
+	bulkHandler      *handlers.BulkModerationHandler
+	tokenManager     *auth.TokenManager
+	adminService     services.AdminServiceInterface
+	sessionService   services.SessionServiceInterface
+	apiKeyService    services.ApiKeyServiceInterface
+	router           *gin.Engine
 }
 
 func NewRouter(
@@ -27,35 +38,63 @@ func NewRouter(
 	authHandler *handlers.AuthHandler,
 	userHandler *handlers.UserHandler,
 	blockedHandler *handlers.BlockedHandler,
+	keysHandler *handlers.KeysHandler,
+	apiKeyHandler *handlers.ApiKeyHandler,
+	auditHandler *handlers.AuditHandler,
+	banReasonHandler *handlers.BanReasonHandler,
+	appealHandler *handlers.AppealHandler,
+	reportHandler *handlers.ReportHandler,
+	bulkHandler *handlers.BulkModerationHandler,
 	tokenManager *auth.TokenManager,
+	adminService services.AdminServiceInterface,
+	sessionService services.SessionServiceInterface,
+	apiKeyService services.ApiKeyServiceInterface,
 ) *Router {
 	return &Router{
 		adminHandler,
 		authHandler,
 		userHandler,
 		blockedHandler,
+		keysHandler,
+		apiKeyHandler,
+		auditHandler,
+		banReasonHandler,
+		appealHandler,
+		reportHandler,
+		bulkHandler,
 		tokenManager,
+		adminService,
+		sessionService,
+		apiKeyService,
 		r,
 	}
 }
 
 func (r *Router) Router() {
 	r.router.Use(gin.Recovery())
+	r.router.Use(middleware.RequestID())
 
 	r.router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000"},
 		AllowMethods:     []string{"PUT", "PATCH", "GET", "POST", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With", "Refresh-Token-X"},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", "X-Request-ID"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
 	r.router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	keysRoutes(r.router, r.keysHandler)
 
-	r.router.Use(middleware.AuthMiddleware(r.tokenManager))
-	adminRoutes(r.router, r.adminHandler)
+	r.router.Use(middleware.AuthMiddleware(r.tokenManager, r.sessionService, r.apiKeyService))
+	adminRoutes(r.router, r.adminHandler, r.adminService)
 	authRoutes(r.router, r.authHandler)
-	userRoutes(r.router, r.userHandler)
-	blockedRoutes(r.router, r.blockedHandler)
+	apiKeyRoutes(r.router, r.apiKeyHandler)
+	auditRoutes(r.router, r.auditHandler, r.adminService)
+	userRoutes(r.router, r.userHandler, r.adminService)
+	blockedRoutes(r.router, r.blockedHandler, r.adminService)
+	banReasonRoutes(r.router, r.banReasonHandler, r.adminService)
+	appealRoutes(r.router, r.appealHandler, r.adminService)
+	reportRoutes(r.router, r.reportHandler, r.adminService)
+	bulkModerationRoutes(r.router, r.bulkHandler, r.adminService)
 }
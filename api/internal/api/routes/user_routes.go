@@ -4,13 +4,17 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/valu/vemeet-admin-api/internal/api/handlers"
 	"github.com/valu/vemeet-admin-api/internal/api/middleware"
+	"github.com/valu/vemeet-admin-api/internal/auth"
+	"github.com/valu/vemeet-admin-api/internal/services"
 )
 
-func userRoutes(r *gin.Engine, userHandler *handlers.UserHandler) {
+func userRoutes(r *gin.Engine, userHandler *handlers.UserHandler, adminService services.AdminServiceInterface) {
 	u := r.Group("/v1/users")
 	u.Use(middleware.RequireAuthenticatedUser())
+	u.Use(middleware.RequirePermission(adminService, auth.PermissionUsersRead))
 	{
 		u.GET("", userHandler.GetUsers)
+		u.GET("/geojson", userHandler.ExportUsersGeoJSON)
 		u.GET("/:id", userHandler.GetUserById)
 		u.GET("/username/:username", userHandler.GetUserByUsername)
 	}
@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LegacyKeyID names the single PASETO_SECRET_KEY when it is used as part of a
+// keyring. Tokens minted before key IDs existed carry no footer and are
+// verified against it.
+const LegacyKeyID = "default"
+
+const symmetricKeySize = 32
+
+// Key is a PASETO key. Secret encrypts v2.local tokens; SigningSeed, when
+// set, is the Ed25519 seed that signs v4.public tokens. The two must differ,
+// since PASETO forbids using one key for both purposes. A key whose NotAfter
+// has passed is retired: it no longer verifies tokens and can be removed
+// from configuration.
+type Key struct {
+	ID          string
+	Secret      []byte
+	SigningSeed []byte
+	NotAfter    *time.Time
+}
+
+// SigningKey returns the Ed25519 key derived from SigningSeed.
+func (k Key) SigningKey() ed25519.PrivateKey {
+	return ed25519.NewKeyFromSeed(k.SigningSeed)
+}
+
+func (k Key) Retired(now time.Time) bool {
+	return k.NotAfter != nil && now.After(*k.NotAfter)
+}
+
+// Keyring holds one active key used for signing and any number of older keys
+// that are still accepted for verification.
+type Keyring struct {
+	active string
+	keys   map[string]Key
+}
+
+func NewKeyring(activeID string, keys []Key) (*Keyring, error) {
+	ring := &Keyring{active: activeID, keys: make(map[string]Key, len(keys))}
+
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("paseto key id is required")
+		}
+		if len(key.Secret) != symmetricKeySize {
+			return nil, fmt.Errorf("paseto key %q must be %d bytes", key.ID, symmetricKeySize)
+		}
+		if key.SigningSeed != nil {
+			if len(key.SigningSeed) != ed25519.SeedSize {
+				return nil, fmt.Errorf("signing seed of paseto key %q must be %d bytes", key.ID, ed25519.SeedSize)
+			}
+			if bytes.Equal(key.SigningSeed, key.Secret) {
+				return nil, fmt.Errorf("paseto key %q must not reuse its secret as signing seed", key.ID)
+			}
+		}
+		if _, exists := ring.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate paseto key id %q", key.ID)
+		}
+		ring.keys[key.ID] = key
+	}
+
+	active, ok := ring.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("active paseto key %q not found", activeID)
+	}
+	if active.Retired(time.Now()) {
+		return nil, fmt.Errorf("active paseto key %q is retired", activeID)
+	}
+
+	return ring, nil
+}
+
+func (k *Keyring) Active() Key {
+	return k.keys[k.active]
+}
+
+// Lookup returns the key with the given ID unless it is unknown or retired.
+func (k *Keyring) Lookup(id string) (Key, bool) {
+	key, ok := k.keys[id]
+	if !ok || key.Retired(time.Now()) {
+		return Key{}, false
+	}
+	return key, true
+}
+
+// Verifying returns every key that still verifies tokens, ordered by ID.
+func (k *Keyring) Verifying() []Key {
+	now := time.Now()
+	keys := make([]Key, 0, len(k.keys))
+	for _, key := range k.keys {
+		if !key.Retired(now) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys
+}
+
+// Retired lists the IDs of keys past their NotAfter date. Every token they
+// signed has expired, so they are safe to delete.
+func (k *Keyring) Retired() []string {
+	now := time.Now()
+	ids := make([]string, 0)
+	for id, key := range k.keys {
+		if key.Retired(now) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ParseKeyList parses a comma separated list of "id:secret" or
+// "id:secret:not_after" entries, where not_after is an RFC 3339 timestamp.
+func ParseKeyList(list string) ([]Key, error) {
+	keys := make([]Key, 0)
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("invalid paseto key entry %q", entry)
+		}
+
+		key := Key{ID: parts[0], Secret: []byte(parts[1])}
+		if len(parts) == 3 {
+			notAfter, err := time.Parse(time.RFC3339, parts[2])
+			if err != nil {
+				return nil, fmt.Errorf("invalid not_after for paseto key %q: %w", parts[0], err)
+			}
+			key.NotAfter = &notAfter
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// ParseSigningSeeds parses a comma separated list of "id:seed" entries, where
+// seed is the hex encoded Ed25519 seed of the key with that ID.
+func ParseSigningSeeds(list string) (map[string][]byte, error) {
+	seeds := make(map[string][]byte)
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, errors.New("invalid paseto signing seed entry, expected id:seed")
+		}
+		seed, err := ParseSigningSeed(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid signing seed for paseto key %q: %w", id, err)
+		}
+		seeds[id] = seed
+	}
+
+	return seeds, nil
+}
+
+// ParseSigningSeed decodes a hex encoded Ed25519 seed.
+func ParseSigningSeed(encoded string) ([]byte, error) {
+	seed, err := hex.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, errors.New("seed must be hex encoded")
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("seed must be %d bytes", ed25519.SeedSize)
+	}
+	return seed, nil
+}
+
+// LoadKeyDir reads every "<id>.key" file in dir. The first line is the
+// secret; an optional "signing_seed=<hex>" line adds the v4.public signing
+// seed and an optional "not_after=<RFC 3339>" line retires the key.
+func LoadKeyDir(dir string) ([]Key, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.key"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	keys := make([]Key, 0, len(paths))
+	for _, path := range paths {
+		key, err := loadKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func loadKeyFile(path string) (Key, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Key{}, fmt.Errorf("failed to open paseto key: %w", err)
+	}
+	defer f.Close()
+
+	key := Key{ID: strings.TrimSuffix(filepath.Base(path), ".key")}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case key.Secret == nil:
+			key.Secret = []byte(line)
+		case strings.HasPrefix(line, "signing_seed="):
+			seed, err := ParseSigningSeed(strings.TrimPrefix(line, "signing_seed="))
+			if err != nil {
+				return Key{}, fmt.Errorf("invalid signing_seed in %s: %w", path, err)
+			}
+			key.SigningSeed = seed
+		case strings.HasPrefix(line, "not_after="):
+			notAfter, err := time.Parse(time.RFC3339, strings.TrimPrefix(line, "not_after="))
+			if err != nil {
+				return Key{}, fmt.Errorf("invalid not_after in %s: %w", path, err)
+			}
+			key.NotAfter = &notAfter
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Key{}, fmt.Errorf("failed to read paseto key: %w", err)
+	}
+
+	return key, nil
+}
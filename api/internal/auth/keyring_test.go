@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testSecret(c byte) []byte {
+	return []byte(strings.Repeat(string(c), symmetricKeySize))
+}
+
+func TestNewKeyring(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		active  string
+		keys    []Key
+		wantErr string
+	}{
+		{
+			name:   "active and older key",
+			active: "k2",
+			keys:   []Key{{ID: "k1", Secret: testSecret('a')}, {ID: "k2", Secret: testSecret('b')}},
+		},
+		{
+			name:    "unknown active key",
+			active:  "k3",
+			keys:    []Key{{ID: "k1", Secret: testSecret('a')}},
+			wantErr: `active paseto key "k3" not found`,
+		},
+		{
+			name:    "retired active key",
+			active:  "k1",
+			keys:    []Key{{ID: "k1", Secret: testSecret('a'), NotAfter: &past}},
+			wantErr: `active paseto key "k1" is retired`,
+		},
+		{
+			name:    "duplicate key id",
+			active:  "k1",
+			keys:    []Key{{ID: "k1", Secret: testSecret('a')}, {ID: "k1", Secret: testSecret('b')}},
+			wantErr: `duplicate paseto key id "k1"`,
+		},
+		{
+			name:    "missing key id",
+			active:  "k1",
+			keys:    []Key{{Secret: testSecret('a')}},
+			wantErr: "paseto key id is required",
+		},
+		{
+			name:    "short secret",
+			active:  "k1",
+			keys:    []Key{{ID: "k1", Secret: []byte("short")}},
+			wantErr: `paseto key "k1" must be 32 bytes`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyring(tt.active, tt.keys)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyringRetirementAndLookup(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	ring, err := NewKeyring("current", []Key{
+		{ID: "current", Secret: testSecret('a')},
+		{ID: "rotating", Secret: testSecret('b'), NotAfter: &future},
+		{ID: "retired", Secret: testSecret('c'), NotAfter: &past},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ring.Active().ID != "current" {
+		t.Errorf("active = %s, want current", ring.Active().ID)
+	}
+
+	tests := []struct {
+		id     string
+		wantOK bool
+	}{
+		{"current", true},
+		{"rotating", true},
+		{"retired", false},
+		{"unknown", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if _, ok := ring.Lookup(tt.id); ok != tt.wantOK {
+			t.Errorf("Lookup(%q) ok = %v, want %v", tt.id, ok, tt.wantOK)
+		}
+	}
+
+	var verifying []string
+	for _, key := range ring.Verifying() {
+		verifying = append(verifying, key.ID)
+	}
+	if want := []string{"current", "rotating"}; !reflect.DeepEqual(verifying, want) {
+		t.Errorf("Verifying = %v, want %v", verifying, want)
+	}
+	if want := []string{"retired"}; !reflect.DeepEqual(ring.Retired(), want) {
+		t.Errorf("Retired = %v, want %v", ring.Retired(), want)
+	}
+}
+
+func TestTokenManagerKeyRotation(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+
+	for _, mode := range []string{TokenModeLocal, TokenModePublic} {
+		t.Run(mode, func(t *testing.T) {
+			cfg := TokenConfig{Mode: mode, Issuer: "issuer", Audience: "audience"}
+			key := func(id string, c byte, notAfter *time.Time) Key {
+				return Key{ID: id, Secret: testSecret(c), SigningSeed: testSecret(c + 1), NotAfter: notAfter}
+			}
+			issue := func(ring *Keyring) string {
+				tm, err := NewTokenManager(ring, cfg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				token, err := tm.CreateToken(1, time.Now().Add(time.Minute), TokenTypeAccess)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return token
+			}
+
+			oldRing, err := NewKeyring("old", []Key{key("old", 'a', nil)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			oldToken := issue(oldRing)
+
+			tests := []struct {
+				name   string
+				keys   []Key
+				wantOK bool
+			}{
+				{name: "old key still verifying", keys: []Key{key("new", 'x', nil), key("old", 'a', nil)}, wantOK: true},
+				{name: "old key retired", keys: []Key{key("new", 'x', nil), key("old", 'a', &past)}},
+				{name: "old key removed", keys: []Key{key("new", 'x', nil)}},
+				{name: "old key id with another secret", keys: []Key{key("new", 'x', nil), key("old", 'm', nil)}},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					ring, err := NewKeyring("new", tt.keys)
+					if err != nil {
+						t.Fatal(err)
+					}
+					tm, err := NewTokenManager(ring, cfg)
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					if _, err := tm.ParseToken(issue(ring), TokenTypeAccess); err != nil {
+						t.Errorf("token from the active key: %v", err)
+					}
+					if _, err := tm.ParseToken(oldToken, TokenTypeAccess); (err == nil) != tt.wantOK {
+						t.Errorf("token from the old key: error = %v, want ok %v", err, tt.wantOK)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestParseKeyList(t *testing.T) {
+	notAfter := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	keys, err := ParseKeyList(" k1:" + string(testSecret('a')) + " ,,k2:" + string(testSecret('b')) + ":2030-01-02T03:04:05Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Key{
+		{ID: "k1", Secret: testSecret('a')},
+		{ID: "k2", Secret: testSecret('b'), NotAfter: &notAfter},
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("keys = %+v, want %+v", keys, want)
+	}
+
+	for _, list := range []string{"k1", "k1:secret:yesterday"} {
+		if _, err := ParseKeyList(list); err == nil {
+			t.Errorf("ParseKeyList(%q) accepted an invalid entry", list)
+		}
+	}
+}
+
+func TestLoadKeyDir(t *testing.T) {
+	dir := t.TempDir()
+	seed := strings.Repeat("0f", 32)
+	files := map[string]string{
+		"k1.key":    string(testSecret('a')) + "\n",
+		"k2.key":    "\n" + string(testSecret('b')) + "\nsigning_seed=" + seed + "\nnot_after=2030-01-02T03:04:05Z\n",
+		"notes.txt": "ignored",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys, err := LoadKeyDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0].ID != "k1" || keys[1].ID != "k2" {
+		t.Fatalf("keys = %+v, want k1 and k2", keys)
+	}
+	if string(keys[1].Secret) != string(testSecret('b')) || keys[1].NotAfter == nil || len(keys[1].SigningSeed) != 32 {
+		t.Errorf("k2 = %+v", keys[1])
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "bad.key"), []byte(string(testSecret('c'))+"\nnot_after=soon\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadKeyDir(dir); err == nil {
+		t.Error("expected an error for an invalid not_after")
+	}
+}
@@ -1,6 +1,11 @@
 package auth
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
@@ -9,26 +14,110 @@ import (
 	"github.com/o1egl/paseto"
 )
 
+const (
+	TokenTypeAccess     = "access"
+	TokenTypeRefresh    = "refresh"
+	TokenTypeMFAPending = "mfa_pending"
+)
+
+const (
+	// TokenModeLocal issues v2.local tokens encrypted with a shared secret.
+	TokenModeLocal = "local"
+	// TokenModePublic issues v4.public tokens signed with Ed25519, which
+	// other services can verify using the published public keys.
+	TokenModePublic = "public"
+)
+
+type TokenConfig struct {
+	Mode     string
+	Issuer   string
+	Audience string
+}
+
 type TokenManager struct {
-	paseto *paseto.V2
-	secret string
+	paseto  *paseto.V2
+	keyring *Keyring
+	cfg     TokenConfig
 }
 
-func NewTokenManager(secret string) *TokenManager {
-	return &TokenManager{
-		paseto: paseto.NewV2(),
-		secret: secret,
+type tokenFooter struct {
+	KeyID string `json:"kid"`
+}
+
+// Claims is the decoded payload of a token. SessionID and TokenID are empty
+// for tokens that are not bound to a server-side session.
+type Claims struct {
+	UserID    int64
+	SessionID string
+	TokenID   string
+	Type      string
+	ExpiresAt time.Time
+}
+
+// PublicKey describes a v4.public verification key as published on the
+// well-known endpoint.
+type PublicKey struct {
+	KeyID     string     `json:"kid"`
+	Version   string     `json:"version"`
+	Purpose   string     `json:"purpose"`
+	PublicKey string     `json:"public_key"`
+	NotAfter  *time.Time `json:"not_after,omitempty"`
+}
+
+func NewTokenManager(keyring *Keyring, cfg TokenConfig) (*TokenManager, error) {
+	switch cfg.Mode {
+	case "":
+		cfg.Mode = TokenModeLocal
+	case TokenModeLocal, TokenModePublic:
+	default:
+		return nil, fmt.Errorf("unknown token mode %q", cfg.Mode)
+	}
+
+	if cfg.Mode == TokenModePublic {
+		if cfg.Issuer == "" || cfg.Audience == "" {
+			return nil, errors.New("issuer and audience are required for public tokens")
+		}
+		if active := keyring.Active(); active.SigningSeed == nil {
+			return nil, fmt.Errorf("active paseto key %q has no signing seed for public tokens", active.ID)
+		}
 	}
+
+	return &TokenManager{
+		paseto:  paseto.NewV2(),
+		keyring: keyring,
+		cfg:     cfg,
+	}, nil
 }
 
 func (tm *TokenManager) CreateToken(userID int64, expiration time.Time, tokenType string) (string, error) {
+	return tm.CreateSessionToken(userID, "", "", expiration, tokenType)
+}
+
+func (tm *TokenManager) CreateSessionToken(userID int64, sessionID, tokenID string, expiration time.Time, tokenType string) (string, error) {
 	claims := map[string]interface{}{
 		"user_id": strconv.FormatInt(userID, 10),
 		"exp":     expiration.Unix(),
 		"type":    tokenType,
 	}
+	if sessionID != "" {
+		claims["sid"] = sessionID
+	}
+	if tokenID != "" {
+		claims["jti"] = tokenID
+	}
+
+	key := tm.keyring.Active()
+	footer := tokenFooter{KeyID: key.ID}
 
-	token, err := tm.paseto.Encrypt([]byte(tm.secret), claims, nil)
+	if tm.cfg.Mode == TokenModePublic {
+		token, err := tm.signPublic(key, userID, expiration, claims, footer)
+		if err != nil {
+			return "", fmt.Errorf("failed to create token: %w", err)
+		}
+		return token, nil
+	}
+
+	token, err := tm.paseto.Encrypt(key.Secret, claims, footer)
 	if err != nil {
 		return "", fmt.Errorf("failed to create token: %w", err)
 	}
@@ -36,48 +125,255 @@ func (tm *TokenManager) CreateToken(userID int64, expiration time.Time, tokenTyp
 	return token, nil
 }
 
+// signPublic adds the registered PASETO claims, which use RFC 3339 strings
+// for times, and signs the token with the key's Ed25519 signing seed.
+func (tm *TokenManager) signPublic(key Key, userID int64, expiration time.Time, claims map[string]interface{}, footer tokenFooter) (string, error) {
+	now := time.Now().UTC()
+
+	if _, ok := claims["jti"]; !ok {
+		jti, err := NewTokenID()
+		if err != nil {
+			return "", err
+		}
+		claims["jti"] = jti
+	}
+
+	claims["iss"] = tm.cfg.Issuer
+	claims["aud"] = tm.cfg.Audience
+	claims["sub"] = strconv.FormatInt(userID, 10)
+	claims["iat"] = now.Format(time.RFC3339)
+	claims["nbf"] = now.Format(time.RFC3339)
+	claims["exp"] = expiration.UTC().Format(time.RFC3339)
+
+	message, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	footerBytes, err := json.Marshal(footer)
+	if err != nil {
+		return "", err
+	}
+
+	return signV4Public(key.SigningKey(), message, footerBytes, nil), nil
+}
+
 func (tm *TokenManager) ValidateToken(token string, expectedType string) (int64, error) {
+	claims, err := tm.ParseToken(token, expectedType)
+	if err != nil {
+		return 0, err
+	}
+
+	return claims.UserID, nil
+}
+
+func (tm *TokenManager) ParseToken(token string, expectedType string) (*Claims, error) {
 	var claims map[string]interface{}
-	var footer string
+	var err error
 
-	err := tm.paseto.Decrypt(token, []byte(tm.secret), &claims, &footer)
+	if tm.cfg.Mode == TokenModePublic {
+		claims, err = tm.verifyPublic(token)
+	} else {
+		claims, err = tm.decrypt(token)
+	}
 	if err != nil {
-		return 0, fmt.Errorf("invalid token: %w", err)
+		return nil, err
 	}
 
 	tokenType, ok := claims["type"].(string)
 	if !ok || tokenType != expectedType {
-		return 0, fmt.Errorf("invalid token type: expected '%s', got '%s'", expectedType, tokenType)
+		return nil, fmt.Errorf("invalid token type: expected '%s', got '%s'", expectedType, tokenType)
 	}
 
 	expClaim, ok := claims["exp"]
 	if !ok {
-		return 0, errors.New("missing expiration claim")
+		return nil, errors.New("missing expiration claim")
 	}
 
-	var expTime time.Time
-
-	switch exp := expClaim.(type) {
-	case float64:
-		expTime = time.Unix(int64(exp), 0)
-	case int64:
-		expTime = time.Unix(exp, 0)
-	default:
-		return 0, errors.New("invalid expiration claim type")
+	expTime, err := parseTimeClaim(expClaim)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiration claim: %w", err)
 	}
 
 	if expTime.Before(time.Now()) {
-		return 0, errors.New("token expired")
+		return nil, errors.New("token expired")
+	}
+
+	if tm.cfg.Mode == TokenModePublic {
+		if err := tm.validateRegisteredClaims(claims); err != nil {
+			return nil, err
+		}
 	}
 
 	userIDStr, ok := claims["user_id"].(string)
 	if !ok {
-		return 0, errors.New("invalid user ID in token")
+		return nil, errors.New("invalid user ID in token")
 	}
 	userID, err := strconv.ParseInt(userIDStr, 10, 64)
 	if err != nil {
-		return 0, fmt.Errorf("invalid user ID format: %w", err)
+		return nil, fmt.Errorf("invalid user ID format: %w", err)
+	}
+
+	sessionID, _ := claims["sid"].(string)
+	tokenID, _ := claims["jti"].(string)
+
+	return &Claims{
+		UserID:    userID,
+		SessionID: sessionID,
+		TokenID:   tokenID,
+		Type:      tokenType,
+		ExpiresAt: expTime,
+	}, nil
+}
+
+func (tm *TokenManager) validateRegisteredClaims(claims map[string]interface{}) error {
+	if iss, _ := claims["iss"].(string); iss != tm.cfg.Issuer {
+		return fmt.Errorf("invalid token issuer '%s'", iss)
+	}
+
+	if aud, _ := claims["aud"].(string); aud != tm.cfg.Audience {
+		return fmt.Errorf("invalid token audience '%s'", aud)
 	}
 
-	return userID, nil
+	if sub, _ := claims["sub"].(string); sub == "" || sub != claims["user_id"] {
+		return errors.New("invalid token subject")
+	}
+
+	nbfClaim, ok := claims["nbf"]
+	if !ok {
+		return errors.New("missing not-before claim")
+	}
+	nbf, err := parseTimeClaim(nbfClaim)
+	if err != nil {
+		return fmt.Errorf("invalid not-before claim: %w", err)
+	}
+	if time.Now().Add(time.Minute).Before(nbf) {
+		return errors.New("token not yet valid")
+	}
+
+	return nil
+}
+
+// PublicKeys returns the verification keys other services need to check
+// v4.public tokens. It is empty in local mode, where keys are secret.
+func (tm *TokenManager) PublicKeys() []PublicKey {
+	keys := make([]PublicKey, 0)
+	if tm.cfg.Mode != TokenModePublic {
+		return keys
+	}
+
+	for _, key := range tm.keyring.Verifying() {
+		if key.SigningSeed == nil {
+			continue
+		}
+		publicKey := key.SigningKey().Public().(ed25519.PublicKey)
+		keys = append(keys, PublicKey{
+			KeyID:     key.ID,
+			Version:   "v4",
+			Purpose:   "public",
+			PublicKey: base64.RawURLEncoding.EncodeToString(publicKey),
+			NotAfter:  key.NotAfter,
+		})
+	}
+
+	return keys
+}
+
+func (tm *TokenManager) Issuer() string {
+	return tm.cfg.Issuer
+}
+
+func (tm *TokenManager) Audience() string {
+	return tm.cfg.Audience
+}
+
+// decrypt picks the verification key from the footer's key ID. Tokens
+// without a footer predate key rotation and are tried against the legacy key.
+func (tm *TokenManager) decrypt(token string) (map[string]interface{}, error) {
+	var footer tokenFooter
+	if err := paseto.ParseFooter(token, &footer); err != nil {
+		footer = tokenFooter{}
+	}
+
+	key, err := tm.lookupKey(footer.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	var rawFooter string
+	if err := tm.paseto.Decrypt(token, key.Secret, &claims, &rawFooter); err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	return claims, nil
+}
+
+func (tm *TokenManager) verifyPublic(token string) (map[string]interface{}, error) {
+	rawFooter, err := parseV4PublicFooter(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	var footer tokenFooter
+	if len(rawFooter) > 0 {
+		if err := json.Unmarshal(rawFooter, &footer); err != nil {
+			return nil, fmt.Errorf("invalid token footer: %w", err)
+		}
+	}
+
+	key, err := tm.lookupKey(footer.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if key.SigningSeed == nil {
+		return nil, fmt.Errorf("invalid token: key id '%s' has no signing seed", key.ID)
+	}
+
+	message, err := verifyV4Public(key.SigningKey().Public().(ed25519.PublicKey), token, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(message, &claims); err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	return claims, nil
+}
+
+func (tm *TokenManager) lookupKey(keyID string) (Key, error) {
+	if keyID == "" {
+		keyID = LegacyKeyID
+	}
+
+	key, ok := tm.keyring.Lookup(keyID)
+	if !ok {
+		return Key{}, fmt.Errorf("invalid token: unknown key id '%s'", keyID)
+	}
+
+	return key, nil
+}
+
+// parseTimeClaim accepts both Unix seconds, used by v2.local tokens, and
+// RFC 3339 strings, used by the registered claims of v4.public tokens.
+func parseTimeClaim(claim interface{}) (time.Time, error) {
+	switch value := claim.(type) {
+	case float64:
+		return time.Unix(int64(value), 0), nil
+	case int64:
+		return time.Unix(value, 0), nil
+	case string:
+		return time.Parse(time.RFC3339, value)
+	default:
+		return time.Time{}, errors.New("invalid time claim type")
+	}
+}
+
+func NewTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
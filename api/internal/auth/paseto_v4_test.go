@@ -0,0 +1,312 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The v4.public vectors 4-S-1 to 4-S-3 from the PASETO test vector suite.
+const (
+	v4VectorSecretKey = "b4cbfb43df4ce210727d953e4a713307fa19bb7d9f85041438d9e11b942a3774" +
+		"1eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2"
+	v4VectorPublicKey = "1eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2"
+	v4VectorPayload   = `{"data":"this is a signed message","exp":"2022-01-01T00:00:00+00:00"}`
+	v4VectorFooter    = `{"kid":"zVhMiPBP9fRf2snEcT7gFTioeA9COcNy9DfgL1W60haN"}`
+)
+
+var v4Vectors = []struct {
+	name     string
+	footer   string
+	implicit string
+	token    string
+}{
+	{
+		name: "4-S-1",
+		token: "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9" +
+			"bg_XBBzds8lTZShVlwwKSgeKpLT3yukTw6JUz3W4h_ExsQV-P0V54zemZDcAxFaSeef1QlXEFtkqxT1ciiQEDA",
+	},
+	{
+		name:   "4-S-2",
+		footer: v4VectorFooter,
+		token: "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9" +
+			"v3Jt8mx_TdM2ceTGoqwrh4yDFn0XsHvvV_D0DtwQxVrJEBMl0F2caAdgnpKlt4p7xBnx1HcO-SPo8FPp214HDw" +
+			".eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9",
+	},
+	{
+		name:     "4-S-3",
+		footer:   v4VectorFooter,
+		implicit: `{"test-vector":"4-S-3"}`,
+		token: "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9" +
+			"NPWciuD3d0o5eXJXG5pJy-DiVEoyPYWs1YSTwWHNJq6DZD3je5gf-0M4JR9ipdUSJbIovzmBECeaWmaqcaP0DQ" +
+			".eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9",
+	},
+}
+
+func v4VectorKeys(t *testing.T) (ed25519.PrivateKey, ed25519.PublicKey) {
+	t.Helper()
+
+	secret, err := hex.DecodeString(v4VectorSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := hex.DecodeString(v4VectorPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ed25519.PrivateKey(secret), ed25519.PublicKey(public)
+}
+
+func TestPreAuthEncode(t *testing.T) {
+	tests := []struct {
+		name   string
+		pieces [][]byte
+		want   string
+	}{
+		{
+			name: "no pieces",
+			want: "\x00\x00\x00\x00\x00\x00\x00\x00",
+		},
+		{
+			name:   "one empty piece",
+			pieces: [][]byte{{}},
+			want:   "\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00",
+		},
+		{
+			name:   "two empty pieces",
+			pieces: [][]byte{{}, {}},
+			want: "\x02\x00\x00\x00\x00\x00\x00\x00" +
+				"\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00",
+		},
+		{
+			name:   "one piece",
+			pieces: [][]byte{[]byte("Paragon")},
+			want:   "\x01\x00\x00\x00\x00\x00\x00\x00\x07\x00\x00\x00\x00\x00\x00\x00Paragon",
+		},
+		{
+			name:   "two pieces",
+			pieces: [][]byte{[]byte("Paragon"), []byte("Initiative")},
+			want: "\x02\x00\x00\x00\x00\x00\x00\x00\x07\x00\x00\x00\x00\x00\x00\x00Paragon" +
+				"\x0a\x00\x00\x00\x00\x00\x00\x00Initiative",
+		},
+		{
+			name:   "piece that looks like an encoding",
+			pieces: [][]byte{[]byte("\x0a\x00\x00\x00\x00\x00\x00\x00Paragon")},
+			want: "\x01\x00\x00\x00\x00\x00\x00\x00\x0f\x00\x00\x00\x00\x00\x00\x00" +
+				"\x0a\x00\x00\x00\x00\x00\x00\x00Paragon",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := preAuthEncode(tt.pieces...); string(got) != tt.want {
+				t.Errorf("preAuthEncode = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignV4PublicVectors(t *testing.T) {
+	privateKey, _ := v4VectorKeys(t)
+
+	for _, tt := range v4Vectors {
+		t.Run(tt.name, func(t *testing.T) {
+			got := signV4Public(privateKey, []byte(v4VectorPayload), []byte(tt.footer), []byte(tt.implicit))
+			if got != tt.token {
+				t.Errorf("token = %s\nwant    %s", got, tt.token)
+			}
+		})
+	}
+}
+
+func TestVerifyV4PublicVectors(t *testing.T) {
+	_, publicKey := v4VectorKeys(t)
+
+	for _, tt := range v4Vectors {
+		t.Run(tt.name, func(t *testing.T) {
+			message, err := verifyV4Public(publicKey, tt.token, []byte(tt.implicit))
+			if err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+			if string(message) != v4VectorPayload {
+				t.Errorf("message = %s, want %s", message, v4VectorPayload)
+			}
+
+			footer, err := parseV4PublicFooter(tt.token)
+			if err != nil || string(footer) != tt.footer {
+				t.Errorf("footer = %q, %v, want %q", footer, err, tt.footer)
+			}
+		})
+	}
+}
+
+func TestVerifyV4PublicRejects(t *testing.T) {
+	_, publicKey := v4VectorKeys(t)
+	otherKey := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize)).Public().(ed25519.PublicKey)
+
+	signed := v4Vectors[1].token
+	body, footer, _ := strings.Cut(strings.TrimPrefix(signed, v4PublicHeader), ".")
+	tamperedFooter := v4Encoding.EncodeToString([]byte(`{"kid":"other"}`))
+
+	tamperedBody, err := v4Encoding.DecodeString(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tamperedBody[0] ^= 1
+
+	tests := []struct {
+		name     string
+		key      ed25519.PublicKey
+		token    string
+		implicit string
+		want     error
+	}{
+		{
+			name:  "tampered footer",
+			key:   publicKey,
+			token: v4PublicHeader + body + "." + tamperedFooter,
+			want:  errV4TokenSignature,
+		},
+		{
+			name:  "missing footer",
+			key:   publicKey,
+			token: v4PublicHeader + body,
+			want:  errV4TokenSignature,
+		},
+		{
+			name:  "footer added to a token signed without one",
+			key:   publicKey,
+			token: v4Vectors[0].token + "." + footer,
+			want:  errV4TokenSignature,
+		},
+		{
+			name:  "tampered message",
+			key:   publicKey,
+			token: v4PublicHeader + v4Encoding.EncodeToString(tamperedBody) + "." + footer,
+			want:  errV4TokenSignature,
+		},
+		{
+			name:  "wrong key",
+			key:   otherKey,
+			token: signed,
+			want:  errV4TokenSignature,
+		},
+		{
+			name:     "wrong implicit assertion",
+			key:      publicKey,
+			token:    v4Vectors[2].token,
+			implicit: `{"test-vector":"4-S-2"}`,
+			want:     errV4TokenSignature,
+		},
+		{
+			name:  "extra dot segment",
+			key:   publicKey,
+			token: signed + "." + footer,
+			want:  errV4TokenFormat,
+		},
+		{
+			name:  "empty footer segment",
+			key:   publicKey,
+			token: v4PublicHeader + body + ".",
+			want:  errV4TokenFormat,
+		},
+		{
+			name:  "other version",
+			key:   publicKey,
+			token: "v2.public." + body + "." + footer,
+			want:  errV4TokenFormat,
+		},
+		{
+			name:  "body shorter than a signature",
+			key:   publicKey,
+			token: v4PublicHeader + v4Encoding.EncodeToString(make([]byte, ed25519.SignatureSize-1)),
+			want:  errV4TokenFormat,
+		},
+		{
+			name:  "body that is not base64url",
+			key:   publicKey,
+			token: v4PublicHeader + "not+base64/" + body,
+			want:  errV4TokenFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := verifyV4Public(tt.key, tt.token, []byte(tt.implicit)); err != tt.want {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewKeyringRejectsSigningSeedReuse(t *testing.T) {
+	secret := bytes.Repeat([]byte{1}, symmetricKeySize)
+
+	tests := []struct {
+		name    string
+		seed    []byte
+		wantErr bool
+	}{
+		{name: "no signing seed"},
+		{name: "separate signing seed", seed: bytes.Repeat([]byte{2}, ed25519.SeedSize)},
+		{name: "secret reused as signing seed", seed: secret, wantErr: true},
+		{name: "short signing seed", seed: []byte{2}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyring("k1", []Key{{ID: "k1", Secret: secret, SigningSeed: tt.seed}})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPublicTokensNeedSigningSeed(t *testing.T) {
+	secret := bytes.Repeat([]byte{1}, symmetricKeySize)
+	cfg := TokenConfig{Mode: TokenModePublic, Issuer: "issuer", Audience: "audience"}
+
+	withoutSeed, err := NewKeyring("k1", []Key{{ID: "k1", Secret: secret}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTokenManager(withoutSeed, cfg); err == nil {
+		t.Error("expected public mode to require a signing seed")
+	}
+
+	seed := bytes.Repeat([]byte{2}, ed25519.SeedSize)
+	withSeed, err := NewKeyring("k1", []Key{{ID: "k1", Secret: secret, SigningSeed: seed}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm, err := NewTokenManager(withSeed, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := tm.PublicKeys()
+	want := v4Encoding.EncodeToString(ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey))
+	if len(keys) != 1 || keys[0].PublicKey != want {
+		t.Errorf("public keys = %+v, want the key of the signing seed", keys)
+	}
+
+	token, err := tm.CreateSessionToken(42, "session", "token", time.Now().Add(time.Minute), TokenTypeAccess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := tm.ParseToken(token, TokenTypeAccess)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if claims.UserID != 42 || claims.SessionID != "session" || claims.TokenID != "token" {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	if leaked := v4Encoding.EncodeToString(ed25519.NewKeyFromSeed(secret).Public().(ed25519.PublicKey)); keys[0].PublicKey == leaked {
+		t.Error("public key is derived from the symmetric secret")
+	}
+}
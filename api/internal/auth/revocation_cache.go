@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// RevocationCache remembers, for a short time, whether a session was found
+// active or revoked so the auth middleware does not hit the database on every
+// request. Revocations made by this process are recorded immediately; other
+// replicas pick them up once their entry expires.
+type RevocationCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]revocationEntry
+}
+
+type revocationEntry struct {
+	revoked   bool
+	checkedAt time.Time
+}
+
+func NewRevocationCache(ttl time.Duration) *RevocationCache {
+	return &RevocationCache{
+		ttl:     ttl,
+		entries: make(map[string]revocationEntry),
+	}
+}
+
+func (c *RevocationCache) Get(sessionID string) (revoked bool, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, found := c.entries[sessionID]
+	if !found {
+		return false, false
+	}
+
+	// Revoked sessions never come back, so only active entries go stale.
+	if !entry.revoked && time.Since(entry.checkedAt) > c.ttl {
+		delete(c.entries, sessionID)
+		return false, false
+	}
+
+	return entry.revoked, true
+}
+
+func (c *RevocationCache) Set(sessionID string, revoked bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) > 10000 {
+		c.evictLocked()
+	}
+	c.entries[sessionID] = revocationEntry{revoked: revoked, checkedAt: time.Now()}
+}
+
+func (c *RevocationCache) evictLocked() {
+	for id, entry := range c.entries {
+		if time.Since(entry.checkedAt) > c.ttl {
+			delete(c.entries, id)
+		}
+	}
+}
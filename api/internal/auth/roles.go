@@ -0,0 +1,116 @@
+package auth
+
+type Role string
+
+const (
+	RoleViewer          Role = "viewer"
+	RoleModerator       Role = "moderator"
+	RoleSeniorModerator Role = "senior_moderator"
+	RoleSuperadmin      Role = "superadmin"
+)
+
+type Permission string
+
+const (
+	PermissionUsersRead       Permission = "users:read"
+	PermissionBlockedRead     Permission = "blocked:read"
+	PermissionBlockedWrite    Permission = "blocked:write"
+	PermissionBlockedDelete   Permission = "blocked:delete"
+	PermissionAdminsRead      Permission = "admins:read"
+	PermissionAdminsWrite     Permission = "admins:write"
+	PermissionSessionsAny     Permission = "sessions:revoke_any"
+	PermissionAuditRead       Permission = "audit:read"
+	PermissionBanReasonsWrite Permission = "ban_reasons:write"
+	PermissionAppealsRead     Permission = "appeals:read"
+	PermissionAppealsSubmit   Permission = "appeals:submit"
+	PermissionAppealsReview   Permission = "appeals:review"
+	PermissionReportsRead     Permission = "reports:read"
+	PermissionReportsSubmit   Permission = "reports:submit"
+	PermissionReportsResolve  Permission = "reports:resolve"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleViewer: {
+		PermissionUsersRead,
+		PermissionBlockedRead,
+		PermissionAppealsRead,
+		PermissionReportsRead,
+	},
+	RoleModerator: {
+		PermissionUsersRead,
+		PermissionBlockedRead,
+		PermissionBlockedWrite,
+		PermissionAppealsRead,
+		PermissionAppealsSubmit,
+		PermissionReportsRead,
+		PermissionReportsResolve,
+	},
+	RoleSeniorModerator: {
+		PermissionUsersRead,
+		PermissionBlockedRead,
+		PermissionBlockedWrite,
+		PermissionBlockedDelete,
+		PermissionAdminsRead,
+		PermissionAuditRead,
+		PermissionBanReasonsWrite,
+		PermissionAppealsRead,
+		PermissionAppealsSubmit,
+		PermissionAppealsReview,
+		PermissionReportsRead,
+		PermissionReportsResolve,
+	},
+	RoleSuperadmin: {
+		PermissionUsersRead,
+		PermissionBlockedRead,
+		PermissionBlockedWrite,
+		PermissionBlockedDelete,
+		PermissionAdminsRead,
+		PermissionAdminsWrite,
+		PermissionSessionsAny,
+		PermissionAuditRead,
+		PermissionBanReasonsWrite,
+		PermissionAppealsRead,
+		PermissionAppealsSubmit,
+		PermissionAppealsReview,
+		PermissionReportsRead,
+		PermissionReportsSubmit,
+		PermissionReportsResolve,
+	},
+}
+
+var roleRank = map[Role]int{
+	RoleViewer:          1,
+	RoleModerator:       2,
+	RoleSeniorModerator: 3,
+	RoleSuperadmin:      4,
+}
+
+// Outranks reports whether r grants strictly more than other.
+func (r Role) Outranks(other Role) bool {
+	return roleRank[r] > roleRank[other]
+}
+
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+func (p Permission) Valid() bool {
+	return HasPermission(RoleSuperadmin, p)
+}
+
+func PermissionsForRole(role Role) []Permission {
+	permissions := rolePermissions[role]
+	result := make([]Permission, len(permissions))
+	copy(result, permissions)
+	return result
+}
+
+func HasPermission(role Role, permission Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
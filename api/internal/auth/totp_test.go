@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed of the RFC 6238 test vectors,
+// "12345678901234567890", in base32.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	key, err := totpEncoding.DecodeString(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The RFC lists eight digit codes; six digit codes are their last six.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		if got := totpCode(key, tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTPWindow(t *testing.T) {
+	key, err := totpEncoding.DecodeString(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / totpPeriod
+	code := func(offset int64) string { return totpCode(key, current+offset) }
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current step", secret: rfc6238Secret, code: code(0), wantStep: current, wantOK: true},
+		{name: "previous step", secret: rfc6238Secret, code: code(-1), wantStep: current - 1, wantOK: true},
+		{name: "next step", secret: rfc6238Secret, code: code(1), wantStep: current + 1, wantOK: true},
+		{name: "two steps ago", secret: rfc6238Secret, code: code(-2)},
+		{name: "two steps ahead", secret: rfc6238Secret, code: code(2)},
+		{name: "surrounding whitespace", secret: rfc6238Secret, code: " " + code(0) + "\n", wantStep: current, wantOK: true},
+		{name: "lower case secret", secret: strings.ToLower(rfc6238Secret), code: code(0), wantStep: current, wantOK: true},
+		{name: "wrong code", secret: rfc6238Secret, code: "000000"},
+		{name: "too short", secret: rfc6238Secret, code: code(0)[:5]},
+		{name: "eight digit code", secret: rfc6238Secret, code: "14050471"},
+		{name: "empty code", secret: rfc6238Secret},
+		{name: "secret that is not base32", secret: "not base32!", code: code(0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(tt.secret, tt.code, now)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("ValidateTOTP = %d, %v, want %d, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Fatalf("secret %q decodes to %d bytes, %v", secret, len(key), err)
+	}
+
+	if _, ok := ValidateTOTP(secret, totpCode(key, time.Now().Unix()/totpPeriod), time.Now()); !ok {
+		t.Error("code for a generated secret was rejected")
+	}
+}
@@ -2,13 +2,61 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	DbUrl        string
-	PasetoSecret string
+	DbUrl string
+	// TrustedProxies lists the addresses or CIDR blocks of the reverse
+	// proxies in front of the API. Only they may set the client address
+	// through X-Forwarded-For.
+	TrustedProxies []string
+
+	PasetoSecret      string
+	PasetoKeys        string
+	PasetoKeysDir     string
+	PasetoActiveKeyID string
+	PasetoSigningKeys string
+	PasetoSigningSeed string
+	TokenMode         string
+	TokenIssuer       string
+	TokenAudience     string
+
+	BootstrapAdminEmail    string
+	BootstrapAdminPassword string
+	BootstrapAdminName     string
+
+	LoginMaxFailedAttempts      int
+	LoginMaxFailedAttemptsPerIP int
+	LoginLockoutDuration        time.Duration
+
+	PasswordResetMaxPerEmail int
+	PasswordResetMaxPerIP    int
+	PasswordResetWindow      time.Duration
+
+	PasswordMinLength    int
+	PasswordHistorySize  int
+	PasswordDenylistFile string
+	NotifierKind         string
+	NotifierFilePath     string
+
+	SuspensionSweepInterval time.Duration
+
+	BulkModerationBatchSize int
+	BulkModerationSyncLimit int
+	BulkModerationMaxItems  int
+
+	OidcIssuerURL     string
+	OidcClientID      string
+	OidcClientSecret  string
+	OidcRedirectURL   string
+	OidcRoleMapping   string
+	OidcGroupsClaim   string
+	OidcAutoProvision bool
 }
 
 func LoadConfig() (*Config, error) {
@@ -17,7 +65,94 @@ func LoadConfig() (*Config, error) {
 	}
 
 	return &Config{
-		DbUrl:        os.Getenv("DB_URL"),
-		PasetoSecret: os.Getenv("PASETO_SECRET_KEY"),
+		DbUrl:          os.Getenv("DB_URL"),
+		TrustedProxies: getEnvList("TRUSTED_PROXIES"),
+
+		PasetoSecret:      os.Getenv("PASETO_SECRET_KEY"),
+		PasetoKeys:        os.Getenv("PASETO_KEYS"),
+		PasetoKeysDir:     os.Getenv("PASETO_KEYS_DIR"),
+		PasetoActiveKeyID: os.Getenv("PASETO_ACTIVE_KEY_ID"),
+		PasetoSigningKeys: os.Getenv("PASETO_SIGNING_KEYS"),
+		PasetoSigningSeed: os.Getenv("PASETO_SIGNING_SEED"),
+		TokenMode:         getEnv("TOKEN_MODE", "local"),
+		TokenIssuer:       getEnv("TOKEN_ISSUER", "vemeet-admin-api"),
+		TokenAudience:     getEnv("TOKEN_AUDIENCE", "vemeet-admin"),
+
+		BootstrapAdminEmail:    os.Getenv("BOOTSTRAP_ADMIN_EMAIL"),
+		BootstrapAdminPassword: os.Getenv("BOOTSTRAP_ADMIN_PASSWORD"),
+		BootstrapAdminName:     os.Getenv("BOOTSTRAP_ADMIN_NAME"),
+
+		LoginMaxFailedAttempts:      getEnvInt("LOGIN_MAX_FAILED_ATTEMPTS", 5),
+		LoginMaxFailedAttemptsPerIP: getEnvInt("LOGIN_MAX_FAILED_ATTEMPTS_PER_IP", 20),
+		LoginLockoutDuration:        getEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+
+		PasswordResetMaxPerEmail: getEnvInt("PASSWORD_RESET_MAX_PER_EMAIL", 3),
+		PasswordResetMaxPerIP:    getEnvInt("PASSWORD_RESET_MAX_PER_IP", 10),
+		PasswordResetWindow:      getEnvDuration("PASSWORD_RESET_WINDOW", time.Hour),
+
+		PasswordMinLength:    getEnvInt("PASSWORD_MIN_LENGTH", 12),
+		PasswordHistorySize:  getEnvInt("PASSWORD_HISTORY_SIZE", 5),
+		PasswordDenylistFile: os.Getenv("PASSWORD_DENYLIST_FILE"),
+		NotifierKind:         os.Getenv("NOTIFIER"),
+		NotifierFilePath:     os.Getenv("NOTIFIER_FILE_PATH"),
+
+		SuspensionSweepInterval: getEnvDuration("SUSPENSION_SWEEP_INTERVAL", time.Minute),
+
+		BulkModerationBatchSize: getEnvInt("BULK_MODERATION_BATCH_SIZE", 50),
+		BulkModerationSyncLimit: getEnvInt("BULK_MODERATION_SYNC_LIMIT", 100),
+		BulkModerationMaxItems:  getEnvInt("BULK_MODERATION_MAX_ITEMS", 10000),
+
+		OidcIssuerURL:     os.Getenv("OIDC_ISSUER_URL"),
+		OidcClientID:      os.Getenv("OIDC_CLIENT_ID"),
+		OidcClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+		OidcRedirectURL:   os.Getenv("OIDC_REDIRECT_URL"),
+		OidcRoleMapping:   os.Getenv("OIDC_ROLE_MAPPING"),
+		OidcGroupsClaim:   getEnv("OIDC_GROUPS_CLAIM", "groups"),
+		OidcAutoProvision: getEnvBool("OIDC_AUTO_PROVISION", false),
 	}, nil
 }
+
+func getEnv(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getEnvList splits a comma separated value, dropping empty entries. It
+// returns nil when the variable is unset.
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvDuration falls back for values that do not parse and for zero or
+// negative durations, which no setting here can use.
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
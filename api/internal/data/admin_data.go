@@ -7,12 +7,30 @@ import (
 )
 
 type Admin struct {
-	ID        int64  `json:"id"`
-	Email     string `json:"email"`
-	Password  string `json:"password"`
-	Name      string `json:"name"`
-	Verified  bool   `json:"verified"`
-	CreatedAt string `json:"created_at"`
+	ID            int64      `json:"id"`
+	Email         string     `json:"email"`
+	Password      string     `json:"-"`
+	Name          string     `json:"name"`
+	Verified      bool       `json:"verified"`
+	Role          string     `json:"role"`
+	TOTPEnabled   bool       `json:"totp_enabled"`
+	DeactivatedAt *time.Time `json:"deactivated_at"`
+	CreatedAt     string     `json:"created_at"`
+}
+
+// Active reports whether the admin may sign in and act.
+func (a *Admin) Active() bool {
+	return a.Verified && a.DeactivatedAt == nil
+}
+
+type AdminPagination struct {
+	Admins     []*Admin `json:"admins"`
+	Total      int64    `json:"total"`
+	HasMore    bool     `json:"has_more"`
+	TotalPages int64    `json:"total_pages"`
+	Page       int64    `json:"page"`
+	Sort       string   `json:"sort"`
+	Order      string   `json:"order"`
 }
 
 type AdminRepositoryInterface interface {
@@ -20,7 +38,10 @@ type AdminRepositoryInterface interface {
 	FindById(id int64) (*Admin, error)
 	InserAdmin(admin *Admin) error
 	UpdateAdmin(admin *Admin) error
-	FindAll() ([]*Admin, error)
+	SetDeactivated(id int64, deactivated bool) error
+	FindAll(page int64, limit int64, sort, order, search string) (*AdminPagination, error)
+	Count() (int64, error)
+	CountActiveByRole(role string) (int64, error)
 }
 
 type AdminRepositoryImpl struct {
@@ -31,47 +52,43 @@ func NewAdminRepository(db *sql.DB) AdminRepositoryInterface {
 	return &AdminRepositoryImpl{db}
 }
 
-func (r *AdminRepositoryImpl) FindByEmail(email string) (*Admin, error) {
-	query := `SELECT id, email, password, name, verified, created_at FROM admin_users WHERE email = $1`
-
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	row := r.db.QueryRowContext(ctx, query, email)
+const adminColumns = `id, email, password, name, verified, role, totp_enabled, deactivated_at, created_at`
 
+func scanAdmin(row interface{ Scan(...any) error }) (*Admin, error) {
 	var admin Admin
-	err := row.Scan(&admin.ID, &admin.Email, &admin.Password, &admin.Name, &admin.Verified, &admin.CreatedAt)
+	err := row.Scan(&admin.ID, &admin.Email, &admin.Password, &admin.Name, &admin.Verified, &admin.Role,
+		&admin.TOTPEnabled, &admin.DeactivatedAt, &admin.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
-
 	return &admin, nil
 }
 
-func (r *AdminRepositoryImpl) FindById(id int64) (*Admin, error) {
-	query := `SELECT id, email, password, name, verified, created_at FROM admin_users WHERE id = $1`
+func (r *AdminRepositoryImpl) FindByEmail(email string) (*Admin, error) {
+	query := `SELECT ` + adminColumns + ` FROM admin_users WHERE email = $1`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	row := r.db.QueryRowContext(ctx, query, id)
+	return scanAdmin(r.db.QueryRowContext(ctx, query, email))
+}
 
-	var admin Admin
-	err := row.Scan(&admin.ID, &admin.Email, &admin.Password, &admin.Name, &admin.Verified, &admin.CreatedAt)
-	if err != nil {
-		return nil, err
-	}
+func (r *AdminRepositoryImpl) FindById(id int64) (*Admin, error) {
+	query := `SELECT ` + adminColumns + ` FROM admin_users WHERE id = $1`
 
-	return &admin, nil
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return scanAdmin(r.db.QueryRowContext(ctx, query, id))
 }
 
 func (r *AdminRepositoryImpl) InserAdmin(admin *Admin) error {
-	query := `INSERT INTO admin_users (email, password, name) VALUES ($1, $2, $3)`
+	query := `INSERT INTO admin_users (email, password, name, role, verified) VALUES ($1, $2, $3, $4, $5)`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	_, err := r.db.ExecContext(ctx, query, admin.Email, admin.Password, admin.Name)
+	_, err := r.db.ExecContext(ctx, query, admin.Email, admin.Password, admin.Name, admin.Role, admin.Verified)
 	if err != nil {
 		return err
 	}
@@ -79,13 +96,15 @@ func (r *AdminRepositoryImpl) InserAdmin(admin *Admin) error {
 	return nil
 }
 
+// UpdateAdmin never touches the password; use PasswordRepository.SetPassword
+// so the hash and history stay consistent.
 func (r *AdminRepositoryImpl) UpdateAdmin(admin *Admin) error {
-	query := `UPDATE admin_users SET email = $1, password = $2, name = $3, verified = $4 WHERE id = $5`
+	query := `UPDATE admin_users SET email = $1, name = $2, verified = $3, role = $4 WHERE id = $5`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	_, err := r.db.ExecContext(ctx, query, admin.Email, admin.Password, admin.Name, admin.Verified, admin.ID)
+	_, err := r.db.ExecContext(ctx, query, admin.Email, admin.Name, admin.Verified, admin.Role, admin.ID)
 	if err != nil {
 		return err
 	}
@@ -93,28 +112,94 @@ func (r *AdminRepositoryImpl) UpdateAdmin(admin *Admin) error {
 	return nil
 }
 
-func (r *AdminRepositoryImpl) FindAll() ([]*Admin, error) {
-	query := `SELECT id, email, password, name, verified, created_at FROM admin_users`
+func (r *AdminRepositoryImpl) SetDeactivated(id int64, deactivated bool) error {
+	query := `UPDATE admin_users SET deactivated_at = NULL WHERE id = $1`
+	if deactivated {
+		query = `UPDATE admin_users SET deactivated_at = NOW() WHERE id = $1 AND deactivated_at IS NULL`
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := r.db.QueryContext(ctx, query)
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *AdminRepositoryImpl) FindAll(page int64, limit int64, sort, order, search string) (*AdminPagination, error) {
+	sort, order = listOrder(sort, order, "id", "email", "name", "role", "created_at")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var list listQuery
+	list.search(search, "email", "name")
+
+	var total int64
+	countQuery := `SELECT COUNT(*) FROM admin_users ` + list.clause()
+	if err := r.db.QueryRowContext(ctx, countQuery, list.args()...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + adminColumns + ` FROM admin_users ` + list.clause() + ` ORDER BY ` + sort + ` ` + order + `, id ` + order + ` ` + list.page(page, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, list.params...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var admins []*Admin
+	admins := make([]*Admin, 0)
 	for rows.Next() {
-		var admin Admin
-		err := rows.Scan(&admin.ID, &admin.Email, &admin.Password, &admin.Name, &admin.Verified, &admin.CreatedAt)
+		admin, err := scanAdmin(rows)
 		if err != nil {
 			return nil, err
 		}
+		admins = append(admins, admin)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	totalPages := (total + limit - 1) / limit
+
+	return &AdminPagination{
+		Admins:     admins,
+		Total:      total,
+		HasMore:    page < totalPages,
+		TotalPages: totalPages,
+		Page:       page,
+		Sort:       sort,
+		Order:      order,
+	}, nil
+}
+
+func (r *AdminRepositoryImpl) Count() (int64, error) {
+	query := `SELECT COUNT(*) FROM admin_users`
 
-		admins = append(admins, &admin)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var count int64
+	err := r.db.QueryRowContext(ctx, query).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *AdminRepositoryImpl) CountActiveByRole(role string) (int64, error) {
+	query := `SELECT COUNT(*) FROM admin_users WHERE role = $1 AND verified AND deactivated_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var count int64
+	err := r.db.QueryRowContext(ctx, query, role).Scan(&count)
+	if err != nil {
+		return 0, err
 	}
 
-	return admins, nil
+	return count, nil
 }
@@ -0,0 +1,153 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+type ApiKey struct {
+	ID         int64      `json:"id"`
+	AdminID    int64      `json:"admin_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	AllowedIPs []string   `json:"allowed_ips"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP *string    `json:"last_used_ip"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type ApiKeyRepositoryInterface interface {
+	Create(key *ApiKey) error
+	FindById(id int64) (*ApiKey, error)
+	FindByHash(keyHash string) (*ApiKey, error)
+	FindByAdminId(adminID int64) ([]*ApiKey, error)
+	Revoke(id int64) (bool, error)
+	TouchLastUsed(id int64, ip string) error
+}
+
+type ApiKeyRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewApiKeyRepository(db *sql.DB) ApiKeyRepositoryInterface {
+	return &ApiKeyRepositoryImpl{db}
+}
+
+// Scopes and IP restrictions are TEXT[] columns; they travel as comma
+// separated strings so the driver never has to deal with array types.
+const apiKeyColumns = `id, admin_id, name, key_prefix, key_hash, array_to_string(scopes, ','),
+			  array_to_string(allowed_ips, ','), expires_at, last_used_at, last_used_ip,
+			  revoked_at, created_at`
+
+func scanApiKey(row interface{ Scan(...any) error }) (*ApiKey, error) {
+	var key ApiKey
+	var scopes, allowedIPs string
+	err := row.Scan(&key.ID, &key.AdminID, &key.Name, &key.Prefix, &key.KeyHash, &scopes,
+		&allowedIPs, &key.ExpiresAt, &key.LastUsedAt, &key.LastUsedIP, &key.RevokedAt, &key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	key.Scopes = splitList(scopes)
+	key.AllowedIPs = splitList(allowedIPs)
+	return &key, nil
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
+
+func (r *ApiKeyRepositoryImpl) Create(key *ApiKey) error {
+	query := `INSERT INTO admin_api_keys (admin_id, name, key_prefix, key_hash, scopes, allowed_ips, expires_at)
+			  VALUES ($1, $2, $3, $4, string_to_array($5, ','), string_to_array($6, ','), $7)
+			  RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return r.db.QueryRowContext(ctx, query, key.AdminID, key.Name, key.Prefix, key.KeyHash,
+		strings.Join(key.Scopes, ","), strings.Join(key.AllowedIPs, ","), key.ExpiresAt).
+		Scan(&key.ID, &key.CreatedAt)
+}
+
+func (r *ApiKeyRepositoryImpl) FindById(id int64) (*ApiKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM admin_api_keys WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return scanApiKey(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *ApiKeyRepositoryImpl) FindByHash(keyHash string) (*ApiKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM admin_api_keys WHERE key_hash = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return scanApiKey(r.db.QueryRowContext(ctx, query, keyHash))
+}
+
+func (r *ApiKeyRepositoryImpl) FindByAdminId(adminID int64) ([]*ApiKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM admin_api_keys WHERE admin_id = $1 ORDER BY created_at DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, adminID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]*ApiKey, 0)
+	for rows.Next() {
+		key, err := scanApiKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (r *ApiKeyRepositoryImpl) Revoke(id int64) (bool, error) {
+	query := `UPDATE admin_api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// TouchLastUsed records usage at most once a minute per key so busy scripts
+// do not turn every request into a write.
+func (r *ApiKeyRepositoryImpl) TouchLastUsed(id int64, ip string) error {
+	query := `UPDATE admin_api_keys SET last_used_at = NOW(), last_used_ip = $2
+			  WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, query, id, ip)
+	return err
+}
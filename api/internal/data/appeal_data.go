@@ -0,0 +1,284 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	AppealStatusOpen        = "open"
+	AppealStatusUnderReview = "under_review"
+	AppealStatusUpheld      = "upheld"
+	AppealStatusOverturned  = "overturned"
+)
+
+// AppealSource values say who filed an appeal or wrote a comment: the banned
+// user, relayed by an internal service, or an admin acting for them.
+const (
+	AppealSourceUser  = "user"
+	AppealSourceAdmin = "admin"
+)
+
+type Appeal struct {
+	ID              int64            `json:"id"`
+	BlockedID       int64            `json:"blocked_id"`
+	UserID          int64            `json:"user_id"`
+	Status          string           `json:"status"`
+	Statement       string           `json:"statement"`
+	SubmittedVia    string           `json:"submitted_via"`
+	SubmittedBy     *int64           `json:"submitted_by"`
+	SubmittedApiKey *int64           `json:"submitted_api_key"`
+	ReviewerID      *int64           `json:"reviewer_id"`
+	DecidedBy       *int64           `json:"decided_by"`
+	DecisionNote    *string          `json:"decision_note"`
+	DecidedAt       *time.Time       `json:"decided_at"`
+	CreatedAt       time.Time        `json:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+	Blocked         *Blocked         `json:"blocked,omitempty"`
+	Comments        []*AppealComment `json:"comments,omitempty"`
+}
+
+// Resolved reports whether the appeal has been decided.
+func (a *Appeal) Resolved() bool {
+	return a.Status == AppealStatusUpheld || a.Status == AppealStatusOverturned
+}
+
+type AppealComment struct {
+	ID         int64     `json:"id"`
+	AppealID   int64     `json:"appeal_id"`
+	AuthorType string    `json:"author_type"`
+	AdminID    *int64    `json:"admin_id"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type AppealFilter struct {
+	Status     string
+	ReviewerID *int64
+	UserID     *int64
+	BlockedID  *int64
+}
+
+type AppealPagination struct {
+	Appeals    []*Appeal `json:"appeals"`
+	Total      int64     `json:"total"`
+	HasMore    bool      `json:"has_more"`
+	TotalPages int64     `json:"total_pages"`
+	Page       int64     `json:"page"`
+	Sort       string    `json:"sort"`
+	Order      string    `json:"order"`
+}
+
+type AppealRepositoryInterface interface {
+	FindById(id int64) (*Appeal, error)
+	FindAll(page int64, limit int64, sort, order string, filter AppealFilter) (*AppealPagination, error)
+	FindPendingByBlocked(blockedID int64) (*Appeal, error)
+	Create(appeal *Appeal) error
+	Assign(id int64, reviewerID int64) (*Appeal, error)
+	FindComments(appealID int64) ([]*AppealComment, error)
+	AddComment(comment *AppealComment) error
+}
+
+type AppealRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewAppealRepository(db *sql.DB) AppealRepositoryInterface {
+	return &AppealRepositoryImpl{db}
+}
+
+const appealColumns = `id, blocked_id, user_id, status, statement, submitted_via, submitted_by, submitted_api_key,
+			  reviewer_id, decided_by, decision_note, decided_at, created_at, updated_at`
+
+func scanAppeal(row interface{ Scan(...any) error }) (*Appeal, error) {
+	var appeal Appeal
+	err := row.Scan(&appeal.ID, &appeal.BlockedID, &appeal.UserID, &appeal.Status, &appeal.Statement,
+		&appeal.SubmittedVia, &appeal.SubmittedBy, &appeal.SubmittedApiKey, &appeal.ReviewerID,
+		&appeal.DecidedBy, &appeal.DecisionNote, &appeal.DecidedAt, &appeal.CreatedAt, &appeal.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &appeal, nil
+}
+
+func (r *AppealRepositoryImpl) FindById(id int64) (*Appeal, error) {
+	query := `SELECT ` + appealColumns + ` FROM ban_appeals WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return scanAppeal(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *AppealRepositoryImpl) FindPendingByBlocked(blockedID int64) (*Appeal, error) {
+	query := `SELECT ` + appealColumns + ` FROM ban_appeals
+			  WHERE blocked_id = $1 AND status IN ('open', 'under_review')`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return scanAppeal(r.db.QueryRowContext(ctx, query, blockedID))
+}
+
+func (r *AppealRepositoryImpl) FindAll(page int64, limit int64, sort, order string, filter AppealFilter) (*AppealPagination, error) {
+	sort, order = listOrder(sort, order, "id", "status", "user_id", "created_at", "updated_at")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var list listQuery
+
+	if filter.Status != "" {
+		list.where(`status = ` + list.bind(filter.Status))
+	}
+
+	if filter.ReviewerID != nil {
+		list.where(`reviewer_id = ` + list.bind(*filter.ReviewerID))
+	}
+
+	if filter.UserID != nil {
+		list.where(`user_id = ` + list.bind(*filter.UserID))
+	}
+
+	if filter.BlockedID != nil {
+		list.where(`blocked_id = ` + list.bind(*filter.BlockedID))
+	}
+
+	countArgs := list.args()
+	query := `SELECT ` + appealColumns + ` FROM ban_appeals ` + list.clause() + ` ORDER BY ` + sort + ` ` + order + `, id ` + order + ` ` + list.page(page, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, list.params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appeals := make([]*Appeal, 0)
+	for rows.Next() {
+		appeal, err := scanAppeal(rows)
+		if err != nil {
+			return nil, err
+		}
+		appeals = append(appeals, appeal)
+	}
+
+	countQuery := `SELECT COUNT(*) FROM ban_appeals ` + list.clause()
+	var total int64
+	err = r.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := (total + limit - 1) / limit
+	hasMore := page < totalPages
+
+	return &AppealPagination{
+		Appeals:    appeals,
+		Total:      total,
+		HasMore:    hasMore,
+		TotalPages: totalPages,
+		Page:       page,
+		Sort:       sort,
+		Order:      order,
+	}, nil
+}
+
+func (r *AppealRepositoryImpl) Create(appeal *Appeal) error {
+	query := `INSERT INTO ban_appeals (blocked_id, user_id, statement, submitted_via, submitted_by, submitted_api_key)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  RETURNING ` + appealColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	created, err := scanAppeal(r.db.QueryRowContext(ctx, query, appeal.BlockedID, appeal.UserID, appeal.Statement,
+		appeal.SubmittedVia, appeal.SubmittedBy, appeal.SubmittedApiKey))
+	if err != nil {
+		return err
+	}
+
+	*appeal = *created
+	return nil
+}
+
+// Assign sets the reviewer of an undecided appeal and moves it to
+// under_review. It returns sql.ErrNoRows when the appeal is already decided.
+func (r *AppealRepositoryImpl) Assign(id int64, reviewerID int64) (*Appeal, error) {
+	query := `UPDATE ban_appeals SET reviewer_id = $2, status = 'under_review', updated_at = NOW()
+			  WHERE id = $1 AND status IN ('open', 'under_review')
+			  RETURNING ` + appealColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return scanAppeal(r.db.QueryRowContext(ctx, query, id, reviewerID))
+}
+
+func (r *AppealRepositoryImpl) FindComments(appealID int64) ([]*AppealComment, error) {
+	query := `SELECT id, appeal_id, author_type, admin_id, body, created_at FROM ban_appeal_comments
+			  WHERE appeal_id = $1 ORDER BY created_at, id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, appealID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := make([]*AppealComment, 0)
+	for rows.Next() {
+		var comment AppealComment
+		err := rows.Scan(&comment.ID, &comment.AppealID, &comment.AuthorType, &comment.AdminID,
+			&comment.Body, &comment.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, &comment)
+	}
+
+	return comments, rows.Err()
+}
+
+func (r *AppealRepositoryImpl) AddComment(comment *AppealComment) error {
+	query := `INSERT INTO ban_appeal_comments (appeal_id, author_type, admin_id, body)
+			  VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return r.db.QueryRowContext(ctx, query, comment.AppealID, comment.AuthorType, comment.AdminID, comment.Body).
+		Scan(&comment.ID, &comment.CreatedAt)
+}
+
+// AppealTxRepositoryInterface is the part of the appeal repository that runs
+// inside a unit of work, so deciding an appeal and lifting its ban commit
+// together.
+type AppealTxRepositoryInterface interface {
+	FindById(id int64) (*Appeal, error)
+	Decide(id int64, status string, decidedBy *int64, note string) (*Appeal, error)
+}
+
+type appealTxRepository struct {
+	ctx context.Context
+	tx  *sql.Tx
+}
+
+func (r *appealTxRepository) FindById(id int64) (*Appeal, error) {
+	query := `SELECT ` + appealColumns + ` FROM ban_appeals WHERE id = $1 FOR UPDATE`
+
+	return scanAppeal(r.tx.QueryRowContext(r.ctx, query, id))
+}
+
+// Decide records the outcome. The deciding admin becomes the reviewer when
+// nobody was assigned.
+func (r *appealTxRepository) Decide(id int64, status string, decidedBy *int64, note string) (*Appeal, error) {
+	query := `UPDATE ban_appeals SET status = $2, decided_by = $3, decision_note = $4, decided_at = NOW(),
+			  reviewer_id = COALESCE(reviewer_id, $3), updated_at = NOW()
+			  WHERE id = $1 AND status IN ('open', 'under_review')
+			  RETURNING ` + appealColumns
+
+	return scanAppeal(r.tx.QueryRowContext(r.ctx, query, id, status, decidedBy, note))
+}
@@ -0,0 +1,244 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// AuditGenesisHash is the prev_hash of the first entry in the chain.
+var AuditGenesisHash = strings.Repeat("0", 64)
+
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	AdminID    *int64          `json:"admin_id"`
+	ApiKeyID   *int64          `json:"api_key_id"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	RequestID  string          `json:"request_id"`
+	IPAddress  string          `json:"ip_address"`
+	PrevHash   string          `json:"prev_hash"`
+	Hash       string          `json:"hash"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// ComputeHash hashes every recorded field together with the previous
+// entry's hash, so editing or removing any row breaks the chain after it.
+func (e *AuditEntry) ComputeHash() string {
+	fields, _ := json.Marshal([]interface{}{
+		e.PrevHash,
+		e.AdminID,
+		e.ApiKeyID,
+		e.Action,
+		e.TargetType,
+		e.TargetID,
+		string(e.Before),
+		string(e.After),
+		e.RequestID,
+		e.IPAddress,
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	sum := sha256.Sum256(fields)
+	return hex.EncodeToString(sum[:])
+}
+
+type AuditFilter struct {
+	AdminID    *int64
+	TargetType string
+	TargetID   string
+	Action     string
+	From       *time.Time
+	To         *time.Time
+}
+
+type AuditPagination struct {
+	Entries    []*AuditEntry `json:"entries"`
+	Total      int64         `json:"total"`
+	HasMore    bool          `json:"has_more"`
+	TotalPages int64         `json:"total_pages"`
+	Page       int64         `json:"page"`
+}
+
+type AuditRepositoryInterface interface {
+	Append(entry *AuditEntry) error
+	FindAll(page int64, limit int64, filter AuditFilter) (*AuditPagination, error)
+	FindAfter(afterID int64, limit int64) ([]*AuditEntry, error)
+}
+
+type AuditRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) AuditRepositoryInterface {
+	return &AuditRepositoryImpl{db}
+}
+
+// auditChainLock serializes appends so two writers never link to the same
+// previous entry.
+const auditChainLock = 7307001
+
+const auditColumns = `id, admin_id, api_key_id, action, target_type, target_id, before, after,
+			  request_id, ip_address, prev_hash, hash, created_at`
+
+func scanAuditEntry(row interface{ Scan(...any) error }) (*AuditEntry, error) {
+	var entry AuditEntry
+	var before, after []byte
+	err := row.Scan(&entry.ID, &entry.AdminID, &entry.ApiKeyID, &entry.Action, &entry.TargetType,
+		&entry.TargetID, &before, &after, &entry.RequestID, &entry.IPAddress, &entry.PrevHash,
+		&entry.Hash, &entry.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	entry.Before = before
+	entry.After = after
+	return &entry, nil
+}
+
+func (r *AuditRepositoryImpl) Append(entry *AuditEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, auditChainLock); err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, `SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1`).Scan(&entry.PrevHash)
+	if err == sql.ErrNoRows {
+		entry.PrevHash = AuditGenesisHash
+	} else if err != nil {
+		return err
+	}
+
+	// Postgres keeps microseconds; truncate first so the stored value hashes
+	// the same when it is read back.
+	entry.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	entry.Hash = entry.ComputeHash()
+
+	insert := `INSERT INTO audit_log (admin_id, api_key_id, action, target_type, target_id, before, after,
+			   request_id, ip_address, prev_hash, hash, created_at)
+			   VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`
+	err = tx.QueryRowContext(ctx, insert, entry.AdminID, entry.ApiKeyID, entry.Action, entry.TargetType,
+		entry.TargetID, nullableJSON(entry.Before), nullableJSON(entry.After), entry.RequestID,
+		entry.IPAddress, entry.PrevHash, entry.Hash, entry.CreatedAt).Scan(&entry.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func nullableJSON(value json.RawMessage) interface{} {
+	if len(value) == 0 {
+		return nil
+	}
+	return string(value)
+}
+
+func (r *AuditRepositoryImpl) FindAll(page int64, limit int64, filter AuditFilter) (*AuditPagination, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var list listQuery
+
+	if filter.AdminID != nil {
+		list.where(`admin_id = ` + list.bind(*filter.AdminID))
+	}
+
+	if filter.TargetType != "" {
+		list.where(`target_type = ` + list.bind(filter.TargetType))
+	}
+
+	if filter.TargetID != "" {
+		list.where(`target_id = ` + list.bind(filter.TargetID))
+	}
+
+	if filter.Action != "" {
+		list.where(`action = ` + list.bind(filter.Action))
+	}
+
+	if filter.From != nil {
+		list.where(`created_at >= ` + list.bind(*filter.From))
+	}
+
+	if filter.To != nil {
+		list.where(`created_at < ` + list.bind(*filter.To))
+	}
+
+	var total int64
+	countQuery := `SELECT COUNT(*) FROM audit_log ` + list.clause()
+	if err := r.db.QueryRowContext(ctx, countQuery, list.args()...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + auditColumns + ` FROM audit_log ` + list.clause() + `
+			  ORDER BY id DESC
+			  ` + list.page(page, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, list.params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]*AuditEntry, 0)
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	totalPages := (total + limit - 1) / limit
+
+	return &AuditPagination{
+		Entries:    entries,
+		Total:      total,
+		HasMore:    page < totalPages,
+		TotalPages: totalPages,
+		Page:       page,
+	}, nil
+}
+
+// FindAfter returns entries in chain order, used to verify the chain in
+// batches.
+func (r *AuditRepositoryImpl) FindAfter(afterID int64, limit int64) ([]*AuditEntry, error) {
+	query := `SELECT ` + auditColumns + ` FROM audit_log WHERE id > $1 ORDER BY id ASC LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]*AuditEntry, 0)
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
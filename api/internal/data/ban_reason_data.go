@@ -0,0 +1,176 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	BanSeverityLow      = "low"
+	BanSeverityMedium   = "medium"
+	BanSeverityHigh     = "high"
+	BanSeverityCritical = "critical"
+)
+
+type BanReason struct {
+	Code                   string     `json:"code"`
+	Label                  string     `json:"label"`
+	Description            string     `json:"description"`
+	Severity               string     `json:"severity"`
+	DefaultDurationSeconds *int64     `json:"default_duration_seconds"`
+	CreatedBy              *int64     `json:"created_by"`
+	RetiredAt              *time.Time `json:"retired_at"`
+	CreatedAt              time.Time  `json:"created_at"`
+}
+
+// DefaultExpiry returns when a ban created at from should end, or nil for a
+// permanent ban.
+func (b *BanReason) DefaultExpiry(from time.Time) *time.Time {
+	if b.DefaultDurationSeconds == nil {
+		return nil
+	}
+	expiry := from.Add(time.Duration(*b.DefaultDurationSeconds) * time.Second)
+	return &expiry
+}
+
+type BanReasonCount struct {
+	Code     string  `json:"code"`
+	Label    *string `json:"label"`
+	Severity *string `json:"severity"`
+	Total    int64   `json:"total"`
+	Active   int64   `json:"active"`
+}
+
+type BanReasonRepositoryInterface interface {
+	FindByCode(code string) (*BanReason, error)
+	FindAll(includeRetired bool) ([]*BanReason, error)
+	Create(reason *BanReason) error
+	Retire(code string) (bool, error)
+	CountBans(status string) ([]*BanReasonCount, error)
+}
+
+type BanReasonRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewBanReasonRepository(db *sql.DB) BanReasonRepositoryInterface {
+	return &BanReasonRepositoryImpl{db}
+}
+
+const banReasonColumns = `code, label, description, severity, default_duration_seconds, created_by, retired_at, created_at`
+
+func scanBanReason(row interface{ Scan(...any) error }) (*BanReason, error) {
+	var reason BanReason
+	err := row.Scan(&reason.Code, &reason.Label, &reason.Description, &reason.Severity,
+		&reason.DefaultDurationSeconds, &reason.CreatedBy, &reason.RetiredAt, &reason.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &reason, nil
+}
+
+func (r *BanReasonRepositoryImpl) FindByCode(code string) (*BanReason, error) {
+	query := `SELECT ` + banReasonColumns + ` FROM ban_reason_codes WHERE code = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return scanBanReason(r.db.QueryRowContext(ctx, query, code))
+}
+
+func (r *BanReasonRepositoryImpl) FindAll(includeRetired bool) ([]*BanReason, error) {
+	query := `SELECT ` + banReasonColumns + ` FROM ban_reason_codes`
+	if !includeRetired {
+		query += ` WHERE retired_at IS NULL`
+	}
+	query += ` ORDER BY code`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reasons := make([]*BanReason, 0)
+	for rows.Next() {
+		reason, err := scanBanReason(rows)
+		if err != nil {
+			return nil, err
+		}
+		reasons = append(reasons, reason)
+	}
+
+	return reasons, rows.Err()
+}
+
+func (r *BanReasonRepositoryImpl) Create(reason *BanReason) error {
+	query := `INSERT INTO ban_reason_codes (code, label, description, severity, default_duration_seconds, created_by)
+			  VALUES ($1, $2, $3, $4, $5, $6) RETURNING created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return r.db.QueryRowContext(ctx, query, reason.Code, reason.Label, reason.Description, reason.Severity,
+		reason.DefaultDurationSeconds, reason.CreatedBy).Scan(&reason.CreatedAt)
+}
+
+// Retire hides a code from new bans. Existing bans keep referencing it.
+func (r *BanReasonRepositoryImpl) Retire(code string) (bool, error) {
+	query := `UPDATE ban_reason_codes SET retired_at = NOW() WHERE code = $1 AND retired_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, query, code)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// CountBans groups bans by reason code. Bans created before codes existed
+// are reported under an empty code.
+func (r *BanReasonRepositoryImpl) CountBans(status string) ([]*BanReasonCount, error) {
+	var list listQuery
+	if condition := blockedStatusCondition(status); condition != "" {
+		list.where(condition)
+	}
+
+	query := `SELECT COALESCE(b.reason_code, ''), c.label, c.severity, COUNT(*),
+			  COUNT(*) FILTER (WHERE b.lifted_at IS NULL AND (b.expires_at IS NULL OR b.expires_at > NOW()))
+			  FROM blockeds b
+			  LEFT JOIN ban_reason_codes c ON c.code = b.reason_code
+			  ` + list.clause() + `
+			  GROUP BY b.reason_code, c.label, c.severity
+			  ORDER BY COUNT(*) DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make([]*BanReasonCount, 0)
+	for rows.Next() {
+		var count BanReasonCount
+		if err := rows.Scan(&count.Code, &count.Label, &count.Severity, &count.Total, &count.Active); err != nil {
+			return nil, err
+		}
+		counts = append(counts, &count)
+	}
+
+	return counts, rows.Err()
+}
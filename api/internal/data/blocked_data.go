@@ -4,15 +4,39 @@ import (
 	"context"
 	"database/sql"
 	"strconv"
+	"strings"
 	"time"
 )
 
+const (
+	BlockedStatusActive    = "active"
+	BlockedStatusExpired   = "expired"
+	BlockedStatusPermanent = "permanent"
+)
+
+const (
+	BlockedLiftReasonExpired = "expired"
+	BlockedLiftReasonWarning = "warning"
+	BlockedLiftReasonAppeal  = "appeal_overturned"
+)
+
 type Blocked struct {
-	ID        int64  `json:"id"`
-	UserID    int64  `json:"user_id"`
-	Reason    string `json:"reason"`
-	CreatedAt string `json:"created_at"`
-	User      *User  `json:"user"`
+	ID         int64   `json:"id"`
+	UserID     int64   `json:"user_id"`
+	ReasonCode *string `json:"reason_code"`
+	Reason     string  `json:"reason"` // free-text notes alongside the code
+	BlockedBy  *int64  `json:"blocked_by"`
+	// Enforcement is warning, suspension or permanent.
+	Enforcement    string     `json:"enforcement"`
+	PolicyStep     *int       `json:"policy_step"`
+	PolicyOverride bool       `json:"policy_override"`
+	OverrideReason *string    `json:"override_reason"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	LiftedAt       *time.Time `json:"lifted_at"`
+	LiftedBy       *int64     `json:"lifted_by"`
+	LiftReason     *string    `json:"lift_reason"`
+	CreatedAt      string     `json:"created_at"`
+	User           *User      `json:"user"`
 }
 
 type BlockedPagination struct {
@@ -27,10 +51,14 @@ type BlockedPagination struct {
 
 type BlockedRepositoryInterface interface {
 	FindById(id int64) (*Blocked, error)
-	FindAll(page int64, limit int64, sort, order, search string) (*BlockedPagination, error)
-	Create(blocked *Blocked) (*Blocked, error)
+	FindAll(page int64, limit int64, sort, order, search, status, reasonCode string) (*BlockedPagination, error)
+	FindByUser(userID int64) ([]*Blocked, error)
+	FindActiveByUser(userID int64) ([]*Blocked, error)
+	// FindBannedUserIDs returns which of userIDs have a ban in force.
+	FindBannedUserIDs(userIDs []int64) (map[int64]bool, error)
+	CountByUserAndReason(userID int64, reasonCode string) (int64, error)
 	Update(blocked *Blocked) (*Blocked, error)
-	Delete(id int64) (bool, error)
+	LiftExpired(limit int) ([]*Blocked, error)
 }
 
 type BlockedRepositoryImpl struct {
@@ -41,155 +69,343 @@ func NewBlockedRepository(db *sql.DB) *BlockedRepositoryImpl {
 	return &BlockedRepositoryImpl{DB: db}
 }
 
-func (r *BlockedRepositoryImpl) FindById(id int64) (*Blocked, error) {
-	query := `SELECT id, user_id, reason, created_at FROM blockeds WHERE id = $1`
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+const blockedColumns = `id, user_id, reason_code, reason, blocked_by, enforcement, policy_step, policy_override,
+			  override_reason, expires_at, lifted_at, lifted_by, lift_reason, created_at`
+
+// blockedActiveCondition matches bans that are currently in force.
+const blockedActiveCondition = `lifted_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())`
+
+// blockedStatusCondition matches bans in status, one of the BlockedStatus
+// values. It returns an empty string for any other status.
+func blockedStatusCondition(status string) string {
+	switch status {
+	case BlockedStatusActive:
+		return blockedActiveCondition
+	case BlockedStatusExpired:
+		// Bans that ran out, whether or not the sweep has lifted them yet;
+		// bans an admin lifted are not expired even once their time passes.
+		return `expires_at IS NOT NULL AND expires_at <= NOW()
+				AND (lifted_at IS NULL OR lift_reason = '` + BlockedLiftReasonExpired + `')`
+	case BlockedStatusPermanent:
+		return `expires_at IS NULL AND lifted_at IS NULL`
+	}
+	return ""
+}
 
-	row := r.DB.QueryRowContext(ctx, query, id)
+// suspensionSweepLock is held for the duration of one expiry sweep so only a
+// single replica lifts bans at a time.
+const suspensionSweepLock = 7307002
 
+func scanBlocked(row interface{ Scan(...any) error }) (*Blocked, error) {
 	var blocked Blocked
-	err := row.Scan(&blocked.ID, &blocked.UserID, &blocked.Reason, &blocked.CreatedAt)
+	err := row.Scan(&blocked.ID, &blocked.UserID, &blocked.ReasonCode, &blocked.Reason, &blocked.BlockedBy,
+		&blocked.Enforcement, &blocked.PolicyStep, &blocked.PolicyOverride, &blocked.OverrideReason,
+		&blocked.ExpiresAt, &blocked.LiftedAt, &blocked.LiftedBy, &blocked.LiftReason, &blocked.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
+	return &blocked, nil
+}
 
-	userQuery := userQuery()
+func (r *BlockedRepositoryImpl) findUser(ctx context.Context, id int64) (*User, error) {
+	return scanUser(r.DB.QueryRowContext(ctx, userQuery(), id))
+}
 
-	row = r.DB.QueryRowContext(ctx, userQuery, blocked.UserID)
+func (r *BlockedRepositoryImpl) FindById(id int64) (*Blocked, error) {
+	query := `SELECT ` + blockedColumns + ` FROM blockeds WHERE id = $1`
 
-	user := &User{}
-	err = row.Scan(&user.ID, &user.Username, &user.Birthday, &user.AwsCognitoId, &user.CreatedAt, &user.Verified,
-		&user.IsPrivate, &user.InboxLocked, &user.SwiperMode, &user.Blocked, &user.Name,
-		&user.Gender, &user.CountryName, &user.CountryFlag, &user.CountryIsoCode, &user.CountryLat,
-		&user.CountryLng, &user.CityName, &user.CityLat, &user.CityLng, &user.Bio, &user.ProfileImageID,
-	)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	blocked, err := scanBlocked(r.DB.QueryRowContext(ctx, query, id))
 	if err != nil {
 		return nil, err
 	}
 
-	blocked.User = user
+	blocked.User, err = r.findUser(ctx, blocked.UserID)
+	if err != nil {
+		return nil, err
+	}
 
-	return &blocked, nil
+	return blocked, nil
 }
 
-func (r *BlockedRepositoryImpl) Create(blocked *Blocked) (*Blocked, error) {
-	query := `INSERT INTO blockeds (user_id, reason) VALUES ($1, $2) RETURNING id, created_at`
+// FindByUser returns every ban the user has had, oldest first.
+func (r *BlockedRepositoryImpl) FindByUser(userID int64) ([]*Blocked, error) {
+	query := `SELECT ` + blockedColumns + ` FROM blockeds WHERE user_id = $1 ORDER BY created_at, id`
 
+	return r.queryBlockeds(query, userID)
+}
+
+// FindActiveByUser returns the user's bans that are currently in force.
+func (r *BlockedRepositoryImpl) FindActiveByUser(userID int64) ([]*Blocked, error) {
+	query := `SELECT ` + blockedColumns + ` FROM blockeds
+			  WHERE user_id = $1 AND ` + blockedActiveCondition + `
+			  ORDER BY created_at, id`
+
+	return r.queryBlockeds(query, userID)
+}
+
+func (r *BlockedRepositoryImpl) queryBlockeds(query string, args ...any) ([]*Blocked, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	row := r.DB.QueryRowContext(ctx, query, blocked.UserID, blocked.Reason)
-
-	err := row.Scan(&blocked.ID, &blocked.CreatedAt)
+	rows, err := r.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	userQuery := userQuery()
+	blockeds := make([]*Blocked, 0)
+	for rows.Next() {
+		blocked, err := scanBlocked(rows)
+		if err != nil {
+			return nil, err
+		}
+		blockeds = append(blockeds, blocked)
+	}
+
+	return blockeds, rows.Err()
+}
 
-	row = r.DB.QueryRowContext(ctx, userQuery, blocked.UserID)
+func (r *BlockedRepositoryImpl) FindBannedUserIDs(userIDs []int64) (map[int64]bool, error) {
+	banned := make(map[int64]bool)
+	if len(userIDs) == 0 {
+		return banned, nil
+	}
+
+	values := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		values[i] = strconv.FormatInt(id, 10)
+	}
+
+	query := `SELECT DISTINCT user_id FROM blockeds
+			  WHERE user_id = ANY(string_to_array($1, ',')::bigint[]) AND ` + blockedActiveCondition
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	user := &User{}
-	err = row.Scan(&user.ID, &user.Username, &user.Birthday, &user.AwsCognitoId, &user.CreatedAt, &user.Verified,
-		&user.IsPrivate, &user.InboxLocked, &user.SwiperMode, &user.Blocked, &user.Name,
-		&user.Gender, &user.CountryName, &user.CountryFlag, &user.CountryIsoCode, &user.CountryLat,
-		&user.CountryLng, &user.CityName, &user.CityLat, &user.CityLng, &user.Bio, &user.ProfileImageID,
-	)
+	rows, err := r.DB.QueryContext(ctx, query, strings.Join(values, ","))
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	blocked.User = user
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		banned[id] = true
+	}
 
-	return blocked, nil
+	return banned, rows.Err()
+}
+
+// CountByUserAndReason counts the earlier bans, warnings included, the user
+// received for reasonCode. Bans overturned on appeal were judged wrong and
+// are left out. It drives the escalation ladder.
+func (r *BlockedRepositoryImpl) CountByUserAndReason(userID int64, reasonCode string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return countByUserAndReason(ctx, r.DB, userID, reasonCode)
+}
+
+func countByUserAndReason(ctx context.Context, q interface {
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}, userID int64, reasonCode string) (int64, error) {
+	query := `SELECT COUNT(*) FROM blockeds WHERE user_id = $1 AND reason_code = $2
+			  AND (lift_reason IS NULL OR lift_reason <> $3)`
+
+	var count int64
+	err := q.QueryRowContext(ctx, query, userID, reasonCode, BlockedLiftReasonAppeal).Scan(&count)
+	return count, err
 }
 
+// Update edits the notes and, when ReasonCode is set, the code; the banned
+// user and expiry are fixed once the ban is created.
 func (r *BlockedRepositoryImpl) Update(blocked *Blocked) (*Blocked, error) {
-	query := `UPDATE blockeds SET user_id = $1, reason = $2 WHERE id = $3 RETURNING created_at`
+	query := `UPDATE blockeds SET reason = $1, reason_code = COALESCE($2, reason_code)
+			  WHERE id = $3 RETURNING ` + blockedColumns
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	row := r.DB.QueryRowContext(ctx, query, blocked.UserID, blocked.Reason, blocked.ID)
+	updated, err := scanBlocked(r.DB.QueryRowContext(ctx, query, blocked.Reason, blocked.ReasonCode, blocked.ID))
+	if err != nil {
+		return nil, err
+	}
 
-	err := row.Scan(&blocked.CreatedAt)
+	updated.User, err = r.findUser(ctx, updated.UserID)
 	if err != nil {
 		return nil, err
 	}
 
-	userQuery := userQuery()
+	return updated, nil
+}
 
-	row = r.DB.QueryRowContext(ctx, userQuery, blocked.UserID)
+// LiftExpired closes up to limit bans whose expiry has passed and clears
+// users.blocked for users left without any ban in force. It returns nothing
+// when another replica is already sweeping.
+func (r *BlockedRepositoryImpl) LiftExpired(limit int) ([]*Blocked, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
-	user := &User{}
-	err = row.Scan(&user.ID, &user.Username, &user.Birthday, &user.AwsCognitoId, &user.CreatedAt, &user.Verified,
-		&user.IsPrivate, &user.InboxLocked, &user.SwiperMode, &user.Blocked, &user.Name,
-		&user.Gender, &user.CountryName, &user.CountryFlag, &user.CountryIsoCode, &user.CountryLat,
-		&user.CountryLng, &user.CityName, &user.CityLat, &user.CityLng, &user.Bio, &user.ProfileImageID,
-	)
+	tx, err := r.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	blocked.User = user
+	var locked bool
+	if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, suspensionSweepLock).Scan(&locked); err != nil {
+		return nil, err
+	}
+	if !locked {
+		return []*Blocked{}, nil
+	}
 
-	return blocked, nil
-}
+	lift := `UPDATE blockeds SET lifted_at = NOW(), lift_reason = $2
+			 WHERE id IN (
+				SELECT id FROM blockeds
+				WHERE lifted_at IS NULL AND expires_at <= NOW()
+				ORDER BY expires_at
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			 )
+			 RETURNING ` + blockedColumns
+	rows, err := tx.QueryContext(ctx, lift, limit, BlockedLiftReasonExpired)
+	if err != nil {
+		return nil, err
+	}
 
-func (r *BlockedRepositoryImpl) Delete(id int64) (bool, error) {
-	query := `DELETE FROM blockeds WHERE id = $1`
+	lifted := make([]*Blocked, 0)
+	for rows.Next() {
+		blocked, err := scanBlocked(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		lifted = append(lifted, blocked)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	unblock := `UPDATE users SET blocked = FALSE
+				WHERE id = $1 AND NOT EXISTS (
+					SELECT 1 FROM blockeds WHERE user_id = $1 AND ` + blockedActiveCondition + `
+				)`
+	for _, blocked := range lifted {
+		if _, err := tx.ExecContext(ctx, unblock, blocked.UserID); err != nil {
+			return nil, err
+		}
+	}
 
-	_, err := r.DB.ExecContext(ctx, query, id)
-	if err != nil {
-		return false, err
+	if err := tx.Commit(); err != nil {
+		return nil, err
 	}
 
-	return true, nil
+	return lifted, nil
 }
 
-func (r *BlockedRepositoryImpl) FindAll(page int64, limit int64, sort, order, search string) (*BlockedPagination, error) {
-	allowedSortFields := map[string]bool{
-		"id":         true,
-		"user_id":    true,
-		"created_at": true,
-	}
-	if !allowedSortFields[sort] {
-		sort = "id"
+// BlockedTxRepositoryInterface holds the ban operations available inside a
+// unit of work.
+type BlockedTxRepositoryInterface interface {
+	FindById(id int64) (*Blocked, error)
+	FindActiveByUser(userID int64) ([]*Blocked, error)
+	CountByUserAndReason(userID int64, reasonCode string) (int64, error)
+	Insert(blocked *Blocked) error
+	Lift(id int64, liftedBy *int64, reason string) (*Blocked, error)
+}
+
+type blockedTxRepository struct {
+	ctx context.Context
+	tx  *sql.Tx
+}
+
+func (r *blockedTxRepository) FindById(id int64) (*Blocked, error) {
+	query := `SELECT ` + blockedColumns + ` FROM blockeds WHERE id = $1 FOR UPDATE`
+
+	return scanBlocked(r.tx.QueryRowContext(r.ctx, query, id))
+}
+
+func (r *blockedTxRepository) FindActiveByUser(userID int64) ([]*Blocked, error) {
+	query := `SELECT ` + blockedColumns + ` FROM blockeds
+			  WHERE user_id = $1 AND ` + blockedActiveCondition + `
+			  ORDER BY id
+			  FOR UPDATE`
+
+	rows, err := r.tx.QueryContext(r.ctx, query, userID)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	if order != "asc" && order != "desc" {
-		order = "desc"
+	blockeds := make([]*Blocked, 0)
+	for rows.Next() {
+		blocked, err := scanBlocked(rows)
+		if err != nil {
+			return nil, err
+		}
+		blockeds = append(blockeds, blocked)
 	}
 
-	offset := (page - 1) * limit
+	return blockeds, rows.Err()
+}
+
+func (r *blockedTxRepository) CountByUserAndReason(userID int64, reasonCode string) (int64, error) {
+	return countByUserAndReason(r.ctx, r.tx, userID, reasonCode)
+}
+
+func (r *blockedTxRepository) Insert(blocked *Blocked) error {
+	query := `INSERT INTO blockeds (user_id, reason_code, reason, blocked_by, enforcement, policy_step,
+			  policy_override, override_reason, expires_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at`
+
+	return r.tx.QueryRowContext(r.ctx, query, blocked.UserID, blocked.ReasonCode, blocked.Reason,
+		blocked.BlockedBy, blocked.Enforcement, blocked.PolicyStep, blocked.PolicyOverride,
+		blocked.OverrideReason, blocked.ExpiresAt).
+		Scan(&blocked.ID, &blocked.CreatedAt)
+}
+
+func (r *blockedTxRepository) Lift(id int64, liftedBy *int64, reason string) (*Blocked, error) {
+	query := `UPDATE blockeds SET lifted_at = NOW(), lifted_by = $2, lift_reason = $3
+			  WHERE id = $1 AND lifted_at IS NULL
+			  RETURNING ` + blockedColumns
+
+	return scanBlocked(r.tx.QueryRowContext(r.ctx, query, id, liftedBy, reason))
+}
+
+func (r *BlockedRepositoryImpl) FindAll(page int64, limit int64, sort, order, search, status, reasonCode string) (*BlockedPagination, error) {
+	sort, order = listOrder(sort, order, "id", "user_id", "reason_code", "expires_at", "created_at")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	whereClause := ""
-	queryParams := make([]interface{}, 0)
-	paramCount := 1
+	var list listQuery
 
+	// Search matches the ban notes or the banned user's username.
 	if search != "" {
-		whereClause = `
-						WHERE (
-								LOWER(reason) LIKE $` + strconv.Itoa(paramCount) + ` OR
-								LOWER(created_at) LIKE $` + strconv.Itoa(paramCount) + `
-						)`
-		queryParams = append(queryParams, "%"+search+"%")
-		paramCount++
+		pattern := list.bind(searchPattern(search))
+		list.where(`(LOWER(reason) LIKE ` + pattern + ` OR
+			user_id IN (SELECT id FROM users WHERE LOWER(username) LIKE ` + pattern + `))`)
 	}
 
-	query := `SELECT id, user_id, reason, created_at FROM blockeds ` + whereClause + ` ORDER BY ` + sort + ` ` + order + ` LIMIT $` + strconv.Itoa(paramCount) + ` OFFSET $` + strconv.Itoa(paramCount+1)
+	if reasonCode != "" {
+		list.where(`reason_code = ` + list.bind(reasonCode))
+	}
+
+	if condition := blockedStatusCondition(status); condition != "" {
+		list.where(condition)
+	}
 
-	queryParams = append(queryParams, limit, offset)
+	countArgs := list.args()
+	query := `SELECT ` + blockedColumns + ` FROM blockeds ` + list.clause() + ` ORDER BY ` + sort + ` ` + order + `, id ` + order + ` ` + list.page(page, limit)
 
-	rows, err := r.DB.QueryContext(ctx, query, queryParams...)
+	rows, err := r.DB.QueryContext(ctx, query, list.params...)
 	if err != nil {
 		return nil, err
 	}
@@ -197,16 +413,16 @@ func (r *BlockedRepositoryImpl) FindAll(page int64, limit int64, sort, order, se
 
 	blockeds := make([]*Blocked, 0)
 	for rows.Next() {
-		var blocked Blocked
-		if err := rows.Scan(&blocked.ID, &blocked.UserID, &blocked.Reason, &blocked.CreatedAt); err != nil {
+		blocked, err := scanBlocked(rows)
+		if err != nil {
 			return nil, err
 		}
-		blockeds = append(blockeds, &blocked)
+		blockeds = append(blockeds, blocked)
 	}
 
-	countQuery := `SELECT COUNT(*) FROM blockeds ` + whereClause
+	countQuery := `SELECT COUNT(*) FROM blockeds ` + list.clause()
 	var total int64
-	err = r.DB.QueryRowContext(ctx, countQuery, queryParams[:paramCount-1]...).Scan(&total)
+	err = r.DB.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total)
 	if err != nil {
 		return nil, err
 	}
@@ -226,10 +442,5 @@ func (r *BlockedRepositoryImpl) FindAll(page int64, limit int64, sort, order, se
 }
 
 func userQuery() string {
-	return `SELECT id, username, birthday, aws_cognito_id, created_at, verified, is_private,
-			  inbox_locked, swiper_mode, blocked, COALESCE(name, ''), COALESCE(gender, ''),
-			  COALESCE(country_name, ''), COALESCE(country_flag, ''), COALESCE(country_iso_code, ''),
-			  COALESCE(country_lat, 0), COALESCE(country_lng, 0), COALESCE(city_name, ''),
-			  COALESCE(city_lat, 0), COALESCE(city_lng, 0), COALESCE(bio, ''), COALESCE(profile_image_id, 0)
-			  FROM users WHERE username = $1`
+	return `SELECT ` + userColumns + ` FROM users WHERE id = $1`
 }
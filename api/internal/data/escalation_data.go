@@ -0,0 +1,84 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	EnforcementWarning    = "warning"
+	EnforcementSuspension = "suspension"
+	EnforcementPermanent  = "permanent"
+)
+
+// EscalationStep is one rung of a reason code's enforcement ladder. Steps
+// are numbered from 1; offenders past the last step stay on it.
+type EscalationStep struct {
+	ReasonCode      string `json:"reason_code"`
+	Step            int    `json:"step"`
+	Action          string `json:"action"`
+	DurationSeconds *int64 `json:"duration_seconds"`
+}
+
+type EscalationRepositoryInterface interface {
+	FindByReason(code string) ([]*EscalationStep, error)
+	ReplaceForReason(code string, steps []*EscalationStep) error
+}
+
+type EscalationRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewEscalationRepository(db *sql.DB) EscalationRepositoryInterface {
+	return &EscalationRepositoryImpl{db}
+}
+
+func (r *EscalationRepositoryImpl) FindByReason(code string) ([]*EscalationStep, error) {
+	query := `SELECT reason_code, step, action, duration_seconds FROM ban_escalation_steps
+			  WHERE reason_code = $1 ORDER BY step`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, code)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	steps := make([]*EscalationStep, 0)
+	for rows.Next() {
+		var step EscalationStep
+		if err := rows.Scan(&step.ReasonCode, &step.Step, &step.Action, &step.DurationSeconds); err != nil {
+			return nil, err
+		}
+		steps = append(steps, &step)
+	}
+
+	return steps, rows.Err()
+}
+
+func (r *EscalationRepositoryImpl) ReplaceForReason(code string, steps []*EscalationStep) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM ban_escalation_steps WHERE reason_code = $1`, code); err != nil {
+		return err
+	}
+
+	insert := `INSERT INTO ban_escalation_steps (reason_code, step, action, duration_seconds) VALUES ($1, $2, $3, $4)`
+	for _, step := range steps {
+		if _, err := tx.ExecContext(ctx, insert, code, step.Step, step.Action, step.DurationSeconds); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
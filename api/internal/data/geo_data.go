@@ -0,0 +1,95 @@
+package data
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// earthRadiusKm is the mean radius of the earth used for every distance.
+const earthRadiusKm = 6371.0088
+
+// MaxGeoRadiusKm is half the earth's circumference; every point on the
+// globe lies within it.
+const MaxGeoRadiusKm = math.Pi * earthRadiusKm
+
+// GeoPoint is a position in decimal degrees.
+type GeoPoint struct {
+	Lat float64
+	Lng float64
+}
+
+// GeoBox is an area between two latitudes and two longitudes. A box whose
+// MinLng is greater than its MaxLng crosses the antimeridian.
+type GeoBox struct {
+	MinLat float64
+	MinLng float64
+	MaxLat float64
+	MaxLng float64
+}
+
+// userLocation is the position users are placed at. It must stay the same
+// expression as users_city_location_idx for the index to be used.
+const userLocation = `point(city_lng, city_lat)`
+
+// geoDistanceSQL returns the haversine distance in km between the user's
+// city and point.
+func geoDistanceSQL(q *listQuery, point GeoPoint) string {
+	lat := q.bind(point.Lat) + `::float8`
+	lng := q.bind(point.Lng) + `::float8`
+
+	return `(` + strconv.FormatFloat(2*earthRadiusKm, 'f', -1, 64) + ` * ASIN(LEAST(1, SQRT(
+		POWER(SIN(RADIANS(city_lat - ` + lat + `) / 2), 2) +
+		COS(RADIANS(` + lat + `)) * COS(RADIANS(city_lat)) * POWER(SIN(RADIANS(city_lng - ` + lng + `) / 2), 2)))))`
+}
+
+// geoBoxSQL returns a condition matching users whose city lies in any of
+// boxes. It is written against userLocation so the GiST index can answer
+// it.
+func geoBoxSQL(q *listQuery, boxes []GeoBox) string {
+	matches := make([]string, 0, len(boxes))
+	for _, box := range boxes {
+		matches = append(matches, userLocation+` <@ box(point(`+q.bind(box.MinLng)+`, `+q.bind(box.MinLat)+
+			`), point(`+q.bind(box.MaxLng)+`, `+q.bind(box.MaxLat)+`))`)
+	}
+	return `(` + strings.Join(matches, " OR ") + `)`
+}
+
+// splitGeoBox splits a box that crosses the antimeridian into one box on
+// either side of it.
+func splitGeoBox(box GeoBox) []GeoBox {
+	if box.MinLng <= box.MaxLng {
+		return []GeoBox{box}
+	}
+	return []GeoBox{
+		{MinLat: box.MinLat, MinLng: box.MinLng, MaxLat: box.MaxLat, MaxLng: 180},
+		{MinLat: box.MinLat, MinLng: -180, MaxLat: box.MaxLat, MaxLng: box.MaxLng},
+	}
+}
+
+// radiusGeoBoxes returns boxes that together cover every point within
+// radiusKm of center. They narrow the search through the index before the
+// exact distance is checked.
+func radiusGeoBoxes(center GeoPoint, radiusKm float64) []GeoBox {
+	angle := radiusKm / earthRadiusKm
+	deltaLat := angle * 180 / math.Pi
+
+	minLat := center.Lat - deltaLat
+	maxLat := center.Lat + deltaLat
+	// A circle that reaches a pole spans every longitude.
+	if minLat <= -90 || maxLat >= 90 {
+		return []GeoBox{{MinLat: math.Max(minLat, -90), MinLng: -180, MaxLat: math.Min(maxLat, 90), MaxLng: 180}}
+	}
+
+	deltaLng := math.Asin(math.Sin(angle)/math.Cos(center.Lat*math.Pi/180)) * 180 / math.Pi
+	minLng := center.Lng - deltaLng
+	maxLng := center.Lng + deltaLng
+	if minLng < -180 {
+		minLng += 360
+	}
+	if maxLng > 180 {
+		maxLng -= 360
+	}
+
+	return splitGeoBox(GeoBox{MinLat: minLat, MinLng: minLng, MaxLat: maxLat, MaxLng: maxLng})
+}
@@ -0,0 +1,140 @@
+package data
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSplitGeoBox(t *testing.T) {
+	tests := []struct {
+		name string
+		box  GeoBox
+		want []GeoBox
+	}{
+		{
+			name: "box that does not cross the antimeridian",
+			box:  GeoBox{MinLat: 40, MinLng: -10, MaxLat: 50, MaxLng: 10},
+			want: []GeoBox{{MinLat: 40, MinLng: -10, MaxLat: 50, MaxLng: 10}},
+		},
+		{
+			name: "box one longitude wide",
+			box:  GeoBox{MinLat: 40, MinLng: 5, MaxLat: 50, MaxLng: 5},
+			want: []GeoBox{{MinLat: 40, MinLng: 5, MaxLat: 50, MaxLng: 5}},
+		},
+		{
+			name: "box that crosses the antimeridian",
+			box:  GeoBox{MinLat: -20, MinLng: 170, MaxLat: -10, MaxLng: -170},
+			want: []GeoBox{
+				{MinLat: -20, MinLng: 170, MaxLat: -10, MaxLng: 180},
+				{MinLat: -20, MinLng: -180, MaxLat: -10, MaxLng: -170},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitGeoBox(tt.box)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d boxes %+v, want %+v", len(got), got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("box %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// kmPerDegree is the length of one degree of latitude.
+const kmPerDegree = earthRadiusKm * math.Pi / 180
+
+func TestRadiusGeoBoxes(t *testing.T) {
+	tests := []struct {
+		name     string
+		center   GeoPoint
+		radiusKm float64
+		want     []GeoBox
+	}{
+		{
+			name:     "circle on the equator",
+			center:   GeoPoint{Lat: 0, Lng: 0},
+			radiusKm: kmPerDegree,
+			want:     []GeoBox{{MinLat: -1, MinLng: -1, MaxLat: 1, MaxLng: 1}},
+		},
+		{
+			name:     "circle at 60 degrees is twice as wide in longitude",
+			center:   GeoPoint{Lat: 60, Lng: 10},
+			radiusKm: 0.01 * kmPerDegree,
+			want:     []GeoBox{{MinLat: 59.99, MinLng: 9.98, MaxLat: 60.01, MaxLng: 10.02}},
+		},
+		{
+			name:     "circle crossing the antimeridian eastwards",
+			center:   GeoPoint{Lat: 0, Lng: 179.5},
+			radiusKm: kmPerDegree,
+			want: []GeoBox{
+				{MinLat: -1, MinLng: 178.5, MaxLat: 1, MaxLng: 180},
+				{MinLat: -1, MinLng: -180, MaxLat: 1, MaxLng: -179.5},
+			},
+		},
+		{
+			name:     "circle crossing the antimeridian westwards",
+			center:   GeoPoint{Lat: 0, Lng: -179.5},
+			radiusKm: kmPerDegree,
+			want: []GeoBox{
+				{MinLat: -1, MinLng: 179.5, MaxLat: 1, MaxLng: 180},
+				{MinLat: -1, MinLng: -180, MaxLat: 1, MaxLng: -178.5},
+			},
+		},
+		{
+			name:     "circle reaching the north pole",
+			center:   GeoPoint{Lat: 89.5, Lng: 30},
+			radiusKm: kmPerDegree,
+			want:     []GeoBox{{MinLat: 88.5, MinLng: -180, MaxLat: 90, MaxLng: 180}},
+		},
+		{
+			name:     "circle reaching the south pole",
+			center:   GeoPoint{Lat: -89, Lng: -120},
+			radiusKm: kmPerDegree,
+			want:     []GeoBox{{MinLat: -90, MinLng: -180, MaxLat: -88, MaxLng: 180}},
+		},
+		{
+			name:     "circle centered on a pole",
+			center:   GeoPoint{Lat: 90, Lng: 0},
+			radiusKm: 1,
+			want:     []GeoBox{{MinLat: 90 - 1/kmPerDegree, MinLng: -180, MaxLat: 90, MaxLng: 180}},
+		},
+		{
+			name:     "radius of half the circumference covers the globe",
+			center:   GeoPoint{Lat: 12, Lng: 34},
+			radiusKm: MaxGeoRadiusKm,
+			want:     []GeoBox{{MinLat: -90, MinLng: -180, MaxLat: 90, MaxLng: 180}},
+		},
+		{
+			name:     "radius beyond half the circumference covers the globe",
+			center:   GeoPoint{Lat: 0, Lng: 0},
+			radiusKm: 2 * MaxGeoRadiusKm,
+			want:     []GeoBox{{MinLat: -90, MinLng: -180, MaxLat: 90, MaxLng: 180}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := radiusGeoBoxes(tt.center, tt.radiusKm)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d boxes %+v, want %+v", len(got), got, tt.want)
+			}
+			for i := range got {
+				if !nearGeoBox(got[i], tt.want[i]) {
+					t.Errorf("box %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func nearGeoBox(a, b GeoBox) bool {
+	near := func(x, y float64) bool { return math.Abs(x-y) < 1e-4 }
+	return near(a.MinLat, b.MinLat) && near(a.MinLng, b.MinLng) &&
+		near(a.MaxLat, b.MaxLat) && near(a.MaxLng, b.MaxLng)
+}
@@ -0,0 +1,171 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var ErrInviteNotRedeemable = errors.New("invite is expired, revoked or already redeemed")
+
+type Invite struct {
+	ID              int64      `json:"id"`
+	Email           string     `json:"email"`
+	Role            string     `json:"role"`
+	TokenHash       string     `json:"-"`
+	InvitedBy       int64      `json:"invited_by"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	RevokedAt       *time.Time `json:"revoked_at"`
+	RedeemedAt      *time.Time `json:"redeemed_at"`
+	RedeemedAdminID *int64     `json:"redeemed_admin_id"`
+	RedeemedIP      *string    `json:"redeemed_ip"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+type InviteRepositoryInterface interface {
+	Create(invite *Invite) error
+	FindById(id int64) (*Invite, error)
+	FindByTokenHash(tokenHash string) (*Invite, error)
+	FindAll() ([]*Invite, error)
+	Revoke(id int64) (bool, error)
+	Redeem(inviteID int64, admin *Admin, ip string) error
+}
+
+type InviteRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewInviteRepository(db *sql.DB) InviteRepositoryInterface {
+	return &InviteRepositoryImpl{db}
+}
+
+const inviteColumns = `id, email, role, token_hash, invited_by, expires_at, revoked_at,
+			  redeemed_at, redeemed_admin_id, redeemed_ip, created_at`
+
+func scanInvite(row interface{ Scan(...any) error }) (*Invite, error) {
+	var invite Invite
+	err := row.Scan(&invite.ID, &invite.Email, &invite.Role, &invite.TokenHash, &invite.InvitedBy,
+		&invite.ExpiresAt, &invite.RevokedAt, &invite.RedeemedAt, &invite.RedeemedAdminID,
+		&invite.RedeemedIP, &invite.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func (r *InviteRepositoryImpl) Create(invite *Invite) error {
+	query := `INSERT INTO admin_invites (email, role, token_hash, invited_by, expires_at)
+			  VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return r.db.QueryRowContext(ctx, query, invite.Email, invite.Role, invite.TokenHash,
+		invite.InvitedBy, invite.ExpiresAt).Scan(&invite.ID, &invite.CreatedAt)
+}
+
+func (r *InviteRepositoryImpl) FindById(id int64) (*Invite, error) {
+	query := `SELECT ` + inviteColumns + ` FROM admin_invites WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return scanInvite(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *InviteRepositoryImpl) FindByTokenHash(tokenHash string) (*Invite, error) {
+	query := `SELECT ` + inviteColumns + ` FROM admin_invites WHERE token_hash = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return scanInvite(r.db.QueryRowContext(ctx, query, tokenHash))
+}
+
+func (r *InviteRepositoryImpl) FindAll() ([]*Invite, error) {
+	query := `SELECT ` + inviteColumns + ` FROM admin_invites ORDER BY created_at DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invites := make([]*Invite, 0)
+	for rows.Next() {
+		invite, err := scanInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, invite)
+	}
+
+	return invites, rows.Err()
+}
+
+func (r *InviteRepositoryImpl) Revoke(id int64) (bool, error) {
+	query := `UPDATE admin_invites SET revoked_at = NOW()
+			  WHERE id = $1 AND revoked_at IS NULL AND redeemed_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// Redeem claims the invite and creates the invited admin in one transaction,
+// so a token can never produce more than one account.
+func (r *InviteRepositoryImpl) Redeem(inviteID int64, admin *Admin, ip string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	claim := `UPDATE admin_invites SET redeemed_at = NOW(), redeemed_ip = $2
+			  WHERE id = $1 AND revoked_at IS NULL AND redeemed_at IS NULL AND expires_at > NOW()`
+	result, err := tx.ExecContext(ctx, claim, inviteID, ip)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrInviteNotRedeemable
+	}
+
+	insert := `INSERT INTO admin_users (email, password, name, role, verified)
+			   VALUES ($1, $2, $3, $4, TRUE) RETURNING id, created_at`
+	err = tx.QueryRowContext(ctx, insert, admin.Email, admin.Password, admin.Name, admin.Role).
+		Scan(&admin.ID, &admin.CreatedAt)
+	if err != nil {
+		return err
+	}
+	admin.Verified = true
+
+	link := `UPDATE admin_invites SET redeemed_admin_id = $2 WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, link, inviteID, admin.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
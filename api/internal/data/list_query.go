@@ -0,0 +1,87 @@
+package data
+
+import (
+	"strconv"
+	"strings"
+)
+
+// listQuery assembles the WHERE, ORDER BY and LIMIT parts of a paginated
+// list. Every value is bound as a query parameter and numbered in the order
+// it is bound, so conditions never carry user input in their SQL text.
+type listQuery struct {
+	conditions []string
+	params     []interface{}
+}
+
+// bind adds value as a parameter and returns its placeholder.
+func (q *listQuery) bind(value interface{}) string {
+	q.params = append(q.params, value)
+	return "$" + strconv.Itoa(len(q.params))
+}
+
+// where adds a condition that every row in the list must meet.
+func (q *listQuery) where(condition string) {
+	q.conditions = append(q.conditions, condition)
+}
+
+// search adds a case-insensitive substring match of term against any of
+// columns. Wildcards in term match literally.
+func (q *listQuery) search(term string, columns ...string) {
+	if term == "" {
+		return
+	}
+
+	placeholder := q.bind(searchPattern(term))
+	matches := make([]string, len(columns))
+	for i, column := range columns {
+		matches[i] = "LOWER(" + column + ") LIKE " + placeholder
+	}
+	q.where("(" + strings.Join(matches, " OR ") + ")")
+}
+
+// clause returns the WHERE clause, or an empty string when nothing filters.
+func (q *listQuery) clause() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+// args returns the parameters bound so far. Take it before page for the
+// count query.
+func (q *listQuery) args() []interface{} {
+	return append([]interface{}(nil), q.params...)
+}
+
+// page binds limit and the offset of page and returns the LIMIT clause.
+func (q *listQuery) page(page int64, limit int64) string {
+	return "LIMIT " + q.bind(limit) + " OFFSET " + q.bind((page-1)*limit)
+}
+
+// listOrder keeps sort to the allowed columns, falling back to the first,
+// and order to asc or desc, falling back to desc.
+func listOrder(sort, order string, allowed ...string) (string, string) {
+	valid := false
+	for _, column := range allowed {
+		if column == sort {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		sort = allowed[0]
+	}
+
+	if order != "asc" && order != "desc" {
+		order = "desc"
+	}
+
+	return sort, order
+}
+
+// searchPattern turns a search term into a LIKE pattern matching it
+// anywhere, lowercased to pair with LOWER() on the column.
+func searchPattern(term string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(term))
+	return "%" + escaped + "%"
+}
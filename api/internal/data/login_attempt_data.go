@@ -0,0 +1,185 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type LoginAttempt struct {
+	ID            int64     `json:"id"`
+	Email         string    `json:"email"`
+	AdminID       *int64    `json:"admin_id"`
+	IPAddress     string    `json:"ip_address"`
+	UserAgent     string    `json:"user_agent"`
+	Success       bool      `json:"success"`
+	FailureReason *string   `json:"failure_reason"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type LoginAttemptPagination struct {
+	Attempts   []*LoginAttempt `json:"attempts"`
+	Total      int64           `json:"total"`
+	HasMore    bool            `json:"has_more"`
+	TotalPages int64           `json:"total_pages"`
+	Page       int64           `json:"page"`
+}
+
+type LoginThrottle struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
+
+type LoginAttemptRepositoryInterface interface {
+	Record(attempt *LoginAttempt) error
+	FindAll(page int64, limit int64, email, ip string) (*LoginAttemptPagination, error)
+	GetThrottle(key string) (*LoginThrottle, error)
+	RegisterFailure(key string, resetAfter time.Duration) (*LoginThrottle, error)
+	Lock(key string, until time.Time) error
+	ClearThrottle(key string) error
+}
+
+type LoginAttemptRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewLoginAttemptRepository(db *sql.DB) LoginAttemptRepositoryInterface {
+	return &LoginAttemptRepositoryImpl{db}
+}
+
+func (r *LoginAttemptRepositoryImpl) Record(attempt *LoginAttempt) error {
+	query := `INSERT INTO admin_login_attempts (email, admin_id, ip_address, user_agent, success, failure_reason)
+			  VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return r.db.QueryRowContext(ctx, query, attempt.Email, attempt.AdminID, attempt.IPAddress,
+		attempt.UserAgent, attempt.Success, attempt.FailureReason).Scan(&attempt.ID, &attempt.CreatedAt)
+}
+
+func (r *LoginAttemptRepositoryImpl) FindAll(page int64, limit int64, email, ip string) (*LoginAttemptPagination, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var list listQuery
+
+	if email != "" {
+		list.where(`email = ` + list.bind(email))
+	}
+
+	if ip != "" {
+		list.where(`ip_address = ` + list.bind(ip))
+	}
+
+	var total int64
+	countQuery := `SELECT COUNT(*) FROM admin_login_attempts ` + list.clause()
+	if err := r.db.QueryRowContext(ctx, countQuery, list.args()...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, email, admin_id, ip_address, user_agent, success, failure_reason, created_at
+			  FROM admin_login_attempts ` + list.clause() + `
+			  ORDER BY created_at DESC, id DESC
+			  ` + list.page(page, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, list.params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := make([]*LoginAttempt, 0)
+	for rows.Next() {
+		var attempt LoginAttempt
+		err := rows.Scan(&attempt.ID, &attempt.Email, &attempt.AdminID, &attempt.IPAddress,
+			&attempt.UserAgent, &attempt.Success, &attempt.FailureReason, &attempt.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, &attempt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	totalPages := (total + limit - 1) / limit
+
+	return &LoginAttemptPagination{
+		Attempts:   attempts,
+		Total:      total,
+		HasMore:    page < totalPages,
+		TotalPages: totalPages,
+		Page:       page,
+	}, nil
+}
+
+// GetThrottle returns nil without an error when the key has no recorded
+// failures.
+func (r *LoginAttemptRepositoryImpl) GetThrottle(key string) (*LoginThrottle, error) {
+	query := `SELECT key, failures, last_failure_at, locked_until FROM login_throttles WHERE key = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var throttle LoginThrottle
+	err := r.db.QueryRowContext(ctx, query, key).
+		Scan(&throttle.Key, &throttle.Failures, &throttle.LastFailureAt, &throttle.LockedUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &throttle, nil
+}
+
+// RegisterFailure atomically bumps the failure counter for key. Counters whose
+// last failure is older than resetAfter start again from one.
+func (r *LoginAttemptRepositoryImpl) RegisterFailure(key string, resetAfter time.Duration) (*LoginThrottle, error) {
+	query := `INSERT INTO login_throttles (key, failures, last_failure_at) VALUES ($1, 1, NOW())
+			  ON CONFLICT (key) DO UPDATE SET
+				failures = CASE
+					WHEN login_throttles.last_failure_at < NOW() - make_interval(secs => $2) THEN 1
+					ELSE login_throttles.failures + 1
+				END,
+				last_failure_at = NOW()
+			  RETURNING key, failures, last_failure_at, locked_until`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var throttle LoginThrottle
+	err := r.db.QueryRowContext(ctx, query, key, resetAfter.Seconds()).
+		Scan(&throttle.Key, &throttle.Failures, &throttle.LastFailureAt, &throttle.LockedUntil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &throttle, nil
+}
+
+func (r *LoginAttemptRepositoryImpl) Lock(key string, until time.Time) error {
+	query := `UPDATE login_throttles SET locked_until = $2, failures = 0 WHERE key = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, query, key, until)
+	return err
+}
+
+func (r *LoginAttemptRepositoryImpl) ClearThrottle(key string) error {
+	query := `DELETE FROM login_throttles WHERE key = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, query, key)
+	return err
+}
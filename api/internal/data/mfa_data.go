@@ -0,0 +1,166 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type TOTPState struct {
+	Secret   string
+	Enabled  bool
+	LastStep int64
+}
+
+type RecoveryCode struct {
+	ID       int64
+	AdminID  int64
+	CodeHash string
+}
+
+type MfaRepositoryInterface interface {
+	GetTOTP(adminID int64) (*TOTPState, error)
+	SetPendingTOTPSecret(adminID int64, secret string) error
+	EnableTOTP(adminID int64, secret string, step int64, recoveryCodeHashes []string) (bool, error)
+	ConsumeTOTPStep(adminID int64, step int64) (bool, error)
+	FindUnusedRecoveryCodes(adminID int64) ([]*RecoveryCode, error)
+	UseRecoveryCode(id int64) (bool, error)
+}
+
+type MfaRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewMfaRepository(db *sql.DB) MfaRepositoryInterface {
+	return &MfaRepositoryImpl{db}
+}
+
+func (r *MfaRepositoryImpl) GetTOTP(adminID int64) (*TOTPState, error) {
+	query := `SELECT COALESCE(totp_secret, ''), totp_enabled, totp_last_step FROM admin_users WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var state TOTPState
+	err := r.db.QueryRowContext(ctx, query, adminID).Scan(&state.Secret, &state.Enabled, &state.LastStep)
+	if err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+func (r *MfaRepositoryImpl) SetPendingTOTPSecret(adminID int64, secret string) error {
+	query := `UPDATE admin_users SET totp_secret = $2 WHERE id = $1 AND totp_enabled = FALSE`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, query, adminID, secret)
+	return err
+}
+
+// EnableTOTP turns on the pending secret and replaces the admin's recovery
+// codes in one transaction. It reports false, changing nothing, when TOTP
+// was enabled or the pending secret replaced since secret was read.
+func (r *MfaRepositoryImpl) EnableTOTP(adminID int64, secret string, step int64, recoveryCodeHashes []string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	enable := `UPDATE admin_users SET totp_enabled = TRUE, totp_last_step = $2
+			   WHERE id = $1 AND totp_enabled = FALSE AND totp_secret = $3`
+	result, err := tx.ExecContext(ctx, enable, adminID, step, secret)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM admin_recovery_codes WHERE admin_id = $1`, adminID); err != nil {
+		return false, err
+	}
+
+	insert := `INSERT INTO admin_recovery_codes (admin_id, code_hash) VALUES ($1, $2)`
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.ExecContext(ctx, insert, adminID, hash); err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit()
+}
+
+// ConsumeTOTPStep records step as used. It reports false when the step is not
+// newer than the last accepted one, which means the code is being replayed.
+func (r *MfaRepositoryImpl) ConsumeTOTPStep(adminID int64, step int64) (bool, error) {
+	query := `UPDATE admin_users SET totp_last_step = $2 WHERE id = $1 AND totp_last_step < $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, query, adminID, step)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r *MfaRepositoryImpl) FindUnusedRecoveryCodes(adminID int64) ([]*RecoveryCode, error) {
+	query := `SELECT id, admin_id, code_hash FROM admin_recovery_codes WHERE admin_id = $1 AND used_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, adminID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	codes := make([]*RecoveryCode, 0)
+	for rows.Next() {
+		var code RecoveryCode
+		if err := rows.Scan(&code.ID, &code.AdminID, &code.CodeHash); err != nil {
+			return nil, err
+		}
+		codes = append(codes, &code)
+	}
+
+	return codes, rows.Err()
+}
+
+func (r *MfaRepositoryImpl) UseRecoveryCode(id int64) (bool, error) {
+	query := `UPDATE admin_recovery_codes SET used_at = NOW() WHERE id = $1 AND used_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
@@ -0,0 +1,495 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const (
+	ModerationJobKindBulkBlock = "bulk_block"
+	ModerationJobKindBanImport = "ban_import"
+)
+
+// A ban import starts out previewed: validated, but nothing applied until
+// it is confirmed.
+const (
+	ModerationJobStatusPreviewed = "previewed"
+	ModerationJobStatusQueued    = "queued"
+	ModerationJobStatusRunning   = "running"
+	ModerationJobStatusCompleted = "completed"
+	ModerationJobStatusFailed    = "failed"
+)
+
+// Item outcomes. already_blocked covers a restriction of the same mode
+// already being in force as well as an existing ban.
+const (
+	ModerationItemApplied        = "applied"
+	ModerationItemAlreadyBlocked = "already_blocked"
+	ModerationItemNotFound       = "not_found"
+	ModerationItemFailed         = "failed"
+)
+
+// Import rows are ready or rejected after the dry run. Confirming the import
+// moves ready rows on to one of the outcomes above.
+const (
+	ModerationItemReady    = "ready"
+	ModerationItemRejected = "rejected"
+)
+
+// moderationItemStatuses lists the outcomes a job of each kind can record.
+var moderationItemStatuses = map[string][]string{
+	ModerationJobKindBulkBlock: {ModerationItemApplied, ModerationItemAlreadyBlocked, ModerationItemNotFound, ModerationItemFailed},
+	ModerationJobKindBanImport: {ModerationItemReady, ModerationItemRejected, ModerationItemApplied,
+		ModerationItemAlreadyBlocked, ModerationItemNotFound, ModerationItemFailed},
+}
+
+// ValidModerationItemStatus reports whether a job of kind can hold items
+// with status.
+func ValidModerationItemStatus(kind, status string) bool {
+	for _, candidate := range moderationItemStatuses[kind] {
+		if candidate == status {
+			return true
+		}
+	}
+	return false
+}
+
+type ModerationJob struct {
+	ID          int64            `json:"id"`
+	Kind        string           `json:"kind"`
+	Status      string           `json:"status"`
+	Params      json.RawMessage  `json:"params"`
+	Total       int              `json:"total"`
+	Processed   int              `json:"processed"`
+	Error       *string          `json:"error"`
+	CreatedBy   *int64           `json:"created_by"`
+	ConfirmedBy *int64           `json:"confirmed_by"`
+	CreatedAt   time.Time        `json:"created_at"`
+	StartedAt   *time.Time       `json:"started_at"`
+	FinishedAt  *time.Time       `json:"finished_at"`
+	Counts      map[string]int64 `json:"counts"`
+}
+
+type ModerationJobItem struct {
+	Position int     `json:"position"`
+	Target   string  `json:"target"`
+	UserID   *int64  `json:"user_id"`
+	Status   string  `json:"status"`
+	Notes    *string `json:"notes,omitempty"`
+	Error    *string `json:"error"`
+	RecordID *int64  `json:"record_id"`
+}
+
+type ModerationJobPagination struct {
+	Jobs       []*ModerationJob `json:"jobs"`
+	Total      int64            `json:"total"`
+	HasMore    bool             `json:"has_more"`
+	TotalPages int64            `json:"total_pages"`
+	Page       int64            `json:"page"`
+}
+
+type ModerationJobItemPagination struct {
+	Items      []*ModerationJobItem `json:"items"`
+	Total      int64                `json:"total"`
+	HasMore    bool                 `json:"has_more"`
+	TotalPages int64                `json:"total_pages"`
+	Page       int64                `json:"page"`
+}
+
+type ModerationJobRepositoryInterface interface {
+	Create(job *ModerationJob) error
+	CreateWithItems(job *ModerationJob, items []*ModerationJobItem) error
+	FindById(id int64) (*ModerationJob, error)
+	FindAll(kind string, page int64, limit int64) (*ModerationJobPagination, error)
+	FindItems(jobID int64, page int64, limit int64, status string) (*ModerationJobItemPagination, error)
+	FindItemsByStatus(jobID int64, status string) ([]*ModerationJobItem, error)
+	Start(id int64) error
+	Confirm(id int64, confirmedBy *int64) (bool, error)
+	RecordItems(jobID int64, items []*ModerationJobItem) error
+	UpdateItems(jobID int64, items []*ModerationJobItem) error
+	Finish(id int64, status string, errMessage *string) error
+	// ClaimStale takes over up to limit queued or running jobs whose
+	// heartbeat is older than staleAfter or was released, bumping their
+	// heartbeat so no other replica claims them too.
+	ClaimStale(staleAfter time.Duration, limit int) ([]*ModerationJob, error)
+	// Release clears the heartbeat of an unfinished job so it can be
+	// claimed straight away.
+	Release(id int64) error
+}
+
+type ModerationJobRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewModerationJobRepository(db *sql.DB) ModerationJobRepositoryInterface {
+	return &ModerationJobRepositoryImpl{db}
+}
+
+const moderationJobColumns = `id, kind, status, params, total, processed, error, created_by, confirmed_by,
+			  created_at, started_at, finished_at`
+
+const moderationJobItemColumns = `position, target, user_id, status, notes, error, record_id`
+
+func scanModerationJob(row interface{ Scan(...any) error }) (*ModerationJob, error) {
+	var job ModerationJob
+	var params []byte
+	err := row.Scan(&job.ID, &job.Kind, &job.Status, &params, &job.Total, &job.Processed, &job.Error,
+		&job.CreatedBy, &job.ConfirmedBy, &job.CreatedAt, &job.StartedAt, &job.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	job.Params = params
+	return &job, nil
+}
+
+func scanModerationJobItem(row interface{ Scan(...any) error }) (*ModerationJobItem, error) {
+	var item ModerationJobItem
+	err := row.Scan(&item.Position, &item.Target, &item.UserID, &item.Status, &item.Notes, &item.Error, &item.RecordID)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *ModerationJobRepositoryImpl) Create(job *ModerationJob) error {
+	query := `INSERT INTO moderation_jobs (kind, params, total, created_by)
+			  VALUES ($1, $2, $3, $4)
+			  RETURNING id, status, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return r.db.QueryRowContext(ctx, query, job.Kind, string(job.Params), job.Total, job.CreatedBy).
+		Scan(&job.ID, &job.Status, &job.CreatedAt)
+}
+
+// CreateWithItems stores a job in job.Status together with its items, for
+// work whose items are known before anything is applied.
+func (r *ModerationJobRepositoryImpl) CreateWithItems(job *ModerationJob, items []*ModerationJobItem) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO moderation_jobs (kind, status, params, total, created_by)
+			  VALUES ($1, $2, $3, $4, $5)
+			  RETURNING id, created_at`
+	err = tx.QueryRowContext(ctx, query, job.Kind, job.Status, string(job.Params), job.Total, job.CreatedBy).
+		Scan(&job.ID, &job.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := insertModerationJobItems(ctx, tx, job.ID, items); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertModerationJobItems(ctx context.Context, tx *sql.Tx, jobID int64, items []*ModerationJobItem) error {
+	insert := `INSERT INTO moderation_job_items (job_id, position, target, user_id, status, notes, error, record_id)
+			   VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	for _, item := range items {
+		_, err := tx.ExecContext(ctx, insert, jobID, item.Position, item.Target, item.UserID, item.Status,
+			item.Notes, item.Error, item.RecordID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindById returns the job with a count of its recorded items per outcome.
+func (r *ModerationJobRepositoryImpl) FindById(id int64) (*ModerationJob, error) {
+	query := `SELECT ` + moderationJobColumns + ` FROM moderation_jobs WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	job, err := scanModerationJob(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT status, COUNT(*) FROM moderation_job_items WHERE job_id = $1 GROUP BY status`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	job.Counts = make(map[string]int64)
+	for _, status := range moderationItemStatuses[job.Kind] {
+		job.Counts[status] = 0
+	}
+	for rows.Next() {
+		var status string
+		var count int64
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		job.Counts[status] = count
+	}
+
+	return job, rows.Err()
+}
+
+// FindAll lists jobs of kind, newest first. Item counts are only filled in
+// by FindById.
+func (r *ModerationJobRepositoryImpl) FindAll(kind string, page int64, limit int64) (*ModerationJobPagination, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var list listQuery
+	list.where(`kind = ` + list.bind(kind))
+
+	countArgs := list.args()
+	query := `SELECT ` + moderationJobColumns + ` FROM moderation_jobs ` + list.clause() + `
+			  ORDER BY created_at DESC, id DESC ` + list.page(page, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, list.params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := make([]*ModerationJob, 0)
+	for rows.Next() {
+		job, err := scanModerationJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var total int64
+	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM moderation_jobs `+list.clause(), countArgs...).Scan(&total)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := (total + limit - 1) / limit
+
+	return &ModerationJobPagination{
+		Jobs:       jobs,
+		Total:      total,
+		HasMore:    page < totalPages,
+		TotalPages: totalPages,
+		Page:       page,
+	}, nil
+}
+
+func (r *ModerationJobRepositoryImpl) FindItems(jobID int64, page int64, limit int64, status string) (*ModerationJobItemPagination, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var list listQuery
+	list.where(`job_id = ` + list.bind(jobID))
+
+	if status != "" {
+		list.where(`status = ` + list.bind(status))
+	}
+
+	countArgs := list.args()
+	query := `SELECT ` + moderationJobItemColumns + ` FROM moderation_job_items ` + list.clause() +
+		` ORDER BY position ` + list.page(page, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, list.params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]*ModerationJobItem, 0)
+	for rows.Next() {
+		item, err := scanModerationJobItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	countQuery := `SELECT COUNT(*) FROM moderation_job_items ` + list.clause()
+	var total int64
+	err = r.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := (total + limit - 1) / limit
+
+	return &ModerationJobItemPagination{
+		Items:      items,
+		Total:      total,
+		HasMore:    page < totalPages,
+		TotalPages: totalPages,
+		Page:       page,
+	}, nil
+}
+
+func (r *ModerationJobRepositoryImpl) FindItemsByStatus(jobID int64, status string) ([]*ModerationJobItem, error) {
+	query := `SELECT ` + moderationJobItemColumns + ` FROM moderation_job_items
+			  WHERE job_id = $1 AND status = $2 ORDER BY position`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, jobID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]*ModerationJobItem, 0)
+	for rows.Next() {
+		item, err := scanModerationJobItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func (r *ModerationJobRepositoryImpl) Start(id int64) error {
+	query := `UPDATE moderation_jobs SET status = 'running', started_at = NOW(), heartbeat_at = NOW() WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// Confirm moves a previewed job to running. It reports false when the job
+// was not awaiting confirmation, so a job is only ever confirmed once.
+func (r *ModerationJobRepositoryImpl) Confirm(id int64, confirmedBy *int64) (bool, error) {
+	query := `UPDATE moderation_jobs SET status = 'running', started_at = NOW(), heartbeat_at = NOW(), confirmed_by = $2
+			  WHERE id = $1 AND status = 'previewed'`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, query, id, confirmedBy)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// RecordItems stores a batch of outcomes and advances the job's progress
+// in one transaction, so processed always matches the stored items.
+func (r *ModerationJobRepositoryImpl) RecordItems(jobID int64, items []*ModerationJobItem) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertModerationJobItems(ctx, tx, jobID, items); err != nil {
+		return err
+	}
+
+	progress := `UPDATE moderation_jobs SET processed = processed + $2, heartbeat_at = NOW() WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, progress, jobID, len(items)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateItems stores the outcome of items recorded earlier, such as the
+// ready rows of a confirmed import, and advances the job's progress.
+func (r *ModerationJobRepositoryImpl) UpdateItems(jobID int64, items []*ModerationJobItem) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	update := `UPDATE moderation_job_items SET status = $3, error = $4, record_id = $5
+			   WHERE job_id = $1 AND position = $2`
+	for _, item := range items {
+		if _, err := tx.ExecContext(ctx, update, jobID, item.Position, item.Status, item.Error, item.RecordID); err != nil {
+			return err
+		}
+	}
+
+	progress := `UPDATE moderation_jobs SET processed = processed + $2, heartbeat_at = NOW() WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, progress, jobID, len(items)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *ModerationJobRepositoryImpl) Finish(id int64, status string, errMessage *string) error {
+	query := `UPDATE moderation_jobs SET status = $2, error = $3, finished_at = NOW() WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, query, id, status, errMessage)
+	return err
+}
+
+func (r *ModerationJobRepositoryImpl) ClaimStale(staleAfter time.Duration, limit int) ([]*ModerationJob, error) {
+	query := `UPDATE moderation_jobs SET heartbeat_at = NOW()
+			  WHERE id IN (
+				SELECT id FROM moderation_jobs
+				WHERE status IN ('queued', 'running')
+				  AND (heartbeat_at IS NULL OR heartbeat_at < NOW() - make_interval(secs => $1))
+				ORDER BY id
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			  )
+			  RETURNING ` + moderationJobColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, staleAfter.Seconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := make([]*ModerationJob, 0)
+	for rows.Next() {
+		job, err := scanModerationJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+func (r *ModerationJobRepositoryImpl) Release(id int64) error {
+	query := `UPDATE moderation_jobs SET heartbeat_at = NULL WHERE id = $1 AND status IN ('queued', 'running')`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
@@ -0,0 +1,59 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type OidcState struct {
+	State        string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+type OidcStateRepositoryInterface interface {
+	Create(state *OidcState) error
+	Consume(state string) (*OidcState, error)
+}
+
+type OidcStateRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewOidcStateRepository(db *sql.DB) OidcStateRepositoryInterface {
+	return &OidcStateRepositoryImpl{db}
+}
+
+func (r *OidcStateRepositoryImpl) Create(state *OidcState) error {
+	query := `INSERT INTO oidc_login_states (state, nonce, code_verifier, expires_at) VALUES ($1, $2, $3, $4)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM oidc_login_states WHERE expires_at < NOW()`); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx, query, state.State, state.Nonce, state.CodeVerifier, state.ExpiresAt)
+	return err
+}
+
+// Consume deletes and returns the login state, so every state value can be
+// used for exactly one callback.
+func (r *OidcStateRepositoryImpl) Consume(state string) (*OidcState, error) {
+	query := `DELETE FROM oidc_login_states WHERE state = $1 AND expires_at > NOW()
+			  RETURNING state, nonce, code_verifier, expires_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var s OidcState
+	err := r.db.QueryRowContext(ctx, query, state).Scan(&s.State, &s.Nonce, &s.CodeVerifier, &s.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
@@ -0,0 +1,151 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type PasswordReset struct {
+	ID          int64
+	AdminID     int64
+	TokenHash   string
+	RequestedIP string
+	ExpiresAt   time.Time
+	UsedAt      *time.Time
+	CreatedAt   time.Time
+}
+
+type PasswordRepositoryInterface interface {
+	FindHistory(adminID int64, limit int) ([]string, error)
+	SetPassword(adminID int64, passwordHash string) error
+	CreateReset(reset *PasswordReset) error
+	FindResetByTokenHash(tokenHash string) (*PasswordReset, error)
+	// ResetPassword marks the reset as used and stores the new hash in one
+	// transaction. It reports false, changing nothing, if the token was
+	// already used or has expired in the meantime.
+	ResetPassword(resetID, adminID int64, passwordHash string) (bool, error)
+}
+
+type PasswordRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewPasswordRepository(db *sql.DB) PasswordRepositoryInterface {
+	return &PasswordRepositoryImpl{db}
+}
+
+func (r *PasswordRepositoryImpl) FindHistory(adminID int64, limit int) ([]string, error) {
+	query := `SELECT password_hash FROM admin_password_history WHERE admin_id = $1
+			  ORDER BY created_at DESC LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, adminID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hashes := make([]string, 0)
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+
+	return hashes, rows.Err()
+}
+
+// SetPassword stores the new hash on the admin and appends it to the
+// password history in one transaction.
+func (r *PasswordRepositoryImpl) SetPassword(adminID int64, passwordHash string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setPassword(ctx, tx, adminID, passwordHash); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func setPassword(ctx context.Context, tx *sql.Tx, adminID int64, passwordHash string) error {
+	if _, err := tx.ExecContext(ctx, `UPDATE admin_users SET password = $2 WHERE id = $1`, adminID, passwordHash); err != nil {
+		return err
+	}
+
+	history := `INSERT INTO admin_password_history (admin_id, password_hash) VALUES ($1, $2)`
+	_, err := tx.ExecContext(ctx, history, adminID, passwordHash)
+	return err
+}
+
+func (r *PasswordRepositoryImpl) CreateReset(reset *PasswordReset) error {
+	query := `INSERT INTO admin_password_resets (admin_id, token_hash, requested_ip, expires_at)
+			  VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return r.db.QueryRowContext(ctx, query, reset.AdminID, reset.TokenHash, reset.RequestedIP, reset.ExpiresAt).
+		Scan(&reset.ID, &reset.CreatedAt)
+}
+
+func (r *PasswordRepositoryImpl) FindResetByTokenHash(tokenHash string) (*PasswordReset, error) {
+	query := `SELECT id, admin_id, token_hash, requested_ip, expires_at, used_at, created_at
+			  FROM admin_password_resets WHERE token_hash = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var reset PasswordReset
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(&reset.ID, &reset.AdminID, &reset.TokenHash,
+		&reset.RequestedIP, &reset.ExpiresAt, &reset.UsedAt, &reset.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reset, nil
+}
+
+func (r *PasswordRepositoryImpl) ResetPassword(resetID, adminID int64, passwordHash string) (bool, error) {
+	query := `UPDATE admin_password_resets SET used_at = NOW()
+			  WHERE id = $1 AND admin_id = $2 AND used_at IS NULL AND expires_at > NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, resetID, adminID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	if err := setPassword(ctx, tx, adminID, passwordHash); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
@@ -0,0 +1,452 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrReportImageNotFound = errors.New("evidence image not found")
+
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusInReview  = "in_review"
+	ReportStatusActioned  = "actioned"
+	ReportStatusDismissed = "dismissed"
+)
+
+var ReportCategories = []string{
+	"spam", "harassment", "fake_profile", "scam", "inappropriate_content", "underage", "other",
+}
+
+// reportPendingCondition matches reports still waiting for a decision.
+const reportPendingCondition = `status IN ('open', 'in_review')`
+
+// reporterTrustQuery scores each reporter by how often their decided reports
+// were actioned, smoothed so a reporter with no history scores 0.5.
+const reporterTrustQuery = `SELECT reporter_id,
+			  (COUNT(*) FILTER (WHERE status = 'actioned') + 1)::float8 /
+			  (COUNT(*) FILTER (WHERE status IN ('actioned', 'dismissed')) + 2) AS trust
+			  FROM user_reports GROUP BY reporter_id`
+
+const reportDefaultTrust = 0.5
+
+type Report struct {
+	ID             int64      `json:"id"`
+	ReporterID     int64      `json:"reporter_id"`
+	ReportedUserID int64      `json:"reported_user_id"`
+	Category       string     `json:"category"`
+	Details        string     `json:"details"`
+	Status         string     `json:"status"`
+	ReviewerID     *int64     `json:"reviewer_id"`
+	ResolvedBy     *int64     `json:"resolved_by"`
+	ResolutionNote *string    `json:"resolution_note"`
+	BlockedID      *int64     `json:"blocked_id"`
+	ResolvedAt     *time.Time `json:"resolved_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	ReporterTrust  *float64   `json:"reporter_trust,omitempty"`
+	Images         []*Image   `json:"images,omitempty"`
+}
+
+// ReportGroup is one entry in the moderation queue: every pending report
+// against the same user. Priority is the summed trust of the distinct
+// reporters, so several credible reporters outrank one reporter filing
+// repeatedly.
+type ReportGroup struct {
+	ReportedUserID  int64     `json:"reported_user_id"`
+	ReportCount     int64     `json:"report_count"`
+	ReporterCount   int64     `json:"reporter_count"`
+	Priority        float64   `json:"priority"`
+	Categories      []string  `json:"categories"`
+	ReportIDs       []int64   `json:"report_ids"`
+	FirstReportedAt time.Time `json:"first_reported_at"`
+	LastReportedAt  time.Time `json:"last_reported_at"`
+}
+
+type ReportFilter struct {
+	Status         string
+	Category       string
+	ReporterID     *int64
+	ReportedUserID *int64
+}
+
+type ReportPagination struct {
+	Reports    []*Report `json:"reports"`
+	Total      int64     `json:"total"`
+	HasMore    bool      `json:"has_more"`
+	TotalPages int64     `json:"total_pages"`
+	Page       int64     `json:"page"`
+	Sort       string    `json:"sort"`
+	Order      string    `json:"order"`
+}
+
+type ReportQueuePagination struct {
+	Groups     []*ReportGroup `json:"groups"`
+	Total      int64          `json:"total"`
+	HasMore    bool           `json:"has_more"`
+	TotalPages int64          `json:"total_pages"`
+	Page       int64          `json:"page"`
+	Sort       string         `json:"sort"`
+	Order      string         `json:"order"`
+}
+
+type ReportRepositoryInterface interface {
+	FindById(id int64) (*Report, error)
+	FindImages(reportID int64) ([]*Image, error)
+	FindAll(page int64, limit int64, sort, order string, filter ReportFilter) (*ReportPagination, error)
+	FindQueue(page int64, limit int64, sort, order, category string) (*ReportQueuePagination, error)
+	ReporterTrust(reporterID int64) (float64, error)
+	Create(report *Report, imageIDs []int64) error
+	StartReview(id int64, reviewerID int64) (*Report, error)
+}
+
+type ReportRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewReportRepository(db *sql.DB) ReportRepositoryInterface {
+	return &ReportRepositoryImpl{db}
+}
+
+const reportColumns = `id, reporter_id, reported_user_id, category, details, status, reviewer_id, resolved_by,
+			  resolution_note, blocked_id, resolved_at, created_at, updated_at`
+
+func scanReport(row interface{ Scan(...any) error }) (*Report, error) {
+	var report Report
+	err := row.Scan(&report.ID, &report.ReporterID, &report.ReportedUserID, &report.Category, &report.Details,
+		&report.Status, &report.ReviewerID, &report.ResolvedBy, &report.ResolutionNote, &report.BlockedID,
+		&report.ResolvedAt, &report.CreatedAt, &report.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (r *ReportRepositoryImpl) FindById(id int64) (*Report, error) {
+	query := `SELECT ` + reportColumns + ` FROM user_reports WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return scanReport(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *ReportRepositoryImpl) FindImages(reportID int64) ([]*Image, error) {
+	query := `SELECT i.id, i.user_id, i.url, i.created_at FROM user_report_images ri
+			  JOIN images i ON i.id = ri.image_id
+			  WHERE ri.report_id = $1 ORDER BY i.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, reportID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := make([]*Image, 0)
+	for rows.Next() {
+		var image Image
+		if err := rows.Scan(&image.ID, &image.UserID, &image.URL, &image.CreatedAt); err != nil {
+			return nil, err
+		}
+		images = append(images, &image)
+	}
+
+	return images, rows.Err()
+}
+
+func (r *ReportRepositoryImpl) FindAll(page int64, limit int64, sort, order string, filter ReportFilter) (*ReportPagination, error) {
+	sort, order = listOrder(sort, order, "id", "status", "category", "reported_user_id", "created_at")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var list listQuery
+
+	if filter.Status != "" {
+		list.where(`status = ` + list.bind(filter.Status))
+	}
+
+	if filter.Category != "" {
+		list.where(`category = ` + list.bind(filter.Category))
+	}
+
+	if filter.ReporterID != nil {
+		list.where(`reporter_id = ` + list.bind(*filter.ReporterID))
+	}
+
+	if filter.ReportedUserID != nil {
+		list.where(`reported_user_id = ` + list.bind(*filter.ReportedUserID))
+	}
+
+	countArgs := list.args()
+	query := `SELECT ` + reportColumns + ` FROM user_reports ` + list.clause() + ` ORDER BY ` + sort + ` ` + order + `, id ` + order + ` ` + list.page(page, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, list.params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := make([]*Report, 0)
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	countQuery := `SELECT COUNT(*) FROM user_reports ` + list.clause()
+	var total int64
+	err = r.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := (total + limit - 1) / limit
+	hasMore := page < totalPages
+
+	return &ReportPagination{
+		Reports:    reports,
+		Total:      total,
+		HasMore:    hasMore,
+		TotalPages: totalPages,
+		Page:       page,
+		Sort:       sort,
+		Order:      order,
+	}, nil
+}
+
+// FindQueue groups pending reports by reported user and ranks the groups.
+func (r *ReportRepositoryImpl) FindQueue(page int64, limit int64, sort, order, category string) (*ReportQueuePagination, error) {
+	sort, order = listOrder(sort, order, "priority", "report_count", "first_reported_at", "last_reported_at")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var list listQuery
+	list.where(reportPendingCondition)
+
+	if category != "" {
+		list.where(`category = ` + list.bind(category))
+	}
+
+	countArgs := list.args()
+
+	query := `WITH trust AS (` + reporterTrustQuery + `),
+			  pending AS (
+				  SELECT r.id, r.reporter_id, r.reported_user_id, r.category, r.created_at,
+				  COALESCE(t.trust, ` + strconv.FormatFloat(reportDefaultTrust, 'f', -1, 64) + `) AS trust
+				  FROM user_reports r LEFT JOIN trust t ON t.reporter_id = r.reporter_id
+				  ` + list.clause() + `
+			  ),
+			  reporters AS (
+				  SELECT reported_user_id, SUM(trust) AS priority, COUNT(*) AS reporter_count
+				  FROM (SELECT DISTINCT reported_user_id, reporter_id, trust FROM pending) d
+				  GROUP BY reported_user_id
+			  )
+			  SELECT p.reported_user_id, COUNT(*) AS report_count, rp.reporter_count, rp.priority,
+			  array_to_string(ARRAY_AGG(DISTINCT p.category), ','), array_to_string(ARRAY_AGG(p.id ORDER BY p.id), ','),
+			  MIN(p.created_at) AS first_reported_at, MAX(p.created_at) AS last_reported_at
+			  FROM pending p JOIN reporters rp ON rp.reported_user_id = p.reported_user_id
+			  GROUP BY p.reported_user_id, rp.reporter_count, rp.priority
+			  ORDER BY ` + sort + ` ` + order + `, p.reported_user_id
+			  ` + list.page(page, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, list.params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make([]*ReportGroup, 0)
+	for rows.Next() {
+		var group ReportGroup
+		var categories, reportIDs string
+		err := rows.Scan(&group.ReportedUserID, &group.ReportCount, &group.ReporterCount, &group.Priority,
+			&categories, &reportIDs, &group.FirstReportedAt, &group.LastReportedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		group.Categories = splitList(categories)
+		for _, id := range splitList(reportIDs) {
+			reportID, err := strconv.ParseInt(id, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			group.ReportIDs = append(group.ReportIDs, reportID)
+		}
+		groups = append(groups, &group)
+	}
+
+	countQuery := `SELECT COUNT(DISTINCT reported_user_id) FROM user_reports ` + list.clause()
+	var total int64
+	err = r.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := (total + limit - 1) / limit
+	hasMore := page < totalPages
+
+	return &ReportQueuePagination{
+		Groups:     groups,
+		Total:      total,
+		HasMore:    hasMore,
+		TotalPages: totalPages,
+		Page:       page,
+		Sort:       sort,
+		Order:      order,
+	}, nil
+}
+
+func (r *ReportRepositoryImpl) ReporterTrust(reporterID int64) (float64, error) {
+	query := `SELECT trust FROM (` + reporterTrustQuery + `) t WHERE reporter_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var trust float64
+	err := r.db.QueryRowContext(ctx, query, reporterID).Scan(&trust)
+	if errors.Is(err, sql.ErrNoRows) {
+		return reportDefaultTrust, nil
+	}
+	return trust, err
+}
+
+// Create stores a report with its evidence images. It returns
+// ErrReportImageNotFound, and stores nothing, when an image id does not
+// exist.
+func (r *ReportRepositoryImpl) Create(report *Report, imageIDs []int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO user_reports (reporter_id, reported_user_id, category, details)
+			  VALUES ($1, $2, $3, $4)
+			  RETURNING ` + reportColumns
+
+	created, err := scanReport(tx.QueryRowContext(ctx, query, report.ReporterID, report.ReportedUserID,
+		report.Category, report.Details))
+	if err != nil {
+		return err
+	}
+
+	if len(imageIDs) > 0 {
+		ids := make([]string, len(imageIDs))
+		for i, id := range imageIDs {
+			ids[i] = strconv.FormatInt(id, 10)
+		}
+
+		attach := `INSERT INTO user_report_images (report_id, image_id)
+				  SELECT $1, id FROM images WHERE id = ANY(string_to_array($2, ',')::bigint[])`
+		result, err := tx.ExecContext(ctx, attach, created.ID, strings.Join(ids, ","))
+		if err != nil {
+			return err
+		}
+		attached, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if attached != int64(len(imageIDs)) {
+			return ErrReportImageNotFound
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	*report = *created
+	return nil
+}
+
+// StartReview claims a pending report for a reviewer. It returns
+// sql.ErrNoRows when the report is already decided.
+func (r *ReportRepositoryImpl) StartReview(id int64, reviewerID int64) (*Report, error) {
+	query := `UPDATE user_reports SET status = 'in_review', reviewer_id = $2, updated_at = NOW()
+			  WHERE id = $1 AND ` + reportPendingCondition + `
+			  RETURNING ` + reportColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return scanReport(r.db.QueryRowContext(ctx, query, id, reviewerID))
+}
+
+// ReportTxRepositoryInterface is the part of the report repository that
+// runs inside a unit of work, so resolving reports and banning the reported
+// user commit together.
+type ReportTxRepositoryInterface interface {
+	FindById(id int64) (*Report, error)
+	Resolve(ids []int64, status string, resolvedBy *int64, note string, blockedID *int64) ([]*Report, error)
+	FindPendingByUser(reportedUserID int64) ([]*Report, error)
+}
+
+type reportTxRepository struct {
+	ctx context.Context
+	tx  *sql.Tx
+}
+
+func (r *reportTxRepository) FindById(id int64) (*Report, error) {
+	query := `SELECT ` + reportColumns + ` FROM user_reports WHERE id = $1 FOR UPDATE`
+
+	return scanReport(r.tx.QueryRowContext(r.ctx, query, id))
+}
+
+func (r *reportTxRepository) FindPendingByUser(reportedUserID int64) ([]*Report, error) {
+	query := `SELECT ` + reportColumns + ` FROM user_reports
+			  WHERE reported_user_id = $1 AND ` + reportPendingCondition + `
+			  ORDER BY id
+			  FOR UPDATE`
+
+	return r.queryReports(query, reportedUserID)
+}
+
+// Resolve closes the given reports that are still pending and returns the
+// ones it changed.
+func (r *reportTxRepository) Resolve(ids []int64, status string, resolvedBy *int64, note string, blockedID *int64) ([]*Report, error) {
+	list := make([]string, len(ids))
+	for i, id := range ids {
+		list[i] = strconv.FormatInt(id, 10)
+	}
+
+	query := `UPDATE user_reports SET status = $2, resolved_by = $3, resolution_note = $4, blocked_id = $5,
+			  reviewer_id = COALESCE(reviewer_id, $3), resolved_at = NOW(), updated_at = NOW()
+			  WHERE id = ANY(string_to_array($1, ',')::bigint[]) AND ` + reportPendingCondition + `
+			  RETURNING ` + reportColumns
+
+	return r.queryReports(query, strings.Join(list, ","), status, resolvedBy, note, blockedID)
+}
+
+func (r *reportTxRepository) queryReports(query string, args ...any) ([]*Report, error) {
+	rows, err := r.tx.QueryContext(r.ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := make([]*Report, 0)
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, rows.Err()
+}
@@ -0,0 +1,317 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	RestrictionModeShadowBan = "shadow_ban"
+	RestrictionModeInboxLock = "inbox_lock"
+	RestrictionModeSwiper    = "swiper_restriction"
+)
+
+// restrictionFlags maps each mode to the users column it overrides and the
+// value it forces while in force.
+var restrictionFlags = map[string]struct {
+	column     string
+	restricted bool
+}{
+	RestrictionModeShadowBan: {"shadow_banned", true},
+	RestrictionModeInboxLock: {"inbox_locked", true},
+	RestrictionModeSwiper:    {"swiper_mode", false},
+}
+
+// ValidRestrictionMode reports whether mode is a known restriction mode.
+func ValidRestrictionMode(mode string) bool {
+	_, ok := restrictionFlags[mode]
+	return ok
+}
+
+// RestrictedValue is the users flag value a restriction of mode enforces.
+func RestrictedValue(mode string) bool {
+	return restrictionFlags[mode].restricted
+}
+
+type UserRestriction struct {
+	ID            int64      `json:"id"`
+	UserID        int64      `json:"user_id"`
+	Mode          string     `json:"mode"`
+	ReasonCode    *string    `json:"reason_code"`
+	Reason        string     `json:"reason"`
+	PreviousValue bool       `json:"previous_value"`
+	CreatedBy     *int64     `json:"created_by"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	LiftedAt      *time.Time `json:"lifted_at"`
+	LiftedBy      *int64     `json:"lifted_by"`
+	LiftReason    *string    `json:"lift_reason"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type RestrictionRepositoryInterface interface {
+	FindById(id int64) (*UserRestriction, error)
+	FindByUser(userID int64) ([]*UserRestriction, error)
+	FindActiveByUser(userID int64) ([]*UserRestriction, error)
+	LiftExpired(limit int) ([]*UserRestriction, error)
+}
+
+type RestrictionRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewRestrictionRepository(db *sql.DB) RestrictionRepositoryInterface {
+	return &RestrictionRepositoryImpl{db}
+}
+
+const restrictionColumns = `id, user_id, mode, reason_code, reason, previous_value, created_by, expires_at,
+			  lifted_at, lifted_by, lift_reason, created_at`
+
+// restrictionActiveCondition matches restrictions that are currently in force.
+const restrictionActiveCondition = `lifted_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())`
+
+// restrictionSweepLock keeps expiry sweeps of restrictions to one replica at
+// a time, like suspensionSweepLock does for bans.
+const restrictionSweepLock = 7307003
+
+func scanRestriction(row interface{ Scan(...any) error }) (*UserRestriction, error) {
+	var restriction UserRestriction
+	err := row.Scan(&restriction.ID, &restriction.UserID, &restriction.Mode, &restriction.ReasonCode,
+		&restriction.Reason, &restriction.PreviousValue, &restriction.CreatedBy, &restriction.ExpiresAt,
+		&restriction.LiftedAt, &restriction.LiftedBy, &restriction.LiftReason, &restriction.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &restriction, nil
+}
+
+func scanRestrictions(rows *sql.Rows) ([]*UserRestriction, error) {
+	defer rows.Close()
+
+	restrictions := make([]*UserRestriction, 0)
+	for rows.Next() {
+		restriction, err := scanRestriction(rows)
+		if err != nil {
+			return nil, err
+		}
+		restrictions = append(restrictions, restriction)
+	}
+
+	return restrictions, rows.Err()
+}
+
+func (r *RestrictionRepositoryImpl) FindById(id int64) (*UserRestriction, error) {
+	query := `SELECT ` + restrictionColumns + ` FROM user_restrictions WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return scanRestriction(r.db.QueryRowContext(ctx, query, id))
+}
+
+// FindByUser returns every restriction the user has had, oldest first.
+func (r *RestrictionRepositoryImpl) FindByUser(userID int64) ([]*UserRestriction, error) {
+	query := `SELECT ` + restrictionColumns + ` FROM user_restrictions WHERE user_id = $1 ORDER BY created_at, id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanRestrictions(rows)
+}
+
+func (r *RestrictionRepositoryImpl) FindActiveByUser(userID int64) ([]*UserRestriction, error) {
+	query := `SELECT ` + restrictionColumns + ` FROM user_restrictions
+			  WHERE user_id = $1 AND ` + restrictionActiveCondition + `
+			  ORDER BY created_at, id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanRestrictions(rows)
+}
+
+// LiftExpired closes up to limit restrictions whose expiry has passed and
+// restores the users flag of any mode left without a restriction. It
+// returns nothing when another replica is already sweeping.
+func (r *RestrictionRepositoryImpl) LiftExpired(limit int) ([]*UserRestriction, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, restrictionSweepLock).Scan(&locked); err != nil {
+		return nil, err
+	}
+	if !locked {
+		return []*UserRestriction{}, nil
+	}
+
+	// Users are locked before their restrictions, the same order as
+	// Restrict and LiftRestriction, so the sweep cannot deadlock with them.
+	due := `SELECT id, user_id, mode FROM (
+				SELECT id, user_id, mode FROM user_restrictions
+				WHERE lifted_at IS NULL AND expires_at <= NOW()
+				ORDER BY expires_at
+				LIMIT $1
+			) due
+			ORDER BY user_id, id`
+	rows, err := tx.QueryContext(ctx, due, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []*UserRestriction
+	for rows.Next() {
+		var c UserRestriction
+		if err := rows.Scan(&c.ID, &c.UserID, &c.Mode); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	lift := `UPDATE user_restrictions SET lifted_at = NOW(), lift_reason = $2
+			 WHERE id = $1 AND lifted_at IS NULL AND expires_at <= NOW()
+			 RETURNING ` + restrictionColumns
+
+	repo := &restrictionTxRepository{ctx, tx}
+	lifted := make([]*UserRestriction, 0, len(candidates))
+	for _, candidate := range candidates {
+		if _, err := repo.LockFlag(candidate.UserID, candidate.Mode); err != nil {
+			return nil, err
+		}
+
+		restriction, err := scanRestriction(tx.QueryRowContext(ctx, lift, candidate.ID, BlockedLiftReasonExpired))
+		if errors.Is(err, sql.ErrNoRows) {
+			// Lifted by a moderator while the sweep waited for the user.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := repo.restoreFlag(restriction); err != nil {
+			return nil, err
+		}
+		lifted = append(lifted, restriction)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return lifted, nil
+}
+
+// RestrictionTxRepositoryInterface holds the restriction operations
+// available inside a unit of work. It owns the users flags the modes
+// override, so a restriction and its flag always change together.
+type RestrictionTxRepositoryInterface interface {
+	// FindById reads the restriction without locking it. Callers lock the
+	// user with LockFlag before changing any of the user's restrictions.
+	FindById(id int64) (*UserRestriction, error)
+	// FindOpen returns the user's restrictions of mode that have not been
+	// lifted, including expired ones the sweep has not reached yet.
+	FindOpen(userID int64, mode string) ([]*UserRestriction, error)
+	// LockFlag locks the user row and returns the current value of the flag
+	// mode overrides.
+	LockFlag(userID int64, mode string) (bool, error)
+	// Insert stores the restriction and forces its flag.
+	Insert(restriction *UserRestriction) error
+	// Lift closes the restriction and restores its flag when no other
+	// restriction of the mode remains open.
+	Lift(id int64, liftedBy *int64, reason string) (*UserRestriction, error)
+}
+
+type restrictionTxRepository struct {
+	ctx context.Context
+	tx  *sql.Tx
+}
+
+func (r *restrictionTxRepository) FindById(id int64) (*UserRestriction, error) {
+	query := `SELECT ` + restrictionColumns + ` FROM user_restrictions WHERE id = $1`
+
+	return scanRestriction(r.tx.QueryRowContext(r.ctx, query, id))
+}
+
+func (r *restrictionTxRepository) FindOpen(userID int64, mode string) ([]*UserRestriction, error) {
+	query := `SELECT ` + restrictionColumns + ` FROM user_restrictions
+			  WHERE user_id = $1 AND mode = $2 AND lifted_at IS NULL
+			  ORDER BY id
+			  FOR UPDATE`
+
+	rows, err := r.tx.QueryContext(r.ctx, query, userID, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanRestrictions(rows)
+}
+
+func (r *restrictionTxRepository) LockFlag(userID int64, mode string) (bool, error) {
+	query := `SELECT ` + restrictionFlags[mode].column + ` FROM users WHERE id = $1 FOR UPDATE`
+
+	var value bool
+	err := r.tx.QueryRowContext(r.ctx, query, userID).Scan(&value)
+	return value, err
+}
+
+func (r *restrictionTxRepository) Insert(restriction *UserRestriction) error {
+	query := `INSERT INTO user_restrictions (user_id, mode, reason_code, reason, previous_value, created_by, expires_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7)
+			  RETURNING ` + restrictionColumns
+
+	created, err := scanRestriction(r.tx.QueryRowContext(r.ctx, query, restriction.UserID, restriction.Mode,
+		restriction.ReasonCode, restriction.Reason, restriction.PreviousValue, restriction.CreatedBy,
+		restriction.ExpiresAt))
+	if err != nil {
+		return err
+	}
+	*restriction = *created
+
+	flag := restrictionFlags[restriction.Mode]
+	_, err = r.tx.ExecContext(r.ctx, `UPDATE users SET `+flag.column+` = $2 WHERE id = $1`,
+		restriction.UserID, flag.restricted)
+	return err
+}
+
+func (r *restrictionTxRepository) Lift(id int64, liftedBy *int64, reason string) (*UserRestriction, error) {
+	query := `UPDATE user_restrictions SET lifted_at = NOW(), lifted_by = $2, lift_reason = $3
+			  WHERE id = $1 AND lifted_at IS NULL
+			  RETURNING ` + restrictionColumns
+
+	lifted, err := scanRestriction(r.tx.QueryRowContext(r.ctx, query, id, liftedBy, reason))
+	if err != nil {
+		return nil, err
+	}
+
+	return lifted, r.restoreFlag(lifted)
+}
+
+func (r *restrictionTxRepository) restoreFlag(lifted *UserRestriction) error {
+	restore := `UPDATE users SET ` + restrictionFlags[lifted.Mode].column + ` = $3
+				WHERE id = $1 AND NOT EXISTS (
+					SELECT 1 FROM user_restrictions WHERE user_id = $1 AND mode = $2 AND lifted_at IS NULL
+				)`
+
+	_, err := r.tx.ExecContext(r.ctx, restore, lifted.UserID, lifted.Mode, lifted.PreviousValue)
+	return err
+}
@@ -0,0 +1,232 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenReused   = errors.New("refresh token reused")
+	ErrSessionRevoked       = errors.New("session revoked")
+)
+
+const (
+	SessionRevokedReasonTokenReuse   = "refresh_token_reuse"
+	SessionRevokedReasonLogout       = "logout"
+	SessionRevokedReasonLogoutOthers = "logout_others"
+	SessionRevokedReasonForced       = "forced_logout"
+	SessionRevokedReasonDeactivated  = "deactivated"
+)
+
+type Session struct {
+	ID            string     `json:"id"`
+	AdminID       int64      `json:"admin_id"`
+	IPAddress     string     `json:"ip_address"`
+	UserAgent     string     `json:"user_agent"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	LastUsedAt    time.Time  `json:"last_used_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason *string    `json:"revoked_reason,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type SessionRepositoryInterface interface {
+	Create(session *Session, tokenID string) error
+	FindById(id string) (*Session, error)
+	Rotate(oldTokenID, newTokenID string, expiresAt time.Time) (*Session, error)
+	FindActiveByAdminId(adminID int64) ([]*Session, error)
+	Revoke(id, reason string) error
+	RevokeAllForAdmin(adminID int64, exceptID, reason string) ([]string, error)
+}
+
+type SessionRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) SessionRepositoryInterface {
+	return &SessionRepositoryImpl{db}
+}
+
+const sessionColumns = `id, admin_id, ip_address, user_agent, expires_at, last_used_at,
+			  revoked_at, revoked_reason, created_at`
+
+func scanSession(row interface{ Scan(...any) error }) (*Session, error) {
+	var session Session
+	err := row.Scan(&session.ID, &session.AdminID, &session.IPAddress, &session.UserAgent,
+		&session.ExpiresAt, &session.LastUsedAt, &session.RevokedAt, &session.RevokedReason,
+		&session.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Create stores a new session together with the first refresh token of its
+// family.
+func (r *SessionRepositoryImpl) Create(session *Session, tokenID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO admin_sessions (id, admin_id, ip_address, user_agent, expires_at)
+			  VALUES ($1, $2, $3, $4, $5) RETURNING last_used_at, created_at`
+	err = tx.QueryRowContext(ctx, query, session.ID, session.AdminID, session.IPAddress,
+		session.UserAgent, session.ExpiresAt).Scan(&session.LastUsedAt, &session.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	tokenQuery := `INSERT INTO admin_refresh_tokens (id, session_id, expires_at) VALUES ($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, tokenQuery, tokenID, session.ID, session.ExpiresAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *SessionRepositoryImpl) FindById(id string) (*Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM admin_sessions WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return scanSession(r.db.QueryRowContext(ctx, query, id))
+}
+
+// Rotate exchanges a refresh token for a new one within the same family.
+// Presenting a token that was already rotated revokes the whole family and
+// returns ErrRefreshTokenReused along with the affected session.
+func (r *SessionRepositoryImpl) Rotate(oldTokenID, newTokenID string, expiresAt time.Time) (*Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var sessionID string
+	var rotatedAt *time.Time
+	tokenQuery := `SELECT session_id, rotated_at FROM admin_refresh_tokens WHERE id = $1 FOR UPDATE`
+	err = tx.QueryRowContext(ctx, tokenQuery, oldTokenID).Scan(&sessionID, &rotatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sessionQuery := `SELECT ` + sessionColumns + ` FROM admin_sessions WHERE id = $1 FOR UPDATE`
+	session, err := scanSession(tx.QueryRowContext(ctx, sessionQuery, sessionID))
+	if err != nil {
+		return nil, err
+	}
+
+	if session.RevokedAt != nil {
+		return session, ErrSessionRevoked
+	}
+
+	if rotatedAt != nil {
+		revoke := `UPDATE admin_sessions SET revoked_at = NOW(), revoked_reason = $2 WHERE id = $1`
+		if _, err := tx.ExecContext(ctx, revoke, session.ID, SessionRevokedReasonTokenReuse); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		return session, ErrRefreshTokenReused
+	}
+
+	rotate := `UPDATE admin_refresh_tokens SET rotated_at = NOW(), replaced_by = $2 WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, rotate, oldTokenID, newTokenID); err != nil {
+		return nil, err
+	}
+
+	insert := `INSERT INTO admin_refresh_tokens (id, session_id, expires_at) VALUES ($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, insert, newTokenID, session.ID, expiresAt); err != nil {
+		return nil, err
+	}
+
+	touch := `UPDATE admin_sessions SET last_used_at = NOW(), expires_at = $2 WHERE id = $1
+			  RETURNING last_used_at, expires_at`
+	err = tx.QueryRowContext(ctx, touch, session.ID, expiresAt).Scan(&session.LastUsedAt, &session.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, tx.Commit()
+}
+
+func (r *SessionRepositoryImpl) Revoke(id, reason string) error {
+	query := `UPDATE admin_sessions SET revoked_at = NOW(), revoked_reason = $2
+			  WHERE id = $1 AND revoked_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, query, id, reason)
+	return err
+}
+
+func (r *SessionRepositoryImpl) FindActiveByAdminId(adminID int64) ([]*Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM admin_sessions
+			  WHERE admin_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+			  ORDER BY last_used_at DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, adminID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]*Session, 0)
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// RevokeAllForAdmin revokes every live session of the admin except exceptID,
+// which may be empty, and returns the IDs it revoked.
+func (r *SessionRepositoryImpl) RevokeAllForAdmin(adminID int64, exceptID, reason string) ([]string, error) {
+	query := `UPDATE admin_sessions SET revoked_at = NOW(), revoked_reason = $3
+			  WHERE admin_id = $1 AND id <> $2 AND revoked_at IS NULL
+			  RETURNING id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, adminID, exceptID, reason)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
@@ -0,0 +1,55 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// TxRepositories exposes the repository operations that can take part in a
+// unit of work. Every call made through it shares one transaction.
+type TxRepositories struct {
+	Blocked      BlockedTxRepositoryInterface
+	Users        UserTxRepositoryInterface
+	Appeals      AppealTxRepositoryInterface
+	Reports      ReportTxRepositoryInterface
+	Restrictions RestrictionTxRepositoryInterface
+}
+
+type UnitOfWorkInterface interface {
+	// Do runs fn in a transaction, committing when fn returns nil and
+	// rolling back otherwise.
+	Do(fn func(repos *TxRepositories) error) error
+}
+
+type UnitOfWorkImpl struct {
+	db *sql.DB
+}
+
+func NewUnitOfWork(db *sql.DB) UnitOfWorkInterface {
+	return &UnitOfWorkImpl{db}
+}
+
+func (u *UnitOfWorkImpl) Do(fn func(repos *TxRepositories) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	repos := &TxRepositories{
+		Blocked:      &blockedTxRepository{ctx, tx},
+		Users:        &userTxRepository{ctx, tx},
+		Appeals:      &appealTxRepository{ctx, tx},
+		Reports:      &reportTxRepository{ctx, tx},
+		Restrictions: &restrictionTxRepository{ctx, tx},
+	}
+	if err := fn(repos); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -3,6 +3,7 @@ package data
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"strconv"
 	"strings"
 	"time"
@@ -25,6 +26,7 @@ type User struct {
 	InboxLocked    bool    `json:"inbox_locked"`
 	SwiperMode     bool    `json:"swiper_mode"`
 	Blocked        bool    `json:"blocked"`
+	ShadowBanned   bool    `json:"shadow_banned"`
 	Name           string  `json:"name,omitempty"`
 	Gender         string  `json:"gender,omitempty"`
 	CountryName    string  `json:"country_name,omitempty"`
@@ -38,6 +40,16 @@ type User struct {
 	Bio            string  `json:"bio,omitempty"`
 	ProfileImageID int64   `json:"profile_image_id,omitempty"`
 	ProfileImage   *Image  `json:"profile_image,omitempty"`
+	// DistanceKm is only filled in when the list is searched by radius.
+	DistanceKm *float64 `json:"distance_km,omitempty"`
+	// Enforcement is only filled in on the user detail endpoint.
+	Enforcement *UserEnforcement `json:"enforcement,omitempty"`
+}
+
+// UserEnforcement gathers everything currently in force against a user.
+type UserEnforcement struct {
+	Bans         []*Blocked         `json:"bans"`
+	Restrictions []*UserRestriction `json:"restrictions"`
 }
 
 type UserPagination struct {
@@ -50,11 +62,49 @@ type UserPagination struct {
 	Order      string  `json:"order"`
 }
 
+// UserFilter narrows the user list. Unset fields do not filter. Search is a
+// case-insensitive substring of the username or name; Genders and
+// CountryIsoCodes match any listed value. The age bounds are inclusive and
+// computed from birthday, and the signup range includes CreatedFrom but
+// not CreatedTo. Radius and Box match on the user's city coordinates, so
+// users without a city never match them.
+type UserFilter struct {
+	Search          string
+	Genders         []string
+	CountryIsoCodes []string
+	CityName        string
+	Verified        *bool
+	IsPrivate       *bool
+	InboxLocked     *bool
+	SwiperMode      *bool
+	Blocked         *bool
+	ShadowBanned    *bool
+	MinAge          *int
+	MaxAge          *int
+	CreatedFrom     *time.Time
+	CreatedTo       *time.Time
+	Radius          *GeoRadius
+	Box             *GeoBox
+}
+
+// GeoRadius matches users whose city is at most Km from Center.
+type GeoRadius struct {
+	Center GeoPoint
+	Km     float64
+}
+
 type UserRepositoryInterface interface {
 	FindByUsername(username string) (*User, error)
 	FindById(id int64) (*User, error)
-	FindAll(page int64, limit int64, sort, order, search string) (*UserPagination, error)
-	ToggleUserBlock(id int64) (bool, error)
+	FindAll(page int64, limit int64, sort, order string, filter UserFilter) (*UserPagination, error)
+	// FindLocated returns up to limit users matching filter that have city
+	// coordinates, in the same order as FindAll. Profile images are not
+	// loaded.
+	FindLocated(sort, order string, filter UserFilter, limit int64) ([]*User, error)
+	// FindExistingIDs returns which of ids belong to a user.
+	FindExistingIDs(ids []int64) (map[int64]bool, error)
+	// FindIDsByUsername maps each username that exists to its user id.
+	FindIDsByUsername(usernames []string) (map[string]int64, error)
 }
 
 type UserRepositoryImpl struct {
@@ -65,178 +115,257 @@ func NewUserRepository(db *sql.DB) UserRepositoryInterface {
 	return &UserRepositoryImpl{db}
 }
 
-func (r *UserRepositoryImpl) FindByUsername(username string) (*User, error) {
-	query := `SELECT id, username, birthday, aws_cognito_id, created_at, verified, is_private,
-			  inbox_locked, swiper_mode, blocked, COALESCE(name, ''), COALESCE(gender, ''),
+const userColumns = `id, username, birthday, aws_cognito_id, created_at, verified, is_private,
+			  inbox_locked, swiper_mode, blocked, shadow_banned, COALESCE(name, ''), COALESCE(gender, ''),
 			  COALESCE(country_name, ''), COALESCE(country_flag, ''), COALESCE(country_iso_code, ''),
 			  COALESCE(country_lat, 0), COALESCE(country_lng, 0), COALESCE(city_name, ''),
-			  COALESCE(city_lat, 0), COALESCE(city_lng, 0), COALESCE(bio, ''), COALESCE(profile_image_id, 0)
-			  FROM users WHERE username = $1`
+			  COALESCE(city_lat, 0), COALESCE(city_lng, 0), COALESCE(bio, ''), COALESCE(profile_image_id, 0)`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// scanUser reads userColumns followed by any extra columns into extra.
+func scanUser(row interface{ Scan(...any) error }, extra ...any) (*User, error) {
+	user := &User{}
+	dest := []any{&user.ID, &user.Username, &user.Birthday, &user.AwsCognitoId, &user.CreatedAt, &user.Verified,
+		&user.IsPrivate, &user.InboxLocked, &user.SwiperMode, &user.Blocked, &user.ShadowBanned, &user.Name,
+		&user.Gender, &user.CountryName, &user.CountryFlag, &user.CountryIsoCode, &user.CountryLat,
+		&user.CountryLng, &user.CityName, &user.CityLat, &user.CityLng, &user.Bio, &user.ProfileImageID}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
 
-	row := r.db.QueryRowContext(ctx, query, username)
+// loadProfileImage attaches the user's profile image. A profile image id
+// that points at a deleted image leaves ProfileImage empty.
+func (r *UserRepositoryImpl) loadProfileImage(ctx context.Context, user *User) error {
+	if user.ProfileImageID == 0 {
+		return nil
+	}
 
-	user := &User{}
-	err := row.Scan(&user.ID, &user.Username, &user.Birthday, &user.AwsCognitoId, &user.CreatedAt, &user.Verified,
-		&user.IsPrivate, &user.InboxLocked, &user.SwiperMode, &user.Blocked, &user.Name, &user.Gender, &user.CountryName,
-		&user.CountryFlag, &user.CountryIsoCode, &user.CountryLat, &user.CountryLng, &user.CityName,
-		&user.CityLat, &user.CityLng, &user.Bio, &user.ProfileImageID)
+	query := `SELECT id, user_id, url, created_at FROM images WHERE id = $1`
 
+	image := &Image{}
+	err := r.db.QueryRowContext(ctx, query, user.ProfileImageID).Scan(&image.ID, &image.UserID, &image.URL, &image.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if user.ProfileImageID != 0 {
-		query = `SELECT id, user_id, url, created_at FROM images WHERE id = $1`
-		row = r.db.QueryRowContext(ctx, query, user.ProfileImageID)
+	user.ProfileImage = image
+	return nil
+}
 
-		image := &Image{}
-		err = row.Scan(&image.ID, &image.UserID, &image.URL, &image.CreatedAt)
-		if err != nil {
-			return nil, err
-		}
+func (r *UserRepositoryImpl) FindByUsername(username string) (*User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE username = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-		user.ProfileImage = image
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, username))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadProfileImage(ctx, user); err != nil {
+		return nil, err
 	}
 
 	return user, nil
 }
 
 func (r *UserRepositoryImpl) FindById(id int64) (*User, error) {
-	query := `SELECT id, username, birthday, aws_cognito_id, created_at, verified, is_private,
-			  inbox_locked, swiper_mode, blocked, COALESCE(name, ''), COALESCE(gender, ''),
-			  COALESCE(country_name, ''), COALESCE(country_flag, ''), COALESCE(country_iso_code, ''),
-			  COALESCE(country_lat, 0), COALESCE(country_lng, 0), COALESCE(city_name, ''),
-			  COALESCE(city_lat, 0), COALESCE(city_lng, 0), COALESCE(bio, ''), COALESCE(profile_image_id, 0)
-			  FROM users WHERE id = $1`
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	row := r.db.QueryRowContext(ctx, query, id)
-
-	user := &User{}
-	err := row.Scan(&user.ID, &user.Username, &user.Birthday, &user.AwsCognitoId, &user.CreatedAt, &user.Verified,
-		&user.IsPrivate, &user.InboxLocked, &user.SwiperMode, &user.Blocked, &user.Name, &user.Gender, &user.CountryName,
-		&user.CountryFlag, &user.CountryIsoCode, &user.CountryLat, &user.CountryLng, &user.CityName,
-		&user.CityLat, &user.CityLng, &user.Bio, &user.ProfileImageID)
-
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		return nil, err
 	}
 
-	if user.ProfileImageID != 0 {
-		query = `SELECT id, user_id, url, created_at FROM images WHERE id = $1`
-		row = r.db.QueryRowContext(ctx, query, user.ProfileImageID)
+	if err := r.loadProfileImage(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
 
-		image := &Image{}
-		err = row.Scan(&image.ID, &image.UserID, &image.URL, &image.CreatedAt)
+// userListQuery turns filter into the conditions of a user list. distance
+// is the SQL for the distance in km from the radius centre, or empty
+// without a radius.
+func userListQuery(filter UserFilter) (list listQuery, distance string) {
+	list.search(filter.Search, "username", "COALESCE(name, '')")
+
+	if len(filter.Genders) > 0 {
+		list.where(`LOWER(gender) = ANY(string_to_array(` + list.bind(strings.Join(filter.Genders, ",")) + `, ','))`)
+	}
+	if len(filter.CountryIsoCodes) > 0 {
+		list.where(`UPPER(country_iso_code) = ANY(string_to_array(` + list.bind(strings.Join(filter.CountryIsoCodes, ",")) + `, ','))`)
+	}
+	if filter.CityName != "" {
+		list.where(`LOWER(city_name) = LOWER(` + list.bind(filter.CityName) + `)`)
+	}
+
+	flags := []struct {
+		column string
+		value  *bool
+	}{
+		{"verified", filter.Verified},
+		{"is_private", filter.IsPrivate},
+		{"inbox_locked", filter.InboxLocked},
+		{"swiper_mode", filter.SwiperMode},
+		{"blocked", filter.Blocked},
+		{"shadow_banned", filter.ShadowBanned},
+	}
+	for _, flag := range flags {
+		if flag.value != nil {
+			list.where(flag.column + ` = ` + list.bind(*flag.value))
+		}
+	}
+
+	// A user is n years old from their nth birthday until the day before
+	// their (n+1)th.
+	if filter.MinAge != nil {
+		list.where(`birthday::date <= CURRENT_DATE - ` + list.bind(*filter.MinAge) + `::int * INTERVAL '1 year'`)
+	}
+	if filter.MaxAge != nil {
+		list.where(`birthday::date > CURRENT_DATE - (` + list.bind(*filter.MaxAge) + `::int + 1) * INTERVAL '1 year'`)
+	}
+
+	if filter.CreatedFrom != nil {
+		list.where(`created_at >= ` + list.bind(*filter.CreatedFrom))
+	}
+	if filter.CreatedTo != nil {
+		list.where(`created_at < ` + list.bind(*filter.CreatedTo))
+	}
+
+	if filter.Box != nil {
+		list.where(geoBoxSQL(&list, splitGeoBox(*filter.Box)))
+	}
+	// The boxes around the circle let the index narrow the rows before the
+	// exact distance is worked out.
+	if filter.Radius != nil {
+		list.where(geoBoxSQL(&list, radiusGeoBoxes(filter.Radius.Center, filter.Radius.Km)))
+		distance = geoDistanceSQL(&list, filter.Radius.Center)
+		list.where(distance + ` <= ` + list.bind(filter.Radius.Km))
+	}
+
+	return list, distance
+}
+
+// userListColumns returns the columns to select for a user list and the
+// sort and order it is listed in. Sorting by distance needs a radius.
+func userListColumns(sort, order, distance string) (string, string, string) {
+	allowed := []string{"id", "username", "created_at", "birthday"}
+	if distance == "" {
+		sort, order = listOrder(sort, order, allowed...)
+		return userColumns, sort, order
+	}
+
+	sort, order = listOrder(sort, order, append(allowed, "distance")...)
+	return userColumns + `, ` + distance + ` AS distance`, sort, order
+}
+
+// scanUsers reads the rows of a user list query, including the distance
+// column when the list has one.
+func scanUsers(rows *sql.Rows, withDistance bool) ([]*User, error) {
+	users := make([]*User, 0)
+	for rows.Next() {
+		var extra []any
+		var distance float64
+		if withDistance {
+			extra = append(extra, &distance)
+		}
+
+		user, err := scanUser(rows, extra...)
 		if err != nil {
 			return nil, err
 		}
+		if withDistance {
+			user.DistanceKm = &distance
+		}
+		users = append(users, user)
+	}
 
-		user.ProfileImage = image
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return user, nil
+	return users, nil
 }
 
-func (r *UserRepositoryImpl) FindAll(page int64, limit int64, sort, order, search string) (*UserPagination, error) {
-	allowedSortFields := map[string]bool{
-		"id":         true,
-		"username":   true,
-		"created_at": true,
+// loadProfileImages attaches the profile image of every user in one query.
+// Profile image ids that point at deleted images leave ProfileImage empty.
+func (r *UserRepositoryImpl) loadProfileImages(ctx context.Context, users []*User) error {
+	byImage := make(map[int64][]*User)
+	ids := make([]string, 0, len(users))
+	for _, user := range users {
+		if user.ProfileImageID == 0 {
+			continue
+		}
+		if _, seen := byImage[user.ProfileImageID]; !seen {
+			ids = append(ids, strconv.FormatInt(user.ProfileImageID, 10))
+		}
+		byImage[user.ProfileImageID] = append(byImage[user.ProfileImageID], user)
 	}
-	if !allowedSortFields[sort] {
-		sort = "id"
+	if len(ids) == 0 {
+		return nil
 	}
 
-	if order != "asc" && order != "desc" {
-		order = "desc"
+	query := `SELECT id, user_id, url, created_at FROM images WHERE id = ANY(string_to_array($1, ',')::bigint[])`
+
+	rows, err := r.db.QueryContext(ctx, query, strings.Join(ids, ","))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		image := &Image{}
+		if err := rows.Scan(&image.ID, &image.UserID, &image.URL, &image.CreatedAt); err != nil {
+			return err
+		}
+		for _, user := range byImage[image.ID] {
+			user.ProfileImage = image
+		}
 	}
 
-	offset := (page - 1) * limit
+	return rows.Err()
+}
 
+func (r *UserRepositoryImpl) FindAll(page int64, limit int64, sort, order string, filter UserFilter) (*UserPagination, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	whereClause := ""
-	queryParams := make([]interface{}, 0)
-	paramCount := 1
-
-	if search != "" {
-		whereClause = `
-            WHERE (
-                LOWER(username) LIKE $1 OR
-                LOWER(COALESCE(name, '')) LIKE $1
-            )`
-		queryParams = append(queryParams, "%"+strings.ToLower(search)+"%")
-		paramCount++
-	}
+	list, distance := userListQuery(filter)
+	columns, sort, order := userListColumns(sort, order, distance)
 
 	var total int64
-	countQuery := `SELECT COUNT(*) FROM users ` + whereClause
-	err := r.db.QueryRowContext(ctx, countQuery, queryParams...).Scan(&total)
+	countQuery := `SELECT COUNT(*) FROM users ` + list.clause()
+	err := r.db.QueryRowContext(ctx, countQuery, list.args()...).Scan(&total)
 	if err != nil {
 		return nil, err
 	}
 
 	totalPages := (total + limit - 1) / limit
 
-	query := `
-        SELECT id, username, birthday, aws_cognito_id, created_at, verified, is_private,
-               inbox_locked, swiper_mode, blocked, COALESCE(name, ''), COALESCE(gender, ''),
-               COALESCE(country_name, ''), COALESCE(country_flag, ''), COALESCE(country_iso_code, ''),
-               COALESCE(country_lat, 0), COALESCE(country_lng, 0), COALESCE(city_name, ''),
-               COALESCE(city_lat, 0), COALESCE(city_lng, 0), COALESCE(bio, ''), COALESCE(profile_image_id, 0)
-        FROM users
-        ` + whereClause + `
-        ORDER BY ` + sort + ` ` + order + `
-        LIMIT $` + strconv.Itoa(paramCount) + ` OFFSET $` + strconv.Itoa(paramCount+1)
-
-	queryParams = append(queryParams, limit, offset)
+	query := `SELECT ` + columns + ` FROM users ` + list.clause() + `
+			  ORDER BY ` + sort + ` ` + order + `, id ` + order + ` ` + list.page(page, limit)
 
-	rows, err := r.db.QueryContext(ctx, query, queryParams...)
+	rows, err := r.db.QueryContext(ctx, query, list.params...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	users := make([]*User, 0)
-	for rows.Next() {
-		user := &User{}
-		err := rows.Scan(
-			&user.ID, &user.Username, &user.Birthday, &user.AwsCognitoId, &user.CreatedAt,
-			&user.Verified, &user.IsPrivate, &user.Blocked, &user.InboxLocked, &user.SwiperMode,
-			&user.Name, &user.Gender, &user.CountryName, &user.CountryFlag,
-			&user.CountryIsoCode, &user.CountryLat, &user.CountryLng,
-			&user.CityName, &user.CityLat, &user.CityLng, &user.Bio,
-			&user.ProfileImageID,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		if user.ProfileImageID != 0 {
-			imageQuery := `SELECT id, user_id, url, created_at FROM images WHERE id = $1`
-			row := r.db.QueryRowContext(ctx, imageQuery, user.ProfileImageID)
-
-			image := &Image{}
-			err = row.Scan(&image.ID, &image.UserID, &image.URL, &image.CreatedAt)
-			if err != nil && err != sql.ErrNoRows {
-				return nil, err
-			}
-			if err != sql.ErrNoRows {
-				user.ProfileImage = image
-			}
-		}
-
-		users = append(users, user)
+	users, err := scanUsers(rows, distance != "")
+	if err != nil {
+		return nil, err
 	}
 
-	if err = rows.Err(); err != nil {
+	if err := r.loadProfileImages(ctx, users); err != nil {
 		return nil, err
 	}
 
@@ -251,16 +380,121 @@ func (r *UserRepositoryImpl) FindAll(page int64, limit int64, sort, order, searc
 	}, nil
 }
 
-func (r *UserRepositoryImpl) ToggleUserBlock(id int64) (bool, error) {
-	query := `UPDATE users SET blocked = NOT blocked WHERE id = $1`
+func (r *UserRepositoryImpl) FindLocated(sort, order string, filter UserFilter, limit int64) ([]*User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	list, distance := userListQuery(filter)
+	list.where(`city_lat IS NOT NULL AND city_lng IS NOT NULL`)
+	columns, sort, order := userListColumns(sort, order, distance)
+
+	query := `SELECT ` + columns + ` FROM users ` + list.clause() + `
+			  ORDER BY ` + sort + ` ` + order + `, id ` + order + ` LIMIT ` + list.bind(limit)
+
+	rows, err := r.db.QueryContext(ctx, query, list.params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// The export has no use for profile images, so they are not loaded.
+	return scanUsers(rows, distance != "")
+}
+
+func (r *UserRepositoryImpl) FindExistingIDs(ids []int64) (map[int64]bool, error) {
+	found := make(map[int64]bool)
+	if len(ids) == 0 {
+		return found, nil
+	}
+
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = strconv.FormatInt(id, 10)
+	}
+
+	query := `SELECT id FROM users WHERE id = ANY(string_to_array($1, ',')::bigint[])`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, strings.Join(values, ","))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		found[id] = true
+	}
+
+	return found, rows.Err()
+}
+
+// FindIDsByUsername matches usernames exactly. The names travel as one
+// comma separated parameter, so a name containing a comma never matches.
+func (r *UserRepositoryImpl) FindIDsByUsername(usernames []string) (map[string]int64, error) {
+	found := make(map[string]int64)
+	values := make([]string, 0, len(usernames))
+	for _, username := range usernames {
+		if username != "" && !strings.Contains(username, ",") {
+			values = append(values, username)
+		}
+	}
+	if len(values) == 0 {
+		return found, nil
+	}
+
+	query := `SELECT id, username FROM users WHERE username = ANY(string_to_array($1, ','))`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	_, err := r.db.ExecContext(ctx, query, id)
+	rows, err := r.db.QueryContext(ctx, query, strings.Join(values, ","))
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var username string
+		if err := rows.Scan(&id, &username); err != nil {
+			return nil, err
+		}
+		found[username] = id
 	}
 
-	return true, nil
+	return found, rows.Err()
+}
+
+// UserTxRepositoryInterface holds the user operations available inside a
+// unit of work.
+type UserTxRepositoryInterface interface {
+	// LockBlocked locks the user row and returns its current blocked flag.
+	LockBlocked(id int64) (bool, error)
+	SetBlocked(id int64, blocked bool) error
+}
+
+type userTxRepository struct {
+	ctx context.Context
+	tx  *sql.Tx
+}
+
+func (r *userTxRepository) LockBlocked(id int64) (bool, error) {
+	query := `SELECT blocked FROM users WHERE id = $1 FOR UPDATE`
+
+	var blocked bool
+	err := r.tx.QueryRowContext(r.ctx, query, id).Scan(&blocked)
+	return blocked, err
+}
+
+func (r *userTxRepository) SetBlocked(id int64, blocked bool) error {
+	query := `UPDATE users SET blocked = $2 WHERE id = $1`
+
+	_, err := r.tx.ExecContext(r.ctx, query, id, blocked)
+	return err
 }
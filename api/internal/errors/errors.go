@@ -12,7 +12,10 @@ type ErrorType string
 const (
 	ErrorTypeValidation     ErrorType = "VALIDATION_ERROR"
 	ErrorTypeAuthentication ErrorType = "AUTHENTICATION_ERROR"
+	ErrorTypeForbidden      ErrorType = "FORBIDDEN"
 	ErrorTypeNotFound       ErrorType = "NOT_FOUND"
+	ErrorTypeConflict       ErrorType = "CONFLICT"
+	ErrorTypeRateLimited    ErrorType = "RATE_LIMITED"
 	ErrorTypeInternal       ErrorType = "INTERNAL_ERROR"
 )
 
@@ -46,6 +49,14 @@ func NewAuthenticationError(message string) *AppError {
 	}
 }
 
+func NewForbiddenError(message string) *AppError {
+	return &AppError{
+		Type:       ErrorTypeForbidden,
+		Message:    message,
+		HTTPStatus: http.StatusForbidden,
+	}
+}
+
 func NewNotFoundError(message string) *AppError {
 	return &AppError{
 		Type:       ErrorTypeNotFound,
@@ -54,6 +65,22 @@ func NewNotFoundError(message string) *AppError {
 	}
 }
 
+func NewConflictError(message string) *AppError {
+	return &AppError{
+		Type:       ErrorTypeConflict,
+		Message:    message,
+		HTTPStatus: http.StatusConflict,
+	}
+}
+
+func NewRateLimitError(message string) *AppError {
+	return &AppError{
+		Type:       ErrorTypeRateLimited,
+		Message:    message,
+		HTTPStatus: http.StatusTooManyRequests,
+	}
+}
+
 func NewInternalError(message string) *AppError {
 	return &AppError{
 		Type:       ErrorTypeInternal,
@@ -1,7 +1,21 @@
 package models
 
-type CreateAdminRequest struct {
-	Email    string `json:"email" binding:"required"`
-	Password string `json:"password" binding:"required"`
+type CreateInviteRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role"`
+}
+
+type RedeemInviteRequest struct {
+	Token    string `json:"token" binding:"required"`
 	Name     string `json:"name" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type UpdateAdminRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email" binding:"omitempty,email"`
+}
+
+type ChangeAdminRoleRequest struct {
+	Role string `json:"role" binding:"required"`
 }
@@ -0,0 +1,19 @@
+package models
+
+type SubmitAppealRequest struct {
+	BlockedID int64  `json:"blocked_id" binding:"required"`
+	Statement string `json:"statement" binding:"required"`
+}
+
+type AssignAppealRequest struct {
+	ReviewerID int64 `json:"reviewer_id" binding:"required"`
+}
+
+type AppealCommentRequest struct {
+	Body string `json:"body" binding:"required"`
+}
+
+type DecideAppealRequest struct {
+	Decision string `json:"decision" binding:"required"`
+	Note     string `json:"note" binding:"required"`
+}
@@ -0,0 +1,18 @@
+package models
+
+type CreateBanReasonRequest struct {
+	Code            string `json:"code" binding:"required"`
+	Label           string `json:"label" binding:"required"`
+	Description     string `json:"description"`
+	Severity        string `json:"severity" binding:"required"`
+	DefaultDuration string `json:"default_duration"`
+}
+
+type EscalationStepRequest struct {
+	Action   string `json:"action" binding:"required"`
+	Duration string `json:"duration"`
+}
+
+type UpdateEscalationLadderRequest struct {
+	Steps []EscalationStepRequest `json:"steps" binding:"required,dive"`
+}
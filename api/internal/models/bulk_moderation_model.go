@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// BulkBlockRequest applies one ban or restriction to many users at once.
+// Mode and the enforcement fields follow the same rules as creating a single
+// ban or restriction. Requests above the inline limit, or with async set,
+// run as a background job.
+type BulkBlockRequest struct {
+	UserIDs        []int64    `json:"user_ids"`
+	Usernames      []string   `json:"usernames"`
+	Mode           string     `json:"mode"`
+	ReasonCode     string     `json:"reason_code" binding:"required"`
+	Reason         string     `json:"reason"`
+	Enforcement    string     `json:"enforcement"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	Permanent      bool       `json:"permanent"`
+	Override       bool       `json:"override"`
+	OverrideReason string     `json:"override_reason"`
+	Async          bool       `json:"async"`
+}
+
+// BanImportRequest carries the form fields of a ban list upload next to the
+// file itself. The column fields map the file's columns; the rest choose
+// the ban applied to every row once the import is confirmed.
+type BanImportRequest struct {
+	Format         string     `form:"format"`
+	UserIDColumn   string     `form:"user_id_column"`
+	UsernameColumn string     `form:"username_column"`
+	NotesColumn    string     `form:"notes_column"`
+	ReasonCode     string     `form:"reason_code" binding:"required"`
+	Reason         string     `form:"reason"`
+	Enforcement    string     `form:"enforcement"`
+	ExpiresAt      *time.Time `form:"expires_at" time_format:"2006-01-02T15:04:05Z07:00"`
+	Permanent      bool       `form:"permanent"`
+	Override       bool       `form:"override"`
+	OverrideReason string     `form:"override_reason"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+type SubmitReportRequest struct {
+	ReporterID     int64   `json:"reporter_id" binding:"required"`
+	ReportedUserID int64   `json:"reported_user_id" binding:"required"`
+	Category       string  `json:"category" binding:"required"`
+	Details        string  `json:"details"`
+	ImageIDs       []int64 `json:"image_ids"`
+}
+
+// ReportBlockRequest bans the reported user while resolving a report. It
+// follows the same rules as creating a ban directly.
+type ReportBlockRequest struct {
+	ReasonCode     string     `json:"reason_code" binding:"required"`
+	Reason         string     `json:"reason"`
+	Enforcement    string     `json:"enforcement"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	Permanent      bool       `json:"permanent"`
+	Override       bool       `json:"override"`
+	OverrideReason string     `json:"override_reason"`
+}
+
+type ResolveReportRequest struct {
+	Outcome           string              `json:"outcome" binding:"required"`
+	Note              string              `json:"note" binding:"required"`
+	IncludeDuplicates bool                `json:"include_duplicates"`
+	Block             *ReportBlockRequest `json:"block"`
+}
@@ -0,0 +1,228 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testClientID = "admin-dashboard"
+
+// testIdP serves discovery, a JWKS with one RSA and one P-256 key, and a
+// token endpoint that returns idToken once the PKCE verifier checks out.
+type testIdP struct {
+	server    *httptest.Server
+	rsaKey    *rsa.PrivateKey
+	ecKey     *ecdsa.PrivateKey
+	challenge string
+	idToken   string
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &testIdP{rsaKey: rsaKey, ecKey: ecKey}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(discoveryDocument{
+			Issuer:                idp.server.URL,
+			AuthorizationEndpoint: idp.server.URL + "/authorize",
+			TokenEndpoint:         idp.server.URL + "/token",
+			JwksURI:               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []jsonWebKey{
+			{KeyType: "RSA", KeyID: "rsa", Use: "sig", N: encodeBigInt(rsaKey.N), E: encodeBigInt(big.NewInt(int64(rsaKey.E)))},
+			{KeyType: "EC", KeyID: "ec", Curve: "P-256", X: encodeBigInt(ecKey.X), Y: encodeBigInt(ecKey.Y)},
+			{KeyType: "RSA", KeyID: "enc", Use: "enc", N: encodeBigInt(rsaKey.N), E: "AQAB"},
+		}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if CodeChallenge(r.PostFormValue("code_verifier")) != idp.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idp.idToken})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+func (idp *testIdP) provider() *Provider {
+	return NewProvider(Config{IssuerURL: idp.server.URL, ClientID: testClientID, RedirectURL: "https://admin.example/callback"})
+}
+
+func (idp *testIdP) claims(nonce string) map[string]interface{} {
+	return map[string]interface{}{
+		"iss":   idp.server.URL,
+		"sub":   "user-1",
+		"aud":   testClientID,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": nonce,
+		"email": "ops@vemeet.com",
+	}
+}
+
+// sign builds a compact JWS with the given header and claims, signing with
+// the key that matches alg. Unknown algorithms get an empty signature.
+func (idp *testIdP) sign(t *testing.T, header, claims map[string]interface{}) string {
+	t.Helper()
+
+	encode := func(v interface{}) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signingInput := encode(header) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch header["alg"] {
+	case "RS256":
+		sig, err := rsa.SignPKCS1v15(rand.Reader, idp.rsaKey, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = sig
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, idp.ecKey, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func encodeBigInt(n *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(n.Bytes())
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	idp := newTestIdP(t)
+	const nonce = "nonce-1"
+
+	rs256 := map[string]interface{}{"alg": "RS256", "kid": "rsa"}
+	es256 := map[string]interface{}{"alg": "ES256", "kid": "ec"}
+	with := func(key string, value interface{}) map[string]interface{} {
+		claims := idp.claims(nonce)
+		claims[key] = value
+		return claims
+	}
+	multiAudience := func(azp string) map[string]interface{} {
+		claims := with("aud", []string{"other", testClientID})
+		claims["azp"] = azp
+		return claims
+	}
+	tamper := func(token string) string {
+		parts := strings.Split(token, ".")
+		payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		payload = []byte(strings.Replace(string(payload), "ops@vemeet.com", "boss@vemeet.com", 1))
+		return parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+	}
+	swapSignature := func(token, from string) string {
+		return token[:strings.LastIndex(token, ".")] + from[strings.LastIndex(from, "."):]
+	}
+	otherToken := idp.sign(t, rs256, with("sub", "user-2"))
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{name: "RS256", token: idp.sign(t, rs256, idp.claims(nonce))},
+		{name: "ES256", token: idp.sign(t, es256, idp.claims(nonce))},
+		{name: "audience list with this client as authorized party", token: idp.sign(t, rs256, multiAudience(testClientID))},
+		{name: "RS256 payload changed after signing", token: tamper(idp.sign(t, rs256, idp.claims(nonce))), wantErr: "invalid id token signature"},
+		{name: "ES256 payload changed after signing", token: tamper(idp.sign(t, es256, idp.claims(nonce))), wantErr: "invalid id token signature"},
+		{name: "signature of another token", token: swapSignature(idp.sign(t, rs256, idp.claims(nonce)), otherToken), wantErr: "invalid id token signature"},
+		{name: "RS256 header on the EC key", token: idp.sign(t, map[string]interface{}{"alg": "RS256", "kid": "ec"}, idp.claims(nonce)), wantErr: "id token key type does not match RS256"},
+		{name: "ES256 header on the RSA key", token: idp.sign(t, map[string]interface{}{"alg": "ES256", "kid": "rsa"}, idp.claims(nonce)), wantErr: "id token key type does not match ES256"},
+		{name: "alg none", token: idp.sign(t, map[string]interface{}{"alg": "none", "kid": "rsa"}, idp.claims(nonce)), wantErr: `unsupported id token algorithm "none"`},
+		{name: "HS256", token: idp.sign(t, map[string]interface{}{"alg": "HS256", "kid": "rsa"}, idp.claims(nonce)), wantErr: `unsupported id token algorithm "HS256"`},
+		{name: "unknown key id", token: idp.sign(t, map[string]interface{}{"alg": "RS256", "kid": "rotated"}, idp.claims(nonce)), wantErr: `unknown id token key "rotated"`},
+		{name: "encryption key", token: idp.sign(t, map[string]interface{}{"alg": "RS256", "kid": "enc"}, idp.claims(nonce)), wantErr: `unknown id token key "enc"`},
+		{name: "wrong audience", token: idp.sign(t, rs256, with("aud", "another-client")), wantErr: "id token audience does not include this client"},
+		{name: "audience list without authorized party", token: idp.sign(t, rs256, multiAudience("")), wantErr: "id token authorized party mismatch"},
+		{name: "audience list with another authorized party", token: idp.sign(t, rs256, multiAudience("other")), wantErr: "id token authorized party mismatch"},
+		{name: "wrong issuer", token: idp.sign(t, rs256, with("iss", "https://evil.example")), wantErr: `unexpected id token issuer "https://evil.example"`},
+		{name: "wrong nonce", token: idp.sign(t, rs256, with("nonce", "nonce-2")), wantErr: "id token nonce mismatch"},
+		{name: "missing nonce", token: idp.sign(t, rs256, with("nonce", "")), wantErr: "id token nonce mismatch"},
+		{name: "expired", token: idp.sign(t, es256, with("exp", time.Now().Add(-2*clockSkew).Unix())), wantErr: "id token expired"},
+		{name: "expired within clock skew", token: idp.sign(t, es256, with("exp", time.Now().Add(-clockSkew/2).Unix()))},
+		{name: "issued in the future", token: idp.sign(t, rs256, with("iat", time.Now().Add(2*clockSkew).Unix())), wantErr: "id token issued in the future"},
+		{name: "no subject", token: idp.sign(t, rs256, with("sub", "")), wantErr: "id token has no subject"},
+		{name: "two segments", token: "a.b", wantErr: "malformed id token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := idp.provider().VerifyIDToken(context.Background(), tt.token, nonce)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			case err == nil && claims.Email != "ops@vemeet.com":
+				t.Fatalf("email = %q", claims.Email)
+			}
+		})
+	}
+}
+
+func TestExchange(t *testing.T) {
+	idp := newTestIdP(t)
+	const verifier = "verifier-1"
+	idp.challenge = CodeChallenge(verifier)
+	idp.idToken = idp.sign(t, map[string]interface{}{"alg": "ES256", "kid": "ec"}, idp.claims("nonce-1"))
+
+	tests := []struct {
+		name     string
+		verifier string
+		nonce    string
+		wantOK   bool
+	}{
+		{name: "matching verifier and nonce", verifier: verifier, nonce: "nonce-1", wantOK: true},
+		{name: "nonce from another login", verifier: verifier, nonce: "nonce-2"},
+		{name: "wrong PKCE verifier", verifier: "verifier-2", nonce: "nonce-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := idp.provider().Exchange(context.Background(), "code", tt.verifier, tt.nonce)
+			if (err == nil) != tt.wantOK {
+				t.Errorf("error = %v, want ok %v", err, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// jwksRefreshInterval limits how often an unknown key ID can trigger a JWKS
+// refetch, so forged tokens cannot hammer the provider.
+const jwksRefreshInterval = 30 * time.Second
+
+type keySet struct {
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+func (p *Provider) signingKey(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	cached := p.keys
+	p.mu.Unlock()
+
+	if cached != nil {
+		if key, ok := cached.lookup(keyID); ok {
+			return key, nil
+		}
+		if time.Since(cached.fetchedAt) < jwksRefreshInterval {
+			return nil, fmt.Errorf("unknown id token key %q", keyID)
+		}
+	}
+
+	fresh, err := p.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.keys = fresh
+	p.mu.Unlock()
+
+	if key, ok := fresh.lookup(keyID); ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown id token key %q", keyID)
+}
+
+// lookup returns the key for keyID. Tokens without a key ID are accepted only
+// when the set holds exactly one key.
+func (s *keySet) lookup(keyID string) (crypto.PublicKey, bool) {
+	if keyID == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+
+	key, ok := s.keys[keyID]
+	return key, ok
+}
+
+func (p *Provider) fetchKeys(ctx context.Context) (*keySet, error) {
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(ctx, doc.JwksURI, &document); err != nil {
+		return nil, fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+
+	set := &keySet{keys: make(map[string]crypto.PublicKey), fetchedAt: time.Now()}
+	for _, jwk := range document.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+		set.keys[jwk.KeyID] = key
+	}
+
+	if len(set.keys) == 0 {
+		return nil, errors.New("jwks contains no usable signing keys")
+	}
+
+	return set, nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Curve != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
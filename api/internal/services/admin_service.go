@@ -1,12 +1,18 @@
 package services
 
 import (
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/valu/vemeet-admin-api/internal/auth"
 	"github.com/valu/vemeet-admin-api/internal/data"
 	"github.com/valu/vemeet-admin-api/internal/errors"
 )
 
 type AdminService struct {
-	adminRepo data.AdminRepositoryInterface
+	adminRepo      data.AdminRepositoryInterface
+	sessionService SessionServiceInterface
+	policy         *PasswordPolicy
 }
 
 type AdminServiceInterface interface {
@@ -14,11 +20,21 @@ type AdminServiceInterface interface {
 	FindAdminById(id int64) (*data.Admin, error)
 	InsertAdmin(admin *data.Admin) error
 	UpdateAdmin(admin *data.Admin) error
-	FindAllAdmins() ([]*data.Admin, error)
+	FindAllAdmins(page int64, limit int64, sort, order, search string) (*data.AdminPagination, error)
+	UpdateProfile(id int64, name, email string) (*data.Admin, error)
+	ChangeRole(actorID, id int64, role string) (*data.Admin, error)
+	SetVerified(actorID, id int64, verified bool) (*data.Admin, error)
+	SetActive(actorID, id int64, active bool) (*data.Admin, error)
+	HasPermission(id int64, permission auth.Permission) (bool, error)
+	BootstrapAdmin(email, password, name string) (bool, error)
 }
 
-func NewAdminService(adminRepo data.AdminRepositoryInterface) AdminServiceInterface {
-	return &AdminService{adminRepo}
+func NewAdminService(
+	adminRepo data.AdminRepositoryInterface,
+	sessionService SessionServiceInterface,
+	policy *PasswordPolicy,
+) AdminServiceInterface {
+	return &AdminService{adminRepo, sessionService, policy}
 }
 
 func (s *AdminService) FindAdminByEmail(email string) (*data.Admin, error) {
@@ -64,6 +80,13 @@ func (s *AdminService) InsertAdmin(admin *data.Admin) error {
 
 	admin.Password = hashedPassword
 
+	if admin.Role == "" {
+		admin.Role = string(auth.RoleViewer)
+	}
+	if !auth.Role(admin.Role).Valid() {
+		return errors.NewValidationError("invalid role")
+	}
+
 	err = s.adminRepo.InserAdmin(admin)
 	if err != nil {
 		return errors.NewValidationError("admin not inserted")
@@ -85,11 +108,218 @@ func (s *AdminService) UpdateAdmin(admin *data.Admin) error {
 	return nil
 }
 
-func (s *AdminService) FindAllAdmins() ([]*data.Admin, error) {
-	admins, err := s.adminRepo.FindAll()
+func (s *AdminService) FindAllAdmins(page int64, limit int64, sort, order, search string) (*data.AdminPagination, error) {
+	admins, err := s.adminRepo.FindAll(page, limit, sort, order, strings.ToLower(search))
 	if err != nil {
-		return nil, errors.NewNotFoundError("admins not found")
+		return nil, errors.NewInternalError("failed to get admins")
 	}
 
 	return admins, nil
 }
+
+func (s *AdminService) UpdateProfile(id int64, name, email string) (*data.Admin, error) {
+	admin, err := s.FindAdminById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if name = strings.TrimSpace(name); name != "" {
+		admin.Name = name
+	}
+
+	if email = strings.ToLower(strings.TrimSpace(email)); email != "" && email != admin.Email {
+		if _, err := s.adminRepo.FindByEmail(email); err == nil {
+			return nil, errors.NewValidationError("email already exists")
+		}
+		admin.Email = email
+	}
+
+	if err := s.adminRepo.UpdateAdmin(admin); err != nil {
+		return nil, errors.NewInternalError("failed to update admin")
+	}
+
+	return admin, nil
+}
+
+func (s *AdminService) ChangeRole(actorID, id int64, role string) (*data.Admin, error) {
+	if !auth.Role(role).Valid() {
+		return nil, errors.NewValidationError("invalid role")
+	}
+	if actorID == id {
+		return nil, errors.NewForbiddenError("you cannot change your own role")
+	}
+
+	admin, err := s.FindAdminById(id)
+	if err != nil {
+		return nil, err
+	}
+	if admin.Role == role {
+		return admin, nil
+	}
+
+	if err := s.ensureNotLastSuperadmin(admin); err != nil {
+		return nil, err
+	}
+
+	admin.Role = role
+	if err := s.adminRepo.UpdateAdmin(admin); err != nil {
+		return nil, errors.NewInternalError("failed to update admin")
+	}
+
+	return admin, nil
+}
+
+// SetVerified approves or withdraws an admin account. Unverifying signs the
+// admin out everywhere.
+func (s *AdminService) SetVerified(actorID, id int64, verified bool) (*data.Admin, error) {
+	if actorID == id && !verified {
+		return nil, errors.NewForbiddenError("you cannot unverify yourself")
+	}
+
+	admin, err := s.FindAdminById(id)
+	if err != nil {
+		return nil, err
+	}
+	if admin.Verified == verified {
+		// Revoking again lets a retry finish what a failed revocation left.
+		if !verified {
+			if err := s.revokeSessions(admin.ID); err != nil {
+				return nil, err
+			}
+		}
+		return admin, nil
+	}
+
+	if !verified {
+		if err := s.ensureNotLastSuperadmin(admin); err != nil {
+			return nil, err
+		}
+	}
+
+	admin.Verified = verified
+	if err := s.adminRepo.UpdateAdmin(admin); err != nil {
+		return nil, errors.NewInternalError("failed to update admin")
+	}
+
+	if !verified {
+		if err := s.revokeSessions(admin.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return admin, nil
+}
+
+// SetActive deactivates or reactivates an admin. Deactivation revokes every
+// session at once, so existing access tokens stop working immediately.
+func (s *AdminService) SetActive(actorID, id int64, active bool) (*data.Admin, error) {
+	if actorID == id && !active {
+		return nil, errors.NewForbiddenError("you cannot deactivate yourself")
+	}
+
+	admin, err := s.FindAdminById(id)
+	if err != nil {
+		return nil, err
+	}
+	if (admin.DeactivatedAt == nil) == active {
+		// Revoking again lets a retry finish what a failed revocation left.
+		if !active {
+			if err := s.revokeSessions(admin.ID); err != nil {
+				return nil, err
+			}
+		}
+		return admin, nil
+	}
+
+	if !active {
+		if err := s.ensureNotLastSuperadmin(admin); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.adminRepo.SetDeactivated(id, !active); err != nil {
+		return nil, errors.NewInternalError("failed to update admin")
+	}
+
+	if !active {
+		if err := s.revokeSessions(admin.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.FindAdminById(id)
+}
+
+// ensureNotLastSuperadmin stops the last active superadmin from being
+// demoted, unverified or deactivated, which would lock everyone out of
+// admin management.
+func (s *AdminService) ensureNotLastSuperadmin(admin *data.Admin) error {
+	if auth.Role(admin.Role) != auth.RoleSuperadmin || !admin.Active() {
+		return nil
+	}
+
+	count, err := s.adminRepo.CountActiveByRole(string(auth.RoleSuperadmin))
+	if err != nil {
+		return errors.NewInternalError("failed to count admins")
+	}
+	if count <= 1 {
+		return errors.NewValidationError("cannot remove the last active superadmin")
+	}
+
+	return nil
+}
+
+// revokeSessions signs a disabled admin out everywhere. Its error fails the
+// request, so the caller never reports an admin as disabled while their
+// sessions are still live.
+func (s *AdminService) revokeSessions(adminID int64) error {
+	revoked, err := s.sessionService.RevokeAllSessions(adminID, data.SessionRevokedReasonDeactivated)
+	if err != nil {
+		log.Error().Err(err).Int64("admin_id", adminID).Msg("Failed to revoke sessions of disabled admin")
+		return errors.NewInternalError("admin was disabled but their sessions could not be revoked, try again")
+	}
+	log.Info().Int64("admin_id", adminID).Int("revoked", revoked).Msg("Revoked sessions of disabled admin")
+	return nil
+}
+
+func (s *AdminService) HasPermission(id int64, permission auth.Permission) (bool, error) {
+	admin, err := s.FindAdminById(id)
+	if err != nil {
+		return false, err
+	}
+
+	if !admin.Active() {
+		return false, nil
+	}
+
+	return auth.HasPermission(auth.Role(admin.Role), permission), nil
+}
+
+// BootstrapAdmin creates the first superadmin. It is a no-op once any admin
+// exists, so every later account has to come through an invite.
+func (s *AdminService) BootstrapAdmin(email, password, name string) (bool, error) {
+	count, err := s.adminRepo.Count()
+	if err != nil {
+		return false, errors.NewInternalError("failed to count admins")
+	}
+	if count > 0 {
+		return false, nil
+	}
+
+	if err := s.policy.Validate(password, nil); err != nil {
+		return false, err
+	}
+
+	admin := &data.Admin{
+		Email:    email,
+		Password: password,
+		Name:     name,
+		Role:     string(auth.RoleSuperadmin),
+		Verified: true,
+	}
+	if err := s.InsertAdmin(admin); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"database/sql"
+	stdErrors "errors"
+	"testing"
+	"time"
+
+	"github.com/valu/vemeet-admin-api/internal/auth"
+	"github.com/valu/vemeet-admin-api/internal/data"
+)
+
+type stateAdminRepo struct {
+	data.AdminRepositoryInterface
+	admins map[int64]*data.Admin
+}
+
+func (r *stateAdminRepo) FindById(id int64) (*data.Admin, error) {
+	admin, ok := r.admins[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *admin
+	return &copied, nil
+}
+
+func (r *stateAdminRepo) UpdateAdmin(admin *data.Admin) error {
+	copied := *admin
+	r.admins[admin.ID] = &copied
+	return nil
+}
+
+func (r *stateAdminRepo) SetDeactivated(id int64, deactivated bool) error {
+	admin := r.admins[id]
+	admin.DeactivatedAt = nil
+	if deactivated {
+		now := time.Now()
+		admin.DeactivatedAt = &now
+	}
+	return nil
+}
+
+// revokingSessions fails every revocation until failures runs out.
+type revokingSessions struct {
+	SessionServiceInterface
+	failures int
+	revoked  []int64
+}
+
+func (s *revokingSessions) RevokeAllSessions(adminID int64, reason string) (int, error) {
+	if s.failures > 0 {
+		s.failures--
+		return 0, stdErrors.New("connection reset")
+	}
+	s.revoked = append(s.revoked, adminID)
+	return 1, nil
+}
+
+func TestDisablingAdminFailsUntilSessionsAreRevoked(t *testing.T) {
+	tests := []struct {
+		name    string
+		disable func(s AdminServiceInterface) (*data.Admin, error)
+	}{
+		{
+			name: "deactivate",
+			disable: func(s AdminServiceInterface) (*data.Admin, error) {
+				return s.SetActive(1, 2, false)
+			},
+		},
+		{
+			name: "unverify",
+			disable: func(s AdminServiceInterface) (*data.Admin, error) {
+				return s.SetVerified(1, 2, false)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &stateAdminRepo{admins: map[int64]*data.Admin{
+				2: {ID: 2, Role: string(auth.RoleViewer), Verified: true},
+			}}
+			sessions := &revokingSessions{failures: 1}
+			service := NewAdminService(repo, sessions, nil)
+
+			if _, err := tt.disable(service); err == nil {
+				t.Fatal("expected an error when sessions could not be revoked")
+			}
+
+			// The admin is already disabled, and a retry still revokes.
+			admin, err := tt.disable(service)
+			if err != nil {
+				t.Fatalf("retry: %v", err)
+			}
+			if admin.Active() {
+				t.Error("admin is still active")
+			}
+			if len(sessions.revoked) != 1 || sessions.revoked[0] != 2 {
+				t.Errorf("revoked sessions of %v, want [2]", sessions.revoked)
+			}
+		})
+	}
+}
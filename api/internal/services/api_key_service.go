@@ -0,0 +1,197 @@
+package services
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/valu/vemeet-admin-api/internal/auth"
+	"github.com/valu/vemeet-admin-api/internal/data"
+	"github.com/valu/vemeet-admin-api/internal/errors"
+)
+
+// ApiKeyPrefix marks API keys so the auth middleware can tell them apart
+// from PASETO tokens in the same Authorization header.
+const ApiKeyPrefix = "vmk_"
+
+type ApiKeyService struct {
+	apiKeyRepo data.ApiKeyRepositoryInterface
+	adminRepo  data.AdminRepositoryInterface
+}
+
+type ApiKeyServiceInterface interface {
+	CreateApiKey(adminID int64, name string, scopes []string, expiresAt *time.Time, allowedIPs []string) (*data.ApiKey, string, error)
+	ListApiKeys(adminID int64) ([]*data.ApiKey, error)
+	RevokeApiKey(adminID, keyID int64) error
+	Authenticate(rawKey, ip string) (*data.ApiKey, error)
+}
+
+func NewApiKeyService(
+	apiKeyRepo data.ApiKeyRepositoryInterface,
+	adminRepo data.AdminRepositoryInterface,
+) ApiKeyServiceInterface {
+	return &ApiKeyService{apiKeyRepo, adminRepo}
+}
+
+// CreateApiKey mints a key for adminID. A key can never carry a scope its
+// owner does not hold, and the raw key is only returned here.
+func (s *ApiKeyService) CreateApiKey(adminID int64, name string, scopes []string, expiresAt *time.Time, allowedIPs []string) (*data.ApiKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", errors.NewValidationError("name is required")
+	}
+	if len(scopes) == 0 {
+		return nil, "", errors.NewValidationError("at least one scope is required")
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", errors.NewValidationError("expiry must be in the future")
+	}
+
+	admin, err := s.adminRepo.FindById(adminID)
+	if err != nil {
+		return nil, "", errors.NewNotFoundError("admin not found")
+	}
+
+	seen := make(map[string]struct{}, len(scopes))
+	uniqueScopes := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !auth.Permission(scope).Valid() {
+			return nil, "", errors.NewValidationError("unknown scope: " + scope)
+		}
+		if !auth.HasPermission(auth.Role(admin.Role), auth.Permission(scope)) {
+			return nil, "", errors.NewForbiddenError("you do not hold scope: " + scope)
+		}
+		if _, ok := seen[scope]; !ok {
+			seen[scope] = struct{}{}
+			uniqueScopes = append(uniqueScopes, scope)
+		}
+	}
+
+	for _, entry := range allowedIPs {
+		if _, ok := parseIPRule(entry); !ok {
+			return nil, "", errors.NewValidationError("invalid IP restriction: " + entry)
+		}
+	}
+
+	secret, err := GenerateOpaqueToken()
+	if err != nil {
+		return nil, "", errors.NewInternalError("failed to generate api key")
+	}
+	rawKey := ApiKeyPrefix + secret
+
+	key := &data.ApiKey{
+		AdminID:    adminID,
+		Name:       name,
+		Prefix:     rawKey[:len(ApiKeyPrefix)+8],
+		KeyHash:    HashOpaqueToken(rawKey),
+		Scopes:     uniqueScopes,
+		AllowedIPs: allowedIPs,
+		ExpiresAt:  expiresAt,
+	}
+	if key.AllowedIPs == nil {
+		key.AllowedIPs = []string{}
+	}
+	if err := s.apiKeyRepo.Create(key); err != nil {
+		return nil, "", errors.NewInternalError("failed to create api key")
+	}
+
+	log.Info().Int64("admin_id", adminID).Int64("api_key_id", key.ID).Strs("scopes", uniqueScopes).Msg("API key created")
+
+	return key, rawKey, nil
+}
+
+func (s *ApiKeyService) ListApiKeys(adminID int64) ([]*data.ApiKey, error) {
+	keys, err := s.apiKeyRepo.FindByAdminId(adminID)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to get api keys")
+	}
+	return keys, nil
+}
+
+func (s *ApiKeyService) RevokeApiKey(adminID, keyID int64) error {
+	key, err := s.apiKeyRepo.FindById(keyID)
+	if err != nil || key.AdminID != adminID {
+		return errors.NewNotFoundError("api key not found")
+	}
+
+	revoked, err := s.apiKeyRepo.Revoke(keyID)
+	if err != nil {
+		return errors.NewInternalError("failed to revoke api key")
+	}
+	if !revoked {
+		return errors.NewValidationError("api key is already revoked")
+	}
+
+	return nil
+}
+
+// Authenticate resolves a raw key presented by ip. The owning admin must
+// still be active; their role is checked again per request by the
+// permission middleware.
+func (s *ApiKeyService) Authenticate(rawKey, ip string) (*data.ApiKey, error) {
+	if !strings.HasPrefix(rawKey, ApiKeyPrefix) {
+		return nil, errors.NewAuthenticationError("invalid api key")
+	}
+
+	key, err := s.apiKeyRepo.FindByHash(HashOpaqueToken(rawKey))
+	if err != nil {
+		return nil, errors.NewAuthenticationError("invalid api key")
+	}
+
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt)) {
+		return nil, errors.NewAuthenticationError("api key is revoked or expired")
+	}
+
+	if !ipAllowed(key.AllowedIPs, ip) {
+		log.Warn().Int64("api_key_id", key.ID).Str("ip", ip).Msg("API key used from disallowed IP")
+		return nil, errors.NewAuthenticationError("api key is not allowed from this address")
+	}
+
+	admin, err := s.adminRepo.FindById(key.AdminID)
+	if err != nil || !admin.Active() {
+		return nil, errors.NewAuthenticationError("api key owner is not active")
+	}
+
+	if err := s.apiKeyRepo.TouchLastUsed(key.ID, ip); err != nil {
+		log.Error().Err(err).Int64("api_key_id", key.ID).Msg("Failed to record API key usage")
+	}
+
+	return key, nil
+}
+
+// parseIPRule accepts a single address or a CIDR block.
+func parseIPRule(entry string) (*net.IPNet, bool) {
+	if _, network, err := net.ParseCIDR(entry); err == nil {
+		return network, true
+	}
+
+	ip := net.ParseIP(entry)
+	if ip == nil {
+		return nil, false
+	}
+	bits := 128
+	if ip.To4() != nil {
+		ip = ip.To4()
+		bits = 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, true
+}
+
+func ipAllowed(rules []string, ip string) bool {
+	if len(rules) == 0 {
+		return true
+	}
+
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+
+	for _, rule := range rules {
+		if network, ok := parseIPRule(rule); ok && network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
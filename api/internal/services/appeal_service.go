@@ -0,0 +1,234 @@
+package services
+
+import (
+	"database/sql"
+	stdErrors "errors"
+	"strings"
+	"time"
+
+	"github.com/valu/vemeet-admin-api/internal/auth"
+	"github.com/valu/vemeet-admin-api/internal/data"
+	"github.com/valu/vemeet-admin-api/internal/errors"
+)
+
+type AppealService struct {
+	appealRepo  data.AppealRepositoryInterface
+	blockedRepo data.BlockedRepositoryInterface
+	adminRepo   data.AdminRepositoryInterface
+	uow         data.UnitOfWorkInterface
+}
+
+// AppealInput describes a new appeal. Source is data.AppealSourceUser when
+// an internal service relays the user's own appeal, and
+// data.AppealSourceAdmin when a moderator files it for them.
+type AppealInput struct {
+	BlockedID   int64
+	Statement   string
+	Source      string
+	SubmittedBy *int64
+	ApiKeyID    *int64
+}
+
+// AppealDecision reports the outcome of DecideAppeal. Lifted is the ban an
+// overturned appeal closed; Applied is false when it was already closed.
+type AppealDecision struct {
+	Appeal  *data.Appeal
+	Lifted  *data.Blocked
+	Applied bool
+}
+
+type AppealServiceInterface interface {
+	GetAppeal(id int64) (*data.Appeal, error)
+	GetAppeals(page int64, limit int64, sort, order string, filter data.AppealFilter) (*data.AppealPagination, error)
+	SubmitAppeal(input AppealInput) (*data.Appeal, error)
+	AssignReviewer(id int64, reviewerID int64) (*data.Appeal, error)
+	AddComment(id int64, source string, adminID *int64, body string) (*data.AppealComment, error)
+	DecideAppeal(id int64, status string, decidedBy *int64, note string) (*AppealDecision, error)
+}
+
+func NewAppealService(
+	appealRepo data.AppealRepositoryInterface,
+	blockedRepo data.BlockedRepositoryInterface,
+	adminRepo data.AdminRepositoryInterface,
+	uow data.UnitOfWorkInterface,
+) AppealServiceInterface {
+	return &AppealService{appealRepo, blockedRepo, adminRepo, uow}
+}
+
+// GetAppeal returns an appeal with its ban and comment thread.
+func (s *AppealService) GetAppeal(id int64) (*data.Appeal, error) {
+	appeal, err := s.findAppeal(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if blocked, err := s.blockedRepo.FindById(appeal.BlockedID); err == nil {
+		appeal.Blocked = blocked
+	}
+
+	comments, err := s.appealRepo.FindComments(id)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to get appeal comments")
+	}
+	appeal.Comments = comments
+
+	return appeal, nil
+}
+
+func (s *AppealService) GetAppeals(page int64, limit int64, sort, order string, filter data.AppealFilter) (*data.AppealPagination, error) {
+	switch filter.Status {
+	case "", data.AppealStatusOpen, data.AppealStatusUnderReview, data.AppealStatusUpheld, data.AppealStatusOverturned:
+	default:
+		return nil, errors.NewValidationError("status must be open, under_review, upheld or overturned")
+	}
+
+	appeals, err := s.appealRepo.FindAll(page, limit, sort, order, filter)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to get appeals")
+	}
+
+	return appeals, nil
+}
+
+// SubmitAppeal opens an appeal against a ban that is still in force. A ban
+// can only have one appeal in progress.
+func (s *AppealService) SubmitAppeal(input AppealInput) (*data.Appeal, error) {
+	statement := strings.TrimSpace(input.Statement)
+	if statement == "" {
+		return nil, errors.NewValidationError("statement is required")
+	}
+
+	blocked, err := s.blockedRepo.FindById(input.BlockedID)
+	if err != nil {
+		return nil, errors.NewNotFoundError("blocked not found")
+	}
+	if blocked.LiftedAt != nil || (blocked.ExpiresAt != nil && !blocked.ExpiresAt.After(time.Now())) {
+		return nil, errors.NewValidationError("ban is no longer in force")
+	}
+
+	if _, err := s.appealRepo.FindPendingByBlocked(blocked.ID); err == nil {
+		return nil, errors.NewValidationError("ban already has an appeal in progress")
+	} else if !stdErrors.Is(err, sql.ErrNoRows) {
+		return nil, errors.NewInternalError("failed to check existing appeals")
+	}
+
+	appeal := &data.Appeal{
+		BlockedID:       blocked.ID,
+		UserID:          blocked.UserID,
+		Statement:       statement,
+		SubmittedVia:    input.Source,
+		SubmittedBy:     input.SubmittedBy,
+		SubmittedApiKey: input.ApiKeyID,
+	}
+	if err := s.appealRepo.Create(appeal); err != nil {
+		return nil, errors.NewInternalError("failed to submit appeal")
+	}
+
+	return appeal, nil
+}
+
+// AssignReviewer hands an undecided appeal to an admin who may decide it
+// and marks it under review.
+func (s *AppealService) AssignReviewer(id int64, reviewerID int64) (*data.Appeal, error) {
+	reviewer, err := s.adminRepo.FindById(reviewerID)
+	if err != nil {
+		return nil, errors.NewNotFoundError("reviewer not found")
+	}
+	if !reviewer.Active() || !auth.HasPermission(auth.Role(reviewer.Role), auth.PermissionAppealsReview) {
+		return nil, errors.NewValidationError("reviewer must be an active admin allowed to review appeals")
+	}
+
+	if _, err := s.findAppeal(id); err != nil {
+		return nil, err
+	}
+
+	appeal, err := s.appealRepo.Assign(id, reviewerID)
+	if err != nil {
+		if stdErrors.Is(err, sql.ErrNoRows) {
+			return nil, errors.NewValidationError("appeal is already decided")
+		}
+		return nil, errors.NewInternalError("failed to assign reviewer")
+	}
+
+	return appeal, nil
+}
+
+// AddComment appends to an appeal's thread. source says whether the user
+// or an admin wrote it.
+func (s *AppealService) AddComment(id int64, source string, adminID *int64, body string) (*data.AppealComment, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, errors.NewValidationError("body is required")
+	}
+
+	if _, err := s.findAppeal(id); err != nil {
+		return nil, err
+	}
+
+	comment := &data.AppealComment{
+		AppealID:   id,
+		AuthorType: source,
+		AdminID:    adminID,
+		Body:       body,
+	}
+	if err := s.appealRepo.AddComment(comment); err != nil {
+		return nil, errors.NewInternalError("failed to add comment")
+	}
+
+	return comment, nil
+}
+
+// DecideAppeal upholds or overturns an appeal. Overturning lifts the ban in
+// the same transaction, so an appeal is never marked overturned while its
+// ban stays in force.
+func (s *AppealService) DecideAppeal(id int64, status string, decidedBy *int64, note string) (*AppealDecision, error) {
+	if status != data.AppealStatusUpheld && status != data.AppealStatusOverturned {
+		return nil, errors.NewValidationError("decision must be upheld or overturned")
+	}
+
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return nil, errors.NewValidationError("note is required")
+	}
+
+	result := &AppealDecision{}
+	err := s.uow.Do(func(repos *data.TxRepositories) error {
+		appeal, err := repos.Appeals.FindById(id)
+		if err != nil {
+			if stdErrors.Is(err, sql.ErrNoRows) {
+				return errors.NewNotFoundError("appeal not found")
+			}
+			return err
+		}
+		if appeal.Resolved() {
+			return errors.NewValidationError("appeal is already decided")
+		}
+
+		result.Appeal, err = repos.Appeals.Decide(id, status, decidedBy, note)
+		if err != nil {
+			return err
+		}
+
+		if status == data.AppealStatusOverturned {
+			result.Lifted, result.Applied, err = liftBan(repos, appeal.BlockedID, decidedBy, data.BlockedLiftReasonAppeal)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, asServiceError(err, "failed to decide appeal")
+	}
+
+	return result, nil
+}
+
+func (s *AppealService) findAppeal(id int64) (*data.Appeal, error) {
+	appeal, err := s.appealRepo.FindById(id)
+	if err != nil {
+		if stdErrors.Is(err, sql.ErrNoRows) {
+			return nil, errors.NewNotFoundError("appeal not found")
+		}
+		return nil, errors.NewInternalError("failed to get appeal")
+	}
+	return appeal, nil
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"encoding/json"
+
+	"github.com/rs/zerolog/log"
+	"github.com/valu/vemeet-admin-api/internal/data"
+	"github.com/valu/vemeet-admin-api/internal/errors"
+)
+
+const (
+	AuditActionBlockedCreate     = "blocked.create"
+	AuditActionBlockedUpdate     = "blocked.update"
+	AuditActionBlockedExpire     = "blocked.expire"
+	AuditActionBlockedLift       = "blocked.lift"
+	AuditActionBlockedOverride   = "blocked.policy_override"
+	AuditActionUserUnblock       = "user.unblock"
+	AuditActionInviteCreate      = "invite.create"
+	AuditActionInviteRevoke      = "invite.revoke"
+	AuditActionInviteRedeem      = "invite.redeem"
+	AuditActionAdminForceLogout  = "admin.force_logout"
+	AuditActionAdminUnlock       = "admin.unlock"
+	AuditActionAdminUpdate       = "admin.update"
+	AuditActionAdminRoleChange   = "admin.role_change"
+	AuditActionAdminVerify       = "admin.verify"
+	AuditActionAdminUnverify     = "admin.unverify"
+	AuditActionAdminDeactivate   = "admin.deactivate"
+	AuditActionAdminReactivate   = "admin.reactivate"
+	AuditActionBanReasonCreate   = "ban_reason.create"
+	AuditActionBanReasonRetire   = "ban_reason.retire"
+	AuditActionBanReasonLadder   = "ban_reason.ladder_update"
+	AuditActionAppealSubmit      = "appeal.submit"
+	AuditActionAppealAssign      = "appeal.assign"
+	AuditActionAppealComment     = "appeal.comment"
+	AuditActionAppealDecide      = "appeal.decide"
+	AuditActionReportReview      = "report.review"
+	AuditActionReportResolve     = "report.resolve"
+	AuditActionRestrictionCreate = "restriction.create"
+	AuditActionRestrictionLift   = "restriction.lift"
+	AuditActionRestrictionExpire = "restriction.expire"
+	AuditActionApiKeyCreate      = "api_key.create"
+	AuditActionApiKeyRevoke      = "api_key.revoke"
+	AuditActionPasswordChange    = "auth.password_change"
+	AuditActionPasswordReset     = "auth.password_reset"
+	AuditActionTOTPEnable        = "auth.totp_enable"
+	AuditActionSessionRevoke     = "auth.session_revoke"
+)
+
+const (
+	AuditTargetBlocked     = "blocked"
+	AuditTargetBanReason   = "ban_reason"
+	AuditTargetInvite      = "invite"
+	AuditTargetAdmin       = "admin"
+	AuditTargetApiKey      = "api_key"
+	AuditTargetSession     = "session"
+	AuditTargetAppeal      = "appeal"
+	AuditTargetReport      = "report"
+	AuditTargetRestriction = "restriction"
+	AuditTargetUser        = "user"
+)
+
+const auditVerifyBatchSize = 500
+
+// AuditActor identifies who performed an action and from where.
+type AuditActor struct {
+	AdminID   *int64
+	ApiKeyID  *int64
+	RequestID string
+	IPAddress string
+}
+
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Checked  int64  `json:"checked"`
+	BrokenAt *int64 `json:"broken_at,omitempty"`
+}
+
+type AuditService struct {
+	auditRepo data.AuditRepositoryInterface
+}
+
+type AuditServiceInterface interface {
+	Record(actor AuditActor, action, targetType, targetID string, before, after interface{})
+	ListEntries(page int64, limit int64, filter data.AuditFilter) (*data.AuditPagination, error)
+	VerifyChain() (*AuditVerification, error)
+}
+
+func NewAuditService(auditRepo data.AuditRepositoryInterface) AuditServiceInterface {
+	return &AuditService{auditRepo}
+}
+
+// Record appends an entry. before and after are stored as JSON snapshots and
+// may be nil. Failures are logged rather than returned because the audited
+// action has already happened by the time it is recorded.
+func (s *AuditService) Record(actor AuditActor, action, targetType, targetID string, before, after interface{}) {
+	entry := &data.AuditEntry{
+		AdminID:    actor.AdminID,
+		ApiKeyID:   actor.ApiKeyID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Before:     snapshot(before),
+		After:      snapshot(after),
+		RequestID:  actor.RequestID,
+		IPAddress:  actor.IPAddress,
+	}
+
+	if err := s.auditRepo.Append(entry); err != nil {
+		log.Error().Err(err).Str("action", action).Str("target_type", targetType).
+			Str("target_id", targetID).Str("request_id", actor.RequestID).Msg("Failed to write audit log entry")
+	}
+}
+
+func snapshot(value interface{}) json.RawMessage {
+	if value == nil {
+		return nil
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encode audit snapshot")
+		return nil
+	}
+	return encoded
+}
+
+func (s *AuditService) ListEntries(page int64, limit int64, filter data.AuditFilter) (*data.AuditPagination, error) {
+	if page < 1 || limit < 1 {
+		return nil, errors.NewValidationError("invalid pagination")
+	}
+
+	entries, err := s.auditRepo.FindAll(page, limit, filter)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to get audit log")
+	}
+	return entries, nil
+}
+
+// VerifyChain walks the whole log and reports the first entry whose hash or
+// link to its predecessor does not match.
+func (s *AuditService) VerifyChain() (*AuditVerification, error) {
+	result := &AuditVerification{Valid: true}
+	prevHash := data.AuditGenesisHash
+	var lastID int64
+
+	for {
+		entries, err := s.auditRepo.FindAfter(lastID, auditVerifyBatchSize)
+		if err != nil {
+			return nil, errors.NewInternalError("failed to read audit log")
+		}
+
+		for _, entry := range entries {
+			if entry.PrevHash != prevHash || entry.ComputeHash() != entry.Hash {
+				id := entry.ID
+				result.Valid = false
+				result.BrokenAt = &id
+				log.Warn().Int64("audit_id", id).Msg("Audit log chain is broken")
+				return result, nil
+			}
+
+			prevHash = entry.Hash
+			lastID = entry.ID
+			result.Checked++
+		}
+
+		if len(entries) < auditVerifyBatchSize {
+			return result, nil
+		}
+	}
+}
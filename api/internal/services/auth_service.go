@@ -1,65 +1,212 @@
 package services
 
 import (
-	"context"
+	stdErrors "errors"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/rs/zerolog/log"
 	"github.com/valu/vemeet-admin-api/internal/auth"
 	"github.com/valu/vemeet-admin-api/internal/data"
 	"github.com/valu/vemeet-admin-api/internal/errors"
 	"github.com/valu/vemeet-admin-api/internal/models"
 )
 
+const (
+	accessTokenTTL     = 60 * time.Minute
+	refreshTokenTTL    = 7 * 24 * time.Hour
+	mfaPendingTokenTTL = 5 * time.Minute
+)
+
+// LoginResult carries either a full token pair or, when the admin has a
+// second factor enabled, a short-lived MFA token to exchange for one.
+type LoginResult struct {
+	Tokens         *models.TokenPair
+	Admin          *data.Admin
+	MFAToken       string
+	MFATokenExpiry time.Time
+}
+
 type AuthService struct {
 	adminRepo    data.AdminRepositoryInterface
+	sessionRepo  data.SessionRepositoryInterface
+	sessions     SessionServiceInterface
+	mfaService   MfaServiceInterface
+	loginGuard   LoginGuardServiceInterface
 	tokenManager auth.TokenManager
 }
 
 type AuthServiceInterface interface {
-	LoginUser(email, password string) (*models.TokenPair, *data.Admin, error)
+	LoginUser(email, password, ip, userAgent string) (*LoginResult, error)
+	CompleteMFALogin(mfaToken, code, recoveryCode, ip, userAgent string) (*LoginResult, error)
+	IssueSession(admin *data.Admin, ip, userAgent string) (*LoginResult, error)
 	RefreshTokens(refreshToken string) (*models.TokenPair, error)
 	GetSession(userId int64) (*data.Admin, error)
 }
 
-func NewAuthService(adminRepo data.AdminRepositoryInterface, tokenManager auth.TokenManager) AuthServiceInterface {
-	return &AuthService{adminRepo, tokenManager}
+func NewAuthService(
+	adminRepo data.AdminRepositoryInterface,
+	sessionRepo data.SessionRepositoryInterface,
+	sessions SessionServiceInterface,
+	mfaService MfaServiceInterface,
+	loginGuard LoginGuardServiceInterface,
+	tokenManager auth.TokenManager,
+) AuthServiceInterface {
+	return &AuthService{adminRepo, sessionRepo, sessions, mfaService, loginGuard, tokenManager}
 }
 
-func (s *AuthService) LoginUser(email, password string) (*models.TokenPair, *data.Admin, error) {
+var (
+	errInvalidCredentials = errors.NewAuthenticationError("invalid email or password")
+
+	dummyPasswordHashOnce sync.Once
+	dummyPasswordHash     string
+)
+
+// comparePasswordConstantTime runs bcrypt even when the admin does not exist
+// so response timing does not reveal which emails are registered.
+func comparePasswordConstantTime(admin *data.Admin, password string) bool {
+	if admin == nil {
+		dummyPasswordHashOnce.Do(func() {
+			dummyPasswordHash, _ = HashPassword("vemeet-admin-dummy-password")
+		})
+		_ = ComparePassword(dummyPasswordHash, password)
+		return false
+	}
+
+	return ComparePassword(admin.Password, password) == nil
+}
+
+func (s *AuthService) LoginUser(email, password, ip, userAgent string) (*LoginResult, error) {
 	if email == "" || password == "" {
-		return nil, nil, errors.NewValidationError("email and password are required")
+		return nil, errors.NewValidationError("email and password are required")
+	}
+
+	if err := s.loginGuard.Check(email, ip); err != nil {
+		s.loginGuard.RecordFailure(email, nil, ip, userAgent, LoginFailureThrottled)
+		return nil, err
 	}
 
 	admin, err := s.adminRepo.FindByEmail(email)
 	if err != nil {
-		return nil, nil, errors.NewNotFoundError("admin not found")
+		admin = nil
 	}
 
-	if !admin.Verified {
-		return nil, nil, errors.NewValidationError("admin is not verified")
+	passwordOK := comparePasswordConstantTime(admin, password)
+	switch {
+	case admin == nil:
+		s.loginGuard.RecordFailure(email, nil, ip, userAgent, LoginFailureUnknownEmail)
+		return nil, errInvalidCredentials
+	case !passwordOK:
+		s.loginGuard.RecordFailure(email, &admin.ID, ip, userAgent, LoginFailureBadPassword)
+		return nil, errInvalidCredentials
+	case admin.DeactivatedAt != nil:
+		s.loginGuard.RecordFailure(email, &admin.ID, ip, userAgent, LoginFailureDeactivated)
+		return nil, errInvalidCredentials
+	case !admin.Verified:
+		s.loginGuard.RecordFailure(email, &admin.ID, ip, userAgent, LoginFailureUnverified)
+		return nil, errInvalidCredentials
 	}
 
-	err = ComparePassword(admin.Password, password)
+	if admin.TOTPEnabled {
+		expiry := time.Now().Add(mfaPendingTokenTTL)
+		mfaToken, err := s.tokenManager.CreateToken(admin.ID, expiry, auth.TokenTypeMFAPending)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create mfa token: %w", err)
+		}
+
+		return &LoginResult{MFAToken: mfaToken, MFATokenExpiry: expiry}, nil
+	}
+
+	return s.IssueSession(admin, ip, userAgent)
+}
+
+func (s *AuthService) CompleteMFALogin(mfaToken, code, recoveryCode, ip, userAgent string) (*LoginResult, error) {
+	if code == "" && recoveryCode == "" {
+		return nil, errors.NewValidationError("code or recovery code is required")
+	}
+
+	userID, err := s.tokenManager.ValidateToken(mfaToken, auth.TokenTypeMFAPending)
 	if err != nil {
-		return nil, nil, errors.NewValidationError("password is incorrect")
+		return nil, errors.NewAuthenticationError("invalid mfa token")
+	}
+
+	admin, err := s.adminRepo.FindById(userID)
+	if err != nil || !admin.Active() {
+		return nil, errors.NewAuthenticationError("invalid mfa token")
+	}
+
+	if err := s.loginGuard.Check(admin.Email, ip); err != nil {
+		s.loginGuard.RecordFailure(admin.Email, &admin.ID, ip, userAgent, LoginFailureThrottled)
+		return nil, err
+	}
+
+	if err := s.mfaService.VerifySecondFactor(admin.ID, code, recoveryCode); err != nil {
+		s.loginGuard.RecordFailure(admin.Email, &admin.ID, ip, userAgent, LoginFailureBadMFA)
+		return nil, err
 	}
 
-	tokens, err := s.GenerateTokens(context.Background(), admin.ID)
+	return s.IssueSession(admin, ip, userAgent)
+}
+
+// IssueSession starts a session for an admin whose credentials were already
+// checked, either by the password/MFA flow or by an SSO identity provider.
+func (s *AuthService) IssueSession(admin *data.Admin, ip, userAgent string) (*LoginResult, error) {
+	tokens, err := s.StartSession(admin.ID, ip, userAgent)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to generate tokens: %w", err)
+		return nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
+	s.loginGuard.RecordSuccess(admin.Email, admin.ID, ip, userAgent)
 
-	return tokens, admin, nil
+	return &LoginResult{Tokens: tokens, Admin: admin}, nil
 }
 
 func (s *AuthService) RefreshTokens(refreshToken string) (*models.TokenPair, error) {
-	userID, err := s.tokenManager.ValidateToken(refreshToken, "refresh")
+	claims, err := s.tokenManager.ParseToken(refreshToken, auth.TokenTypeRefresh)
+	if err != nil || claims.SessionID == "" || claims.TokenID == "" {
+		return nil, errors.NewAuthenticationError("invalid refresh token")
+	}
+
+	newTokenID, err := auth.NewTokenID()
 	if err != nil {
-		return nil, fmt.Errorf("invalid refresh token: %w", err)
+		return nil, fmt.Errorf("failed to generate token id: %w", err)
 	}
 
-	tokens, err := s.GenerateTokens(context.Background(), userID)
+	refreshTokenExp := time.Now().Add(refreshTokenTTL)
+	session, err := s.sessionRepo.Rotate(claims.TokenID, newTokenID, refreshTokenExp)
+	switch {
+	case stdErrors.Is(err, data.ErrRefreshTokenReused):
+		s.sessions.MarkRevoked(session.ID)
+		log.Warn().
+			Int64("admin_id", session.AdminID).
+			Str("session_id", session.ID).
+			Str("token_id", claims.TokenID).
+			Msg("Refresh token reuse detected, session revoked")
+		return nil, errors.NewAuthenticationError("invalid refresh token")
+	case stdErrors.Is(err, data.ErrSessionRevoked), stdErrors.Is(err, data.ErrRefreshTokenNotFound):
+		return nil, errors.NewAuthenticationError("invalid refresh token")
+	case err != nil:
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	if session.AdminID != claims.UserID {
+		return nil, errors.NewAuthenticationError("invalid refresh token")
+	}
+
+	// A deactivated or unverified admin keeps no session, even if revoking
+	// it when they were disabled failed.
+	admin, err := s.adminRepo.FindById(session.AdminID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load admin: %w", err)
+	}
+	if !admin.Active() {
+		if _, err := s.sessions.RevokeAllSessions(session.AdminID, data.SessionRevokedReasonDeactivated); err != nil {
+			log.Error().Err(err).Int64("admin_id", session.AdminID).Msg("Failed to revoke sessions of disabled admin")
+		}
+		return nil, errors.NewAuthenticationError("invalid refresh token")
+	}
+
+	tokens, err := s.GenerateTokens(session.AdminID, session.ID, newTokenID, refreshTokenExp)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
@@ -67,17 +214,43 @@ func (s *AuthService) RefreshTokens(refreshToken string) (*models.TokenPair, err
 	return tokens, nil
 }
 
-func (s *AuthService) GenerateTokens(ctx context.Context, userID int64) (*models.TokenPair, error) {
-	now := time.Now()
-	accessTokenExp := now.Add(60 * time.Minute)
-	refreshTokenExp := now.Add(7 * 24 * time.Hour)
+// StartSession persists a new session for the admin and issues the first
+// token pair bound to it.
+func (s *AuthService) StartSession(userID int64, ip, userAgent string) (*models.TokenPair, error) {
+	sessionID, err := auth.NewTokenID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate session id: %w", err)
+	}
+
+	tokenID, err := auth.NewTokenID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token id: %w", err)
+	}
+
+	refreshTokenExp := time.Now().Add(refreshTokenTTL)
+	session := &data.Session{
+		ID:        sessionID,
+		AdminID:   userID,
+		IPAddress: ip,
+		UserAgent: userAgent,
+		ExpiresAt: refreshTokenExp,
+	}
+	if err := s.sessionRepo.Create(session, tokenID); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return s.GenerateTokens(userID, sessionID, tokenID, refreshTokenExp)
+}
+
+func (s *AuthService) GenerateTokens(userID int64, sessionID, refreshTokenID string, refreshTokenExp time.Time) (*models.TokenPair, error) {
+	accessTokenExp := time.Now().Add(accessTokenTTL)
 
-	accessToken, err := s.tokenManager.CreateToken(userID, accessTokenExp, "access")
+	accessToken, err := s.tokenManager.CreateSessionToken(userID, sessionID, "", accessTokenExp, auth.TokenTypeAccess)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create access token: %w", err)
 	}
 
-	refreshToken, err := s.tokenManager.CreateToken(userID, refreshTokenExp, "refresh")
+	refreshToken, err := s.tokenManager.CreateSessionToken(userID, sessionID, refreshTokenID, refreshTokenExp, auth.TokenTypeRefresh)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create refresh token: %w", err)
 	}
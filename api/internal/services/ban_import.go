@@ -0,0 +1,271 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	stdErrors "errors"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/valu/vemeet-admin-api/internal/errors"
+)
+
+const (
+	BanImportFormatCSV    = "csv"
+	BanImportFormatNDJSON = "ndjson"
+)
+
+// BanImportMapping names the CSV header columns or NDJSON keys that hold
+// each field. Empty names fall back to user_id, username and notes, and a
+// fallback column may be missing from the file as long as either user_id
+// or username is present. Names are matched case-insensitively.
+type BanImportMapping struct {
+	UserIDColumn   string `json:"user_id_column"`
+	UsernameColumn string `json:"username_column"`
+	NotesColumn    string `json:"notes_column"`
+}
+
+// maxImportLineBytes bounds a single NDJSON line.
+const maxImportLineBytes = 1 << 20
+
+// banImportRow is one row of an uploaded ban list. Rows that could not be
+// read carry err and are rejected without looking anything up.
+type banImportRow struct {
+	line     int
+	userID   string
+	username string
+	notes    string
+	err      string
+}
+
+// banImportFormat resolves the upload format, falling back to the file
+// extension when none is given.
+func banImportFormat(format, filename string) (string, error) {
+	switch strings.ToLower(format) {
+	case BanImportFormatCSV:
+		return BanImportFormatCSV, nil
+	case BanImportFormatNDJSON:
+		return BanImportFormatNDJSON, nil
+	case "":
+	default:
+		return "", errors.NewValidationError("format must be csv or ndjson")
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".ndjson", ".jsonl":
+		return BanImportFormatNDJSON, nil
+	default:
+		return BanImportFormatCSV, nil
+	}
+}
+
+// parseBanImport reads every row of the upload. It fails only when the file
+// as a whole is unusable; problems with a single row are left on that row.
+func parseBanImport(r io.Reader, format string, mapping BanImportMapping, maxRows int) ([]*banImportRow, error) {
+	var rows []*banImportRow
+	var err error
+	if format == BanImportFormatNDJSON {
+		rows, err = parseBanImportNDJSON(r, mapping, maxRows)
+	} else {
+		rows, err = parseBanImportCSV(r, mapping, maxRows)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.NewValidationError("the file has no rows")
+	}
+
+	return rows, nil
+}
+
+type importColumn struct {
+	name     string
+	explicit bool
+}
+
+func importColumns(mapping BanImportMapping) (userID, username, notes importColumn) {
+	column := func(name, fallback string) importColumn {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			return importColumn{name: fallback}
+		}
+		return importColumn{name: name, explicit: true}
+	}
+
+	return column(mapping.UserIDColumn, "user_id"), column(mapping.UsernameColumn, "username"),
+		column(mapping.NotesColumn, "notes")
+}
+
+func tooManyImportRows(maxRows int) error {
+	return errors.NewValidationError("the file has more than " + strconv.Itoa(maxRows) + " rows")
+}
+
+func parseBanImportCSV(r io.Reader, mapping BanImportMapping, maxRows int) ([]*banImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if stdErrors.Is(err, io.EOF) {
+			return nil, errors.NewValidationError("the file has no rows")
+		}
+		return nil, errors.NewValidationError("the file has no readable CSV header")
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	userIDColumn, usernameColumn, notesColumn := importColumns(mapping)
+	position := func(column importColumn) (int, error) {
+		if i, ok := index[column.name]; ok {
+			return i, nil
+		}
+		if column.explicit {
+			return -1, errors.NewValidationError("the CSV header has no " + column.name + " column")
+		}
+		return -1, nil
+	}
+
+	userIDIndex, err := position(userIDColumn)
+	if err != nil {
+		return nil, err
+	}
+	usernameIndex, err := position(usernameColumn)
+	if err != nil {
+		return nil, err
+	}
+	notesIndex, err := position(notesColumn)
+	if err != nil {
+		return nil, err
+	}
+	if userIDIndex < 0 && usernameIndex < 0 {
+		return nil, errors.NewValidationError("the CSV header needs a " + userIDColumn.name + " or " +
+			usernameColumn.name + " column")
+	}
+
+	field := func(record []string, i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	rows := make([]*banImportRow, 0)
+	for {
+		record, err := reader.Read()
+		if stdErrors.Is(err, io.EOF) {
+			break
+		}
+		if maxRows > 0 && len(rows) == maxRows {
+			return nil, tooManyImportRows(maxRows)
+		}
+
+		var parseErr *csv.ParseError
+		if stdErrors.As(err, &parseErr) {
+			rows = append(rows, &banImportRow{line: parseErr.StartLine, err: "malformed CSV row"})
+			continue
+		}
+		if err != nil {
+			return nil, errors.NewValidationError("the file could not be read")
+		}
+
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, &banImportRow{
+			line:     line,
+			userID:   field(record, userIDIndex),
+			username: field(record, usernameIndex),
+			notes:    field(record, notesIndex),
+		})
+	}
+
+	return rows, nil
+}
+
+func parseBanImportNDJSON(r io.Reader, mapping BanImportMapping, maxRows int) ([]*banImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineBytes)
+
+	userIDColumn, usernameColumn, notesColumn := importColumns(mapping)
+
+	rows := make([]*banImportRow, 0)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if line == 1 {
+			text = bytes.TrimPrefix(text, []byte("\ufeff"))
+		}
+		if len(text) == 0 {
+			continue
+		}
+		if maxRows > 0 && len(rows) == maxRows {
+			return nil, tooManyImportRows(maxRows)
+		}
+
+		row := &banImportRow{line: line}
+		rows = append(rows, row)
+
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.UseNumber()
+		var object map[string]interface{}
+		if err := decoder.Decode(&object); err != nil || object == nil {
+			row.err = "line is not a JSON object"
+			continue
+		}
+
+		fields := make(map[string]interface{}, len(object))
+		for key, value := range object {
+			fields[strings.ToLower(strings.TrimSpace(key))] = value
+		}
+
+		var ok bool
+		if row.userID, ok = importValue(fields[userIDColumn.name]); !ok {
+			row.err = userIDColumn.name + " must be a string or number"
+			continue
+		}
+		if row.username, ok = importValue(fields[usernameColumn.name]); !ok {
+			row.err = usernameColumn.name + " must be a string or number"
+			continue
+		}
+		if row.notes, ok = importValue(fields[notesColumn.name]); !ok {
+			row.err = notesColumn.name + " must be a string or number"
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		if stdErrors.Is(err, bufio.ErrTooLong) {
+			return nil, errors.NewValidationError("line " + strconv.Itoa(line+1) + " is longer than " +
+				strconv.Itoa(maxImportLineBytes) + " bytes")
+		}
+		return nil, errors.NewValidationError("the file could not be read")
+	}
+
+	return rows, nil
+}
+
+// importValue reads an NDJSON field as text. Missing and null fields are
+// empty.
+func importValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return "", true
+	case string:
+		return strings.TrimSpace(v), true
+	case json.Number:
+		return v.String(), true
+	default:
+		return "", false
+	}
+}
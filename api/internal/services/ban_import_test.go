@@ -0,0 +1,287 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/valu/vemeet-admin-api/internal/data"
+)
+
+// importRow is the comparable part of a banImportRow.
+type importRow struct {
+	line     int
+	userID   string
+	username string
+	notes    string
+	err      string
+}
+
+func importRows(rows []*banImportRow) []importRow {
+	out := make([]importRow, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, importRow{row.line, row.userID, row.username, row.notes, row.err})
+	}
+	return out
+}
+
+func TestParseBanImportCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		mapping BanImportMapping
+		maxRows int
+		want    []importRow
+		wantErr string
+	}{
+		{
+			name:  "default columns in any order and case",
+			input: "Notes,USERNAME,user_id\nspam,alice,12\n,bob,\n",
+			want: []importRow{
+				{line: 2, userID: "12", username: "alice", notes: "spam"},
+				{line: 3, username: "bob"},
+			},
+		},
+		{
+			name:    "mapped columns",
+			input:   "id,handle,comment\n7, carol ,scam\n",
+			mapping: BanImportMapping{UserIDColumn: "ID", UsernameColumn: "handle", NotesColumn: "comment"},
+			want:    []importRow{{line: 2, userID: "7", username: "carol", notes: "scam"}},
+		},
+		{
+			name:  "byte order mark before the header",
+			input: "\ufeffuser_id\n5\n",
+			want:  []importRow{{line: 2, userID: "5"}},
+		},
+		{
+			name:  "missing fallback columns are empty",
+			input: "username\ndave\n",
+			want:  []importRow{{line: 2, username: "dave"}},
+		},
+		{
+			name:  "short rows leave the missing fields empty",
+			input: "user_id,username,notes\n9\n",
+			want:  []importRow{{line: 2, userID: "9"}},
+		},
+		{
+			name:  "malformed row is kept with an error",
+			input: "user_id,notes\n1,\"unterminated\n",
+			want:  []importRow{{line: 2, err: "malformed CSV row"}},
+		},
+		{
+			name:  "listing a user twice keeps both rows",
+			input: "user_id\n4\n4\n",
+			want:  []importRow{{line: 2, userID: "4"}, {line: 3, userID: "4"}},
+		},
+		{
+			name:    "explicit column missing from the header",
+			input:   "user_id\n1\n",
+			mapping: BanImportMapping{NotesColumn: "comment"},
+			wantErr: "the CSV header has no comment column",
+		},
+		{
+			name:    "neither user_id nor username",
+			input:   "notes\nspam\n",
+			wantErr: "the CSV header needs a user_id or username column",
+		},
+		{
+			name:    "empty file",
+			input:   "",
+			wantErr: "the file has no rows",
+		},
+		{
+			name:    "too many rows",
+			input:   "user_id\n1\n2\n3\n",
+			maxRows: 2,
+			wantErr: "the file has more than 2 rows",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := parseBanImportCSV(strings.NewReader(tt.input), tt.mapping, tt.maxRows)
+			checkImportParse(t, rows, err, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func TestParseBanImportNDJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		mapping BanImportMapping
+		maxRows int
+		want    []importRow
+		wantErr string
+	}{
+		{
+			name: "default keys in any case, numbers and strings",
+			input: `{"User_ID": 12, "notes": "spam"}` + "\n" +
+				`{"username": " alice ", "user_id": null}` + "\n",
+			want: []importRow{
+				{line: 1, userID: "12", notes: "spam"},
+				{line: 2, username: "alice"},
+			},
+		},
+		{
+			name:    "mapped keys",
+			input:   `{"id": "7", "handle": "carol", "comment": "scam", "extra": true}`,
+			mapping: BanImportMapping{UserIDColumn: "id", UsernameColumn: "Handle", NotesColumn: "comment"},
+			want:    []importRow{{line: 1, userID: "7", username: "carol", notes: "scam"}},
+		},
+		{
+			name:  "blank lines are skipped but counted",
+			input: "\ufeff\n" + `{"user_id": 3}` + "\n\n" + `{"user_id": 4}` + "\n",
+			want:  []importRow{{line: 2, userID: "3"}, {line: 4, userID: "4"}},
+		},
+		{
+			name:  "line that is not an object",
+			input: `[1, 2]` + "\n" + `not json` + "\n" + `null`,
+			want: []importRow{
+				{line: 1, err: "line is not a JSON object"},
+				{line: 2, err: "line is not a JSON object"},
+				{line: 3, err: "line is not a JSON object"},
+			},
+		},
+		{
+			name:    "field of the wrong type",
+			input:   `{"user_id": {"id": 1}}` + "\n" + `{"user_id": 2, "comment": [1]}`,
+			mapping: BanImportMapping{NotesColumn: "comment"},
+			want: []importRow{
+				{line: 1, err: "user_id must be a string or number"},
+				{line: 2, userID: "2", err: "comment must be a string or number"},
+			},
+		},
+		{
+			name:  "listing a user twice keeps both rows",
+			input: `{"user_id": 4}` + "\n" + `{"user_id": "4"}`,
+			want:  []importRow{{line: 1, userID: "4"}, {line: 2, userID: "4"}},
+		},
+		{
+			name:    "too many rows",
+			input:   `{"user_id": 1}` + "\n" + `{"user_id": 2}`,
+			maxRows: 1,
+			wantErr: "the file has more than 1 rows",
+		},
+		{
+			name:    "line too long",
+			input:   `{"notes": "` + strings.Repeat("x", maxImportLineBytes) + `"}`,
+			wantErr: "line 1 is longer than",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := parseBanImportNDJSON(strings.NewReader(tt.input), tt.mapping, tt.maxRows)
+			checkImportParse(t, rows, err, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func checkImportParse(t *testing.T, rows []*banImportRow, err error, want []importRow, wantErr string) {
+	t.Helper()
+
+	if wantErr != "" {
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Fatalf("error = %v, want %q", err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := importRows(rows); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %+v\nwant   %+v", got, want)
+	}
+}
+
+type importUserRepo struct {
+	data.UserRepositoryInterface
+	ids       map[int64]bool
+	usernames map[string]int64
+}
+
+func (r *importUserRepo) FindExistingIDs(ids []int64) (map[int64]bool, error) {
+	found := make(map[int64]bool)
+	for _, id := range ids {
+		if r.ids[id] {
+			found[id] = true
+		}
+	}
+	return found, nil
+}
+
+func (r *importUserRepo) FindIDsByUsername(usernames []string) (map[string]int64, error) {
+	found := make(map[string]int64)
+	for _, username := range usernames {
+		if id, ok := r.usernames[username]; ok {
+			found[username] = id
+		}
+	}
+	return found, nil
+}
+
+type importBlockedRepo struct {
+	data.BlockedRepositoryInterface
+	banned map[int64]bool
+}
+
+func (r *importBlockedRepo) FindBannedUserIDs(userIDs []int64) (map[int64]bool, error) {
+	return r.banned, nil
+}
+
+func TestCheckImportRows(t *testing.T) {
+	service := &BulkModerationService{
+		userRepo: &importUserRepo{
+			ids:       map[int64]bool{1: true, 2: true, 3: true},
+			usernames: map[string]int64{"alice": 1, "carol": 3},
+		},
+		blockedRepo: &importBlockedRepo{banned: map[int64]bool{3: true}},
+	}
+
+	rows, err := parseBanImportCSV(strings.NewReader("user_id,username\n"+
+		"1,\n"+
+		",alice\n"+
+		"2,\n"+
+		"2,\n"+
+		",carol\n"+
+		"99,\n"+
+		",nobody\n"+
+		"abc,\n"+
+		",\n"), BanImportMapping{}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	items, err := service.checkImportRows(rows)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []struct {
+		status string
+		err    string
+	}{
+		{data.ModerationItemReady, ""},
+		{data.ModerationItemRejected, "same user as line 2"},
+		{data.ModerationItemReady, ""},
+		{data.ModerationItemRejected, "same user as line 4"},
+		{data.ModerationItemAlreadyBlocked, ""},
+		{data.ModerationItemRejected, "user not found"},
+		{data.ModerationItemRejected, "user not found"},
+		{data.ModerationItemRejected, "invalid user_id: abc"},
+		{data.ModerationItemRejected, "row has neither a user_id nor a username"},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("got %d items, want %d", len(items), len(want))
+	}
+	for i, item := range items {
+		var itemErr string
+		if item.Error != nil {
+			itemErr = *item.Error
+		}
+		if item.Status != want[i].status || itemErr != want[i].err {
+			t.Errorf("line %d: got %s %q, want %s %q", item.Position, item.Status, itemErr, want[i].status, want[i].err)
+		}
+	}
+}
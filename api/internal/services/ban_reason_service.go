@@ -0,0 +1,179 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valu/vemeet-admin-api/internal/data"
+	"github.com/valu/vemeet-admin-api/internal/errors"
+)
+
+var banReasonCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,63}$`)
+
+type BanReasonService struct {
+	banReasonRepo data.BanReasonRepositoryInterface
+	ladderRepo    data.EscalationRepositoryInterface
+}
+
+// LadderStepInput is one rung of an escalation ladder as submitted by an
+// admin. Duration is a Go duration and only applies to suspensions.
+type LadderStepInput struct {
+	Action   string
+	Duration string
+}
+
+type BanReasonServiceInterface interface {
+	ListReasons(includeRetired bool) ([]*data.BanReason, error)
+	CreateReason(code, label, description, severity, defaultDuration string, createdBy *int64) (*data.BanReason, error)
+	RetireReason(code string) error
+	CountBansByReason(status string) ([]*data.BanReasonCount, error)
+	GetLadder(code string) ([]*data.EscalationStep, error)
+	SetLadder(code string, steps []LadderStepInput) ([]*data.EscalationStep, error)
+}
+
+func NewBanReasonService(
+	banReasonRepo data.BanReasonRepositoryInterface,
+	ladderRepo data.EscalationRepositoryInterface,
+) BanReasonServiceInterface {
+	return &BanReasonService{banReasonRepo, ladderRepo}
+}
+
+func (s *BanReasonService) ListReasons(includeRetired bool) ([]*data.BanReason, error) {
+	reasons, err := s.banReasonRepo.FindAll(includeRetired)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to get ban reasons")
+	}
+	return reasons, nil
+}
+
+// CreateReason adds a code to the catalogue. defaultDuration is a Go
+// duration such as "24h" or "168h"; empty means permanent.
+func (s *BanReasonService) CreateReason(code, label, description, severity, defaultDuration string, createdBy *int64) (*data.BanReason, error) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if !banReasonCodePattern.MatchString(code) {
+		return nil, errors.NewValidationError("code must be lowercase letters, digits and underscores")
+	}
+
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return nil, errors.NewValidationError("label is required")
+	}
+
+	switch severity {
+	case data.BanSeverityLow, data.BanSeverityMedium, data.BanSeverityHigh, data.BanSeverityCritical:
+	default:
+		return nil, errors.NewValidationError("severity must be low, medium, high or critical")
+	}
+
+	reason := &data.BanReason{
+		Code:        code,
+		Label:       label,
+		Description: strings.TrimSpace(description),
+		Severity:    severity,
+		CreatedBy:   createdBy,
+	}
+
+	if defaultDuration != "" {
+		duration, err := time.ParseDuration(defaultDuration)
+		if err != nil || duration < time.Minute {
+			return nil, errors.NewValidationError("default_duration must be a duration of at least 1m, or empty for permanent")
+		}
+		seconds := int64(duration / time.Second)
+		reason.DefaultDurationSeconds = &seconds
+	}
+
+	if _, err := s.banReasonRepo.FindByCode(code); err == nil {
+		return nil, errors.NewValidationError("code already exists")
+	}
+
+	if err := s.banReasonRepo.Create(reason); err != nil {
+		return nil, errors.NewInternalError("failed to create ban reason")
+	}
+
+	return reason, nil
+}
+
+func (s *BanReasonService) RetireReason(code string) error {
+	if _, err := s.banReasonRepo.FindByCode(code); err != nil {
+		return errors.NewNotFoundError("ban reason not found")
+	}
+
+	retired, err := s.banReasonRepo.Retire(code)
+	if err != nil {
+		return errors.NewInternalError("failed to retire ban reason")
+	}
+	if !retired {
+		return errors.NewValidationError("ban reason is already retired")
+	}
+
+	return nil
+}
+
+func (s *BanReasonService) CountBansByReason(status string) ([]*data.BanReasonCount, error) {
+	switch status {
+	case "", data.BlockedStatusActive, data.BlockedStatusExpired, data.BlockedStatusPermanent:
+	default:
+		return nil, errors.NewValidationError("status must be active, expired or permanent")
+	}
+
+	counts, err := s.banReasonRepo.CountBans(status)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to count bans")
+	}
+	return counts, nil
+}
+
+func (s *BanReasonService) GetLadder(code string) ([]*data.EscalationStep, error) {
+	if _, err := s.banReasonRepo.FindByCode(code); err != nil {
+		return nil, errors.NewNotFoundError("ban reason not found")
+	}
+
+	steps, err := s.ladderRepo.FindByReason(code)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to get escalation ladder")
+	}
+	return steps, nil
+}
+
+// SetLadder replaces the escalation ladder for a code. An empty ladder
+// makes new bans fall back to the code's default duration. Permanent can
+// only be the last step, since nothing can follow it.
+func (s *BanReasonService) SetLadder(code string, inputs []LadderStepInput) ([]*data.EscalationStep, error) {
+	if _, err := s.banReasonRepo.FindByCode(code); err != nil {
+		return nil, errors.NewNotFoundError("ban reason not found")
+	}
+
+	steps := make([]*data.EscalationStep, 0, len(inputs))
+	for i, input := range inputs {
+		step := &data.EscalationStep{ReasonCode: code, Step: i + 1, Action: input.Action}
+		position := "step " + strconv.Itoa(i+1)
+
+		switch input.Action {
+		case data.EnforcementWarning, data.EnforcementPermanent:
+			if input.Duration != "" {
+				return nil, errors.NewValidationError(position + ": duration only applies to suspensions")
+			}
+			if input.Action == data.EnforcementPermanent && i != len(inputs)-1 {
+				return nil, errors.NewValidationError(position + ": permanent must be the last step")
+			}
+		case data.EnforcementSuspension:
+			duration, err := time.ParseDuration(input.Duration)
+			if err != nil || duration < time.Minute {
+				return nil, errors.NewValidationError(position + ": suspension needs a duration of at least 1m")
+			}
+			seconds := int64(duration / time.Second)
+			step.DurationSeconds = &seconds
+		default:
+			return nil, errors.NewValidationError(position + ": action must be warning, suspension or permanent")
+		}
+
+		steps = append(steps, step)
+	}
+
+	if err := s.ladderRepo.ReplaceForReason(code, steps); err != nil {
+		return nil, errors.NewInternalError("failed to update escalation ladder")
+	}
+	return steps, nil
+}
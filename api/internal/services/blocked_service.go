@@ -1,21 +1,90 @@
 package services
 
-import "github.com/valu/vemeet-admin-api/internal/data"
+import (
+	"database/sql"
+	stdErrors "errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valu/vemeet-admin-api/internal/data"
+	"github.com/valu/vemeet-admin-api/internal/errors"
+)
 
 type BlockedService struct {
-	blockedRepo data.BlockedRepositoryInterface
+	blockedRepo   data.BlockedRepositoryInterface
+	banReasonRepo data.BanReasonRepositoryInterface
+	ladderRepo    data.EscalationRepositoryInterface
+	uow           data.UnitOfWorkInterface
+}
+
+// overrideTolerance absorbs clock skew between a client that echoes a
+// proposed expiry back and the server computing it again.
+const overrideTolerance = time.Minute
+
+// BlockInput describes a new ban. When Enforcement, ExpiresAt and Permanent
+// are all unset, the reason code's escalation policy decides. Any explicit
+// choice that differs from the policy needs Override and an OverrideReason.
+type BlockInput struct {
+	UserID         int64
+	ReasonCode     string
+	Notes          string
+	Enforcement    string
+	ExpiresAt      *time.Time
+	Permanent      bool
+	Override       bool
+	OverrideReason string
+	BlockedBy      *int64
+}
+
+// BlockProposal is what the escalation policy recommends for a user's next
+// ban under a reason code. Step is nil when the code has no ladder and its
+// default duration is used instead.
+type BlockProposal struct {
+	ReasonCode      string     `json:"reason_code"`
+	PriorBans       int64      `json:"prior_bans"`
+	Step            *int       `json:"step"`
+	Enforcement     string     `json:"enforcement"`
+	DurationSeconds *int64     `json:"duration_seconds"`
+	ExpiresAt       *time.Time `json:"expires_at"`
+}
+
+// BlockResult reports whether BlockUser created a ban. Applied is false when
+// the user already had one in force, in which case Blocked is that ban.
+// Proposal is nil in that case.
+type BlockResult struct {
+	Blocked  *data.Blocked
+	Proposal *BlockProposal
+	Applied  bool
+}
+
+// UnblockResult lists the bans closed by UnblockUser. Applied is false when
+// the user had nothing to lift.
+type UnblockResult struct {
+	Lifted  []*data.Blocked
+	Applied bool
 }
 
 type BlockedServiceInterface interface {
 	GetBlockedById(id int64) (*data.Blocked, error)
-	GetBlockeds(page int64, limit int64, sort, order, search string) (*data.BlockedPagination, error)
-	CreateBlocked(blocked *data.Blocked) (*data.Blocked, error)
+	GetBlockeds(page int64, limit int64, sort, order, search, status, reasonCode string) (*data.BlockedPagination, error)
+	ProposeBlock(userID int64, reasonCode string) (*BlockProposal, error)
+	BlockUser(input BlockInput) (*BlockResult, error)
+	BlockUserWith(input BlockInput, also func(repos *data.TxRepositories, result *BlockResult) error) (*BlockResult, error)
+	BlockUsers(inputs []BlockInput) ([]*BlockResult, error)
+	UnblockUser(userID int64, liftedBy *int64, reason string) (*UnblockResult, error)
 	UpdateBlocked(blocked *data.Blocked) (*data.Blocked, error)
-	DeleteBlocked(id int64) (bool, error)
+	LiftBlocked(id int64, liftedBy *int64, reason string) (*UnblockResult, error)
+	GetUserBans(userID int64) ([]*data.Blocked, error)
 }
 
-func NewBlockedService(blockedRepo data.BlockedRepositoryInterface) BlockedServiceInterface {
-	return &BlockedService{blockedRepo}
+func NewBlockedService(
+	blockedRepo data.BlockedRepositoryInterface,
+	banReasonRepo data.BanReasonRepositoryInterface,
+	ladderRepo data.EscalationRepositoryInterface,
+	uow data.UnitOfWorkInterface,
+) BlockedServiceInterface {
+	return &BlockedService{blockedRepo, banReasonRepo, ladderRepo, uow}
 }
 
 func (s *BlockedService) GetBlockedById(id int64) (*data.Blocked, error) {
@@ -27,8 +96,14 @@ func (s *BlockedService) GetBlockedById(id int64) (*data.Blocked, error) {
 	return blocked, nil
 }
 
-func (s *BlockedService) GetBlockeds(page int64, limit int64, sort, order, search string) (*data.BlockedPagination, error) {
-	blockeds, err := s.blockedRepo.FindAll(page, limit, sort, order, search)
+func (s *BlockedService) GetBlockeds(page int64, limit int64, sort, order, search, status, reasonCode string) (*data.BlockedPagination, error) {
+	switch status {
+	case "", data.BlockedStatusActive, data.BlockedStatusExpired, data.BlockedStatusPermanent:
+	default:
+		return nil, errors.NewValidationError("status must be active, expired or permanent")
+	}
+
+	blockeds, err := s.blockedRepo.FindAll(page, limit, sort, order, search, status, reasonCode)
 	if err != nil {
 		return nil, err
 	}
@@ -36,16 +111,336 @@ func (s *BlockedService) GetBlockeds(page int64, limit int64, sort, order, searc
 	return blockeds, nil
 }
 
-func (s *BlockedService) CreateBlocked(blocked *data.Blocked) (*data.Blocked, error) {
-	blocked, err := s.blockedRepo.Create(blocked)
+// ProposeBlock previews the step the escalation policy would apply if the
+// user were banned under reasonCode now.
+func (s *BlockedService) ProposeBlock(userID int64, reasonCode string) (*BlockProposal, error) {
+	reason, err := s.activeReason(reasonCode)
 	if err != nil {
 		return nil, err
 	}
 
-	return blocked, nil
+	ladder, err := s.ladderRepo.FindByReason(reason.Code)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to load escalation ladder")
+	}
+
+	prior, err := s.blockedRepo.CountByUserAndReason(userID, reason.Code)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to count prior bans")
+	}
+
+	return proposeBlock(reason, ladder, prior, time.Now()), nil
+}
+
+// proposeBlock picks the ladder step after the user's prior bans for the
+// code, staying on the last step once it is reached. Without a ladder the
+// code's default duration applies.
+func proposeBlock(reason *data.BanReason, ladder []*data.EscalationStep, prior int64, now time.Time) *BlockProposal {
+	proposal := &BlockProposal{ReasonCode: reason.Code, PriorBans: prior}
+
+	if len(ladder) == 0 {
+		proposal.DurationSeconds = reason.DefaultDurationSeconds
+		proposal.ExpiresAt = reason.DefaultExpiry(now)
+		proposal.Enforcement = data.EnforcementSuspension
+		if proposal.ExpiresAt == nil {
+			proposal.Enforcement = data.EnforcementPermanent
+		}
+		return proposal
+	}
+
+	index := int(prior)
+	if index >= len(ladder) {
+		index = len(ladder) - 1
+	}
+	step := ladder[index]
+
+	proposal.Step = &step.Step
+	proposal.Enforcement = step.Action
+	proposal.DurationSeconds = step.DurationSeconds
+	if step.DurationSeconds != nil {
+		expiresAt := now.Add(time.Duration(*step.DurationSeconds) * time.Second)
+		proposal.ExpiresAt = &expiresAt
+	}
+
+	return proposal
+}
+
+// chosenEnforcement turns the moderator's explicit choice into an
+// enforcement and expiry. ok is false when the input leaves it to policy.
+func chosenEnforcement(input BlockInput, proposal *BlockProposal, now time.Time) (enforcement string, expiresAt *time.Time, ok bool, err error) {
+	switch {
+	case input.Permanent:
+		if input.Enforcement != "" && input.Enforcement != data.EnforcementPermanent {
+			return "", nil, false, errors.NewValidationError("permanent conflicts with enforcement " + input.Enforcement)
+		}
+		return data.EnforcementPermanent, nil, true, nil
+	case input.Enforcement == "" && input.ExpiresAt == nil:
+		return "", nil, false, nil
+	}
+
+	switch input.Enforcement {
+	case data.EnforcementWarning, data.EnforcementPermanent:
+		if input.ExpiresAt != nil {
+			return "", nil, false, errors.NewValidationError("expires_at only applies to suspensions")
+		}
+		return input.Enforcement, nil, true, nil
+	case "", data.EnforcementSuspension:
+		expiresAt = input.ExpiresAt
+		if expiresAt == nil && proposal.DurationSeconds != nil {
+			expiresAt = proposal.ExpiresAt
+		}
+		if expiresAt == nil {
+			return "", nil, false, errors.NewValidationError("expires_at is required for a suspension")
+		}
+		if !expiresAt.After(now) {
+			return "", nil, false, errors.NewValidationError("expires_at must be in the future")
+		}
+		return data.EnforcementSuspension, expiresAt, true, nil
+	default:
+		return "", nil, false, errors.NewValidationError("enforcement must be warning, suspension or permanent")
+	}
+}
+
+// matchesProposal reports whether an explicit choice is what the policy
+// would have applied anyway.
+func matchesProposal(proposal *BlockProposal, enforcement string, expiresAt *time.Time) bool {
+	if enforcement != proposal.Enforcement {
+		return false
+	}
+	if enforcement != data.EnforcementSuspension {
+		return true
+	}
+
+	diff := expiresAt.Sub(*proposal.ExpiresAt)
+	return diff <= overrideTolerance && diff >= -overrideTolerance
+}
+
+// BlockUser bans a user. The ban record and users.blocked are written in one
+// transaction, and blocking an already banned user returns the existing ban
+// instead of creating a second one, so retries are safe.
+//
+// The enforcement comes from the reason code's escalation ladder, based on
+// the user's earlier bans for that code. A moderator may choose otherwise
+// only with input.Override and a reason; the ban records the step the
+// policy proposed and whether it was overridden. Warnings are stored as
+// bans closed on creation and never set users.blocked.
+func (s *BlockedService) BlockUser(input BlockInput) (*BlockResult, error) {
+	return s.BlockUserWith(input, nil)
+}
+
+// BlockUserWith is BlockUser with extra work run in the same transaction
+// once the ban is in place, for callers that must record something
+// alongside it. An error from also rolls the ban back.
+func (s *BlockedService) BlockUserWith(input BlockInput, also func(repos *data.TxRepositories, result *BlockResult) error) (*BlockResult, error) {
+	plan, err := s.planBlock(input, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BlockResult{}
+	err = s.uow.Do(func(repos *data.TxRepositories) error {
+		if err := plan.apply(repos, result); err != nil {
+			return err
+		}
+		if also != nil {
+			return also(repos, result)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, asServiceError(err, "failed to block user")
+	}
+
+	if blocked, err := s.blockedRepo.FindById(result.Blocked.ID); err == nil {
+		result.Blocked = blocked
+	}
+
+	return result, nil
+}
+
+// BlockUsers bans several users in one transaction, following the same
+// rules as BlockUser for each. Any failure rolls back the whole batch;
+// callers that need per-user outcomes retry the users one at a time.
+func (s *BlockedService) BlockUsers(inputs []BlockInput) ([]*BlockResult, error) {
+	ladders := make(map[string][]*data.EscalationStep)
+	plans := make([]*blockPlan, len(inputs))
+	for i, input := range inputs {
+		plan, err := s.planBlock(input, ladders)
+		if err != nil {
+			return nil, err
+		}
+		plans[i] = plan
+	}
+
+	results := make([]*BlockResult, len(inputs))
+	err := s.uow.Do(func(repos *data.TxRepositories) error {
+		for i, plan := range plans {
+			results[i] = &BlockResult{}
+			if err := plan.apply(repos, results[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, asServiceError(err, "failed to block users")
+	}
+
+	return results, nil
+}
+
+// blockPlan is a validated ban request, ready to apply inside a unit of
+// work.
+type blockPlan struct {
+	input          BlockInput
+	reason         *data.BanReason
+	ladder         []*data.EscalationStep
+	overrideReason string
+}
+
+// planBlock validates input and loads what applying it needs. ladders, when
+// not nil, caches escalation ladders across calls for the same code.
+func (s *BlockedService) planBlock(input BlockInput, ladders map[string][]*data.EscalationStep) (*blockPlan, error) {
+	reason, err := s.activeReason(input.ReasonCode)
+	if err != nil {
+		return nil, err
+	}
+
+	ladder, cached := ladders[reason.Code]
+	if !cached {
+		ladder, err = s.ladderRepo.FindByReason(reason.Code)
+		if err != nil {
+			return nil, errors.NewInternalError("failed to load escalation ladder")
+		}
+		if ladders != nil {
+			ladders[reason.Code] = ladder
+		}
+	}
+
+	overrideReason := strings.TrimSpace(input.OverrideReason)
+	if input.Override && overrideReason == "" {
+		return nil, errors.NewValidationError("override_reason is required when overriding the escalation policy")
+	}
+
+	return &blockPlan{input, reason, ladder, overrideReason}, nil
+}
+
+func (p *blockPlan) apply(repos *data.TxRepositories, result *BlockResult) error {
+	input, reason := p.input, p.reason
+	userID := input.UserID
+
+	if _, err := repos.Users.LockBlocked(userID); err != nil {
+		if stdErrors.Is(err, sql.ErrNoRows) {
+			return errors.NewNotFoundError("user not found")
+		}
+		return err
+	}
+
+	active, err := repos.Blocked.FindActiveByUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if len(active) > 0 {
+		result.Blocked = active[0]
+		return repos.Users.SetBlocked(userID, true)
+	}
+
+	prior, err := repos.Blocked.CountByUserAndReason(userID, reason.Code)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	proposal := proposeBlock(reason, p.ladder, prior, now)
+
+	blocked := &data.Blocked{
+		UserID:      userID,
+		ReasonCode:  &reason.Code,
+		Reason:      strings.TrimSpace(input.Notes),
+		BlockedBy:   input.BlockedBy,
+		Enforcement: proposal.Enforcement,
+		PolicyStep:  proposal.Step,
+		ExpiresAt:   proposal.ExpiresAt,
+	}
+
+	enforcement, expiresAt, chosen, err := chosenEnforcement(input, proposal, now)
+	if err != nil {
+		return err
+	}
+	if chosen && !matchesProposal(proposal, enforcement, expiresAt) {
+		if !input.Override {
+			return errors.NewValidationError("requested enforcement differs from the escalation policy, which proposes " +
+				describeProposal(proposal) + "; set override with an override_reason to apply it")
+		}
+		blocked.Enforcement = enforcement
+		blocked.ExpiresAt = expiresAt
+		blocked.PolicyOverride = true
+		blocked.OverrideReason = &p.overrideReason
+	}
+
+	if err := repos.Blocked.Insert(blocked); err != nil {
+		return err
+	}
+	result.Blocked = blocked
+	result.Proposal = proposal
+	result.Applied = true
+
+	if blocked.Enforcement == data.EnforcementWarning {
+		lifted, err := repos.Blocked.Lift(blocked.ID, input.BlockedBy, data.BlockedLiftReasonWarning)
+		if err != nil {
+			return err
+		}
+		result.Blocked = lifted
+		return nil
+	}
+
+	return repos.Users.SetBlocked(userID, true)
+}
+
+// UnblockUser closes every ban in force for the user and clears
+// users.blocked in one transaction.
+func (s *BlockedService) UnblockUser(userID int64, liftedBy *int64, reason string) (*UnblockResult, error) {
+	result := &UnblockResult{Lifted: make([]*data.Blocked, 0)}
+	err := s.uow.Do(func(repos *data.TxRepositories) error {
+		blocked, err := repos.Users.LockBlocked(userID)
+		if err != nil {
+			if stdErrors.Is(err, sql.ErrNoRows) {
+				return errors.NewNotFoundError("user not found")
+			}
+			return err
+		}
+
+		active, err := repos.Blocked.FindActiveByUser(userID)
+		if err != nil {
+			return err
+		}
+
+		for _, ban := range active {
+			lifted, err := repos.Blocked.Lift(ban.ID, liftedBy, reason)
+			if err != nil {
+				return err
+			}
+			result.Lifted = append(result.Lifted, lifted)
+		}
+
+		result.Applied = blocked || len(active) > 0
+		return repos.Users.SetBlocked(userID, false)
+	})
+	if err != nil {
+		return nil, asServiceError(err, "failed to unblock user")
+	}
+
+	return result, nil
 }
 
 func (s *BlockedService) UpdateBlocked(blocked *data.Blocked) (*data.Blocked, error) {
+	if blocked.ReasonCode != nil {
+		if _, err := s.activeReason(*blocked.ReasonCode); err != nil {
+			return nil, err
+		}
+	}
+
 	blocked, err := s.blockedRepo.Update(blocked)
 	if err != nil {
 		return nil, err
@@ -54,11 +449,116 @@ func (s *BlockedService) UpdateBlocked(blocked *data.Blocked) (*data.Blocked, er
 	return blocked, nil
 }
 
-func (s *BlockedService) DeleteBlocked(id int64) (bool, error) {
-	deleted, err := s.blockedRepo.Delete(id)
+// LiftBlocked closes a single ban, recording who lifted it and why, and
+// clears users.blocked when no other ban is still in force. The record is
+// kept for the user's history. Lifting an already closed ban returns it
+// unchanged.
+func (s *BlockedService) LiftBlocked(id int64, liftedBy *int64, reason string) (*UnblockResult, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.NewValidationError("reason is required")
+	}
+
+	result := &UnblockResult{Lifted: make([]*data.Blocked, 0)}
+	err := s.uow.Do(func(repos *data.TxRepositories) error {
+		lifted, applied, err := liftBan(repos, id, liftedBy, reason)
+		if err != nil {
+			return err
+		}
+		result.Lifted = append(result.Lifted, lifted)
+		result.Applied = applied
+		return nil
+	})
 	if err != nil {
-		return false, err
+		return nil, asServiceError(err, "failed to lift blocked")
 	}
 
-	return deleted, nil
+	return result, nil
+}
+
+// liftBan closes one ban inside a unit of work and keeps users.blocked in
+// step with whatever is still in force. applied is false when the ban was
+// already closed.
+func liftBan(repos *data.TxRepositories, id int64, liftedBy *int64, reason string) (*data.Blocked, bool, error) {
+	blocked, err := repos.Blocked.FindById(id)
+	if err != nil {
+		if stdErrors.Is(err, sql.ErrNoRows) {
+			return nil, false, errors.NewNotFoundError("blocked not found")
+		}
+		return nil, false, err
+	}
+	if blocked.LiftedAt != nil {
+		return blocked, false, nil
+	}
+
+	if _, err := repos.Users.LockBlocked(blocked.UserID); err != nil {
+		return nil, false, err
+	}
+
+	lifted, err := repos.Blocked.Lift(id, liftedBy, reason)
+	if err != nil {
+		return nil, false, err
+	}
+
+	remaining, err := repos.Blocked.FindActiveByUser(blocked.UserID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return lifted, true, repos.Users.SetBlocked(blocked.UserID, len(remaining) > 0)
+}
+
+func (s *BlockedService) GetUserBans(userID int64) ([]*data.Blocked, error) {
+	bans, err := s.blockedRepo.FindByUser(userID)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to get ban history")
+	}
+
+	return bans, nil
+}
+
+// describeProposal renders a proposal for error messages, e.g.
+// "step 2: suspension for 24h0m0s".
+func describeProposal(proposal *BlockProposal) string {
+	description := proposal.Enforcement
+	if proposal.DurationSeconds != nil {
+		description += " for " + (time.Duration(*proposal.DurationSeconds) * time.Second).String()
+	}
+	if proposal.Step != nil {
+		description = "step " + strconv.Itoa(*proposal.Step) + ": " + description
+	}
+	return description
+}
+
+// activeReason loads a reason code that may still be used for new bans.
+func (s *BlockedService) activeReason(code string) (*data.BanReason, error) {
+	return activeBanReason(s.banReasonRepo, code)
+}
+
+// activeBanReason loads a reason code that may still be used for new bans
+// and restrictions.
+func activeBanReason(banReasonRepo data.BanReasonRepositoryInterface, code string) (*data.BanReason, error) {
+	if code == "" {
+		return nil, errors.NewValidationError("reason_code is required")
+	}
+
+	reason, err := banReasonRepo.FindByCode(code)
+	if err != nil {
+		return nil, errors.NewValidationError("unknown reason_code: " + code)
+	}
+	if reason.RetiredAt != nil {
+		return nil, errors.NewValidationError("reason_code is retired: " + code)
+	}
+
+	return reason, nil
+}
+
+// asServiceError passes typed service errors through and hides anything
+// else behind an internal error.
+func asServiceError(err error, message string) error {
+	var appErr *errors.AppError
+	if stdErrors.As(err, &appErr) {
+		return err
+	}
+	return errors.NewInternalError(message)
 }
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/valu/vemeet-admin-api/internal/data"
+)
+
+func seconds(d time.Duration) *int64 {
+	s := int64(d / time.Second)
+	return &s
+}
+
+func testLadder() []*data.EscalationStep {
+	return []*data.EscalationStep{
+		{ReasonCode: "spam", Step: 1, Action: data.EnforcementWarning},
+		{ReasonCode: "spam", Step: 2, Action: data.EnforcementSuspension, DurationSeconds: seconds(24 * time.Hour)},
+		{ReasonCode: "spam", Step: 3, Action: data.EnforcementSuspension, DurationSeconds: seconds(7 * 24 * time.Hour)},
+		{ReasonCode: "spam", Step: 4, Action: data.EnforcementPermanent},
+	}
+}
+
+func TestProposeBlock(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		reason      *data.BanReason
+		ladder      []*data.EscalationStep
+		prior       int64
+		step        *int
+		enforcement string
+		duration    *int64
+	}{
+		{
+			name:        "no ladder uses the default duration",
+			reason:      &data.BanReason{Code: "spam", DefaultDurationSeconds: seconds(time.Hour)},
+			enforcement: data.EnforcementSuspension,
+			duration:    seconds(time.Hour),
+		},
+		{
+			name:        "no ladder without a default is permanent",
+			reason:      &data.BanReason{Code: "spam"},
+			prior:       3,
+			enforcement: data.EnforcementPermanent,
+		},
+		{
+			name:        "first ban takes the first step",
+			reason:      &data.BanReason{Code: "spam", DefaultDurationSeconds: seconds(time.Hour)},
+			ladder:      testLadder(),
+			step:        intPtr(1),
+			enforcement: data.EnforcementWarning,
+		},
+		{
+			name:        "one prior ban takes the second step",
+			reason:      &data.BanReason{Code: "spam"},
+			ladder:      testLadder(),
+			prior:       1,
+			step:        intPtr(2),
+			enforcement: data.EnforcementSuspension,
+			duration:    seconds(24 * time.Hour),
+		},
+		{
+			name:        "two prior bans take the third step",
+			reason:      &data.BanReason{Code: "spam"},
+			ladder:      testLadder(),
+			prior:       2,
+			step:        intPtr(3),
+			enforcement: data.EnforcementSuspension,
+			duration:    seconds(7 * 24 * time.Hour),
+		},
+		{
+			name:        "reaching the last step",
+			reason:      &data.BanReason{Code: "spam"},
+			ladder:      testLadder(),
+			prior:       3,
+			step:        intPtr(4),
+			enforcement: data.EnforcementPermanent,
+		},
+		{
+			name:        "stays on the last step past the end of the ladder",
+			reason:      &data.BanReason{Code: "spam"},
+			ladder:      testLadder()[:3],
+			prior:       10,
+			step:        intPtr(3),
+			enforcement: data.EnforcementSuspension,
+			duration:    seconds(7 * 24 * time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proposal := proposeBlock(tt.reason, tt.ladder, tt.prior, now)
+
+			if proposal.ReasonCode != tt.reason.Code || proposal.PriorBans != tt.prior {
+				t.Errorf("got reason %q with %d prior bans, want %q with %d", proposal.ReasonCode, proposal.PriorBans, tt.reason.Code, tt.prior)
+			}
+			if !equalInt(proposal.Step, tt.step) {
+				t.Errorf("step = %v, want %v", deref(proposal.Step), deref(tt.step))
+			}
+			if proposal.Enforcement != tt.enforcement {
+				t.Errorf("enforcement = %q, want %q", proposal.Enforcement, tt.enforcement)
+			}
+			if !equalInt64(proposal.DurationSeconds, tt.duration) {
+				t.Errorf("duration = %v, want %v", deref(proposal.DurationSeconds), deref(tt.duration))
+			}
+
+			switch {
+			case tt.duration == nil && proposal.ExpiresAt != nil:
+				t.Errorf("expires_at = %v, want none", *proposal.ExpiresAt)
+			case tt.duration != nil && (proposal.ExpiresAt == nil || !proposal.ExpiresAt.Equal(now.Add(time.Duration(*tt.duration)*time.Second))):
+				t.Errorf("expires_at = %v, want %d seconds after now", proposal.ExpiresAt, *tt.duration)
+			}
+		})
+	}
+}
+
+func TestBlockOverrideDetection(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	reason := &data.BanReason{Code: "spam"}
+	suspension := proposeBlock(reason, testLadder(), 1, now)
+	warning := proposeBlock(reason, testLadder(), 0, now)
+	proposedExpiry := *suspension.ExpiresAt
+
+	at := func(t time.Time) *time.Time { return &t }
+
+	tests := []struct {
+		name     string
+		input    BlockInput
+		proposal *BlockProposal
+		chosen   bool
+		override bool
+		invalid  bool
+	}{
+		{
+			name:     "nothing chosen leaves it to policy",
+			input:    BlockInput{},
+			proposal: suspension,
+		},
+		{
+			name:     "same enforcement is not an override",
+			input:    BlockInput{Enforcement: data.EnforcementWarning},
+			proposal: warning,
+			chosen:   true,
+		},
+		{
+			name:     "suspension without expiry takes the proposed one",
+			input:    BlockInput{Enforcement: data.EnforcementSuspension},
+			proposal: suspension,
+			chosen:   true,
+		},
+		{
+			name:     "echoed expiry within tolerance is not an override",
+			input:    BlockInput{ExpiresAt: at(proposedExpiry.Add(30 * time.Second))},
+			proposal: suspension,
+			chosen:   true,
+		},
+		{
+			name:     "expiry beyond tolerance is an override",
+			input:    BlockInput{ExpiresAt: at(proposedExpiry.Add(2 * time.Minute))},
+			proposal: suspension,
+			chosen:   true,
+			override: true,
+		},
+		{
+			name:     "different enforcement is an override",
+			input:    BlockInput{Enforcement: data.EnforcementWarning},
+			proposal: suspension,
+			chosen:   true,
+			override: true,
+		},
+		{
+			name:     "permanent instead of a suspension is an override",
+			input:    BlockInput{Permanent: true},
+			proposal: suspension,
+			chosen:   true,
+			override: true,
+		},
+		{
+			name:     "suspension instead of a warning needs an expiry",
+			input:    BlockInput{Enforcement: data.EnforcementSuspension},
+			proposal: warning,
+			invalid:  true,
+		},
+		{
+			name:     "suspension instead of a warning is an override",
+			input:    BlockInput{Enforcement: data.EnforcementSuspension, ExpiresAt: at(now.Add(time.Hour))},
+			proposal: warning,
+			chosen:   true,
+			override: true,
+		},
+		{
+			name:     "expiry in the past",
+			input:    BlockInput{ExpiresAt: at(now.Add(-time.Hour))},
+			proposal: suspension,
+			invalid:  true,
+		},
+		{
+			name:     "permanent conflicting with enforcement",
+			input:    BlockInput{Permanent: true, Enforcement: data.EnforcementWarning},
+			proposal: suspension,
+			invalid:  true,
+		},
+		{
+			name:     "expiry on a warning",
+			input:    BlockInput{Enforcement: data.EnforcementWarning, ExpiresAt: at(now.Add(time.Hour))},
+			proposal: suspension,
+			invalid:  true,
+		},
+		{
+			name:     "unknown enforcement",
+			input:    BlockInput{Enforcement: "shadowban"},
+			proposal: suspension,
+			invalid:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enforcement, expiresAt, chosen, err := chosenEnforcement(tt.input, tt.proposal, now)
+			if tt.invalid {
+				if err == nil {
+					t.Fatalf("expected a validation error, got %q", enforcement)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if chosen != tt.chosen {
+				t.Fatalf("chosen = %v, want %v", chosen, tt.chosen)
+			}
+			if !chosen {
+				return
+			}
+			if override := !matchesProposal(tt.proposal, enforcement, expiresAt); override != tt.override {
+				t.Errorf("override = %v, want %v", override, tt.override)
+			}
+		})
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func equalInt(a, b *int) bool {
+	return (a == nil) == (b == nil) && (a == nil || *a == *b)
+}
+
+func equalInt64(a, b *int64) bool {
+	return (a == nil) == (b == nil) && (a == nil || *a == *b)
+}
+
+func deref[T any](p *T) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
@@ -0,0 +1,828 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	stdErrors "errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/valu/vemeet-admin-api/internal/data"
+	"github.com/valu/vemeet-admin-api/internal/errors"
+)
+
+// BulkModerationConfig bounds bulk requests. Requests of up to SyncLimit
+// items run inline; larger ones run as a background job. Items are applied
+// BatchSize at a time, one transaction per batch.
+type BulkModerationConfig struct {
+	BatchSize int
+	SyncLimit int
+	MaxItems  int
+}
+
+// BulkModeBan selects a ban rather than a restriction in BulkBlockInput.
+const BulkModeBan = "ban"
+
+// BulkBlockInput applies one ban or restriction to many users. Targets are
+// UserIDs followed by Usernames, and item positions follow that order. Mode
+// is "ban" (or empty) or a restriction mode; the remaining fields mean what
+// they mean on BlockInput and RestrictInput.
+type BulkBlockInput struct {
+	UserIDs        []int64    `json:"user_ids,omitempty"`
+	Usernames      []string   `json:"usernames,omitempty"`
+	Mode           string     `json:"mode"`
+	ReasonCode     string     `json:"reason_code"`
+	Notes          string     `json:"reason,omitempty"`
+	Enforcement    string     `json:"enforcement,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	Permanent      bool       `json:"permanent,omitempty"`
+	Override       bool       `json:"override,omitempty"`
+	OverrideReason string     `json:"override_reason,omitempty"`
+	Async          bool       `json:"-"`
+}
+
+// BulkBlockResult holds the per-item outcomes of a bulk request that ran
+// inline, or the job tracking it when it runs in the background.
+type BulkBlockResult struct {
+	Items  []*data.ModerationJobItem `json:"items,omitempty"`
+	Counts map[string]int64          `json:"counts,omitempty"`
+	Job    *data.ModerationJob       `json:"job,omitempty"`
+}
+
+// BanImportInput is an uploaded ban list. Format is csv or ndjson, or
+// empty to go by the file extension. Ban holds the settings every row is
+// banned with; its targets are ignored.
+type BanImportInput struct {
+	Data     io.Reader
+	Filename string
+	Format   string
+	Mapping  BanImportMapping
+	Ban      BulkBlockInput
+}
+
+// banImportParams is what an import job keeps so the ban can be applied
+// as previewed once the import is confirmed.
+type banImportParams struct {
+	Filename string           `json:"filename"`
+	Format   string           `json:"format"`
+	Mapping  BanImportMapping `json:"mapping"`
+	Ban      BulkBlockInput   `json:"ban"`
+}
+
+type BulkModerationService struct {
+	userRepo           data.UserRepositoryInterface
+	blockedRepo        data.BlockedRepositoryInterface
+	banReasonRepo      data.BanReasonRepositoryInterface
+	jobRepo            data.ModerationJobRepositoryInterface
+	blockedService     BlockedServiceInterface
+	restrictionService RestrictionServiceInterface
+	auditService       AuditServiceInterface
+	config             BulkModerationConfig
+
+	// mu guards stopping. Background jobs are counted in running so
+	// Shutdown can wait for them.
+	mu       sync.Mutex
+	stopping bool
+	running  sync.WaitGroup
+}
+
+type BulkModerationServiceInterface interface {
+	BulkBlock(actor AuditActor, input BulkBlockInput) (*BulkBlockResult, error)
+	// GetJob and GetJobItems only find jobs of the given kind, so each
+	// kind's routes keep to their own jobs.
+	GetJob(kind string, id int64) (*data.ModerationJob, error)
+	GetJobItems(kind string, id int64, page int64, limit int64, status string) (*data.ModerationJobItemPagination, error)
+	PreviewBanImport(actor AuditActor, input BanImportInput) (*data.ModerationJob, error)
+	ConfirmBanImport(actor AuditActor, id int64) (*data.ModerationJob, error)
+	GetBanImports(page int64, limit int64) (*data.ModerationJobPagination, error)
+	// RunJobRecovery resumes jobs left unfinished by a stopped process,
+	// at once and then periodically, until ctx is cancelled.
+	RunJobRecovery(ctx context.Context)
+	// Shutdown stops jobs after their current batch, releasing them to be
+	// resumed, and waits for that or for ctx to end.
+	Shutdown(ctx context.Context) error
+}
+
+func NewBulkModerationService(
+	userRepo data.UserRepositoryInterface,
+	blockedRepo data.BlockedRepositoryInterface,
+	banReasonRepo data.BanReasonRepositoryInterface,
+	jobRepo data.ModerationJobRepositoryInterface,
+	blockedService BlockedServiceInterface,
+	restrictionService RestrictionServiceInterface,
+	auditService AuditServiceInterface,
+	config BulkModerationConfig,
+) BulkModerationServiceInterface {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 50
+	}
+	return &BulkModerationService{
+		userRepo:           userRepo,
+		blockedRepo:        blockedRepo,
+		banReasonRepo:      banReasonRepo,
+		jobRepo:            jobRepo,
+		blockedService:     blockedService,
+		restrictionService: restrictionService,
+		auditService:       auditService,
+		config:             config,
+	}
+}
+
+// BulkBlock bans or restricts every target. Each batch runs in one
+// transaction; when a batch fails its users are retried one at a time so a
+// single bad item only fails itself. Targets that match no user are
+// reported as not_found rather than failing the request.
+func (s *BulkModerationService) BulkBlock(actor AuditActor, input BulkBlockInput) (*BulkBlockResult, error) {
+	if err := s.validate(&input); err != nil {
+		return nil, err
+	}
+
+	total := len(input.UserIDs) + len(input.Usernames)
+	if !input.Async && total <= s.config.SyncLimit {
+		items, err := s.resolve(input)
+		if err != nil {
+			return nil, err
+		}
+		for start := 0; start < len(items); start += s.config.BatchSize {
+			s.applyBatch(actor, input, items[start:min(start+s.config.BatchSize, len(items))])
+		}
+		return &BulkBlockResult{Items: items, Counts: countItems(items)}, nil
+	}
+
+	params, err := json.Marshal(input)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to create bulk job")
+	}
+
+	job := &data.ModerationJob{
+		Kind:      data.ModerationJobKindBulkBlock,
+		Params:    params,
+		Total:     total,
+		CreatedBy: actor.AdminID,
+	}
+	if err := s.jobRepo.Create(job); err != nil {
+		return nil, errors.NewInternalError("failed to create bulk job")
+	}
+
+	s.spawn(job.ID, func() { s.runJob(actor, input, job) })
+
+	return &BulkBlockResult{Job: job}, nil
+}
+
+func (s *BulkModerationService) validate(input *BulkBlockInput) error {
+	total := len(input.UserIDs) + len(input.Usernames)
+	if total == 0 {
+		return errors.NewValidationError("user_ids or usernames is required")
+	}
+	if s.config.MaxItems > 0 && total > s.config.MaxItems {
+		return errors.NewValidationError("at most " + strconv.Itoa(s.config.MaxItems) + " users can be moderated at once")
+	}
+
+	return s.validateEnforcement(input)
+}
+
+// validateEnforcement checks the settings shared by every target, so a bad
+// request fails up front rather than once per item.
+func (s *BulkModerationService) validateEnforcement(input *BulkBlockInput) error {
+	if input.Mode == "" {
+		input.Mode = BulkModeBan
+	}
+	if input.Mode != BulkModeBan {
+		if !data.ValidRestrictionMode(input.Mode) {
+			return errors.NewValidationError("mode must be ban, shadow_ban, inbox_lock or swiper_restriction")
+		}
+		if input.Enforcement != "" || input.Override {
+			return errors.NewValidationError("enforcement and override only apply to bans")
+		}
+	}
+
+	if input.Override && strings.TrimSpace(input.OverrideReason) == "" {
+		return errors.NewValidationError("override_reason is required when overriding the escalation policy")
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return errors.NewValidationError("expires_at must be in the future")
+	}
+
+	_, err := activeBanReason(s.banReasonRepo, input.ReasonCode)
+	return err
+}
+
+// resolve turns the targets into job items, in position order. Targets
+// that match no user come back already marked not_found.
+func (s *BulkModerationService) resolve(input BulkBlockInput) ([]*data.ModerationJobItem, error) {
+	existing, err := s.userRepo.FindExistingIDs(input.UserIDs)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to look up users")
+	}
+	byUsername, err := s.userRepo.FindIDsByUsername(input.Usernames)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to look up users")
+	}
+
+	items := make([]*data.ModerationJobItem, 0, len(input.UserIDs)+len(input.Usernames))
+	for _, id := range input.UserIDs {
+		item := &data.ModerationJobItem{Position: len(items), Target: strconv.FormatInt(id, 10)}
+		if existing[id] {
+			item.UserID = &id
+		}
+		items = append(items, item)
+	}
+	for _, username := range input.Usernames {
+		item := &data.ModerationJobItem{Position: len(items), Target: username}
+		if id, ok := byUsername[username]; ok {
+			item.UserID = &id
+		}
+		items = append(items, item)
+	}
+
+	for _, item := range items {
+		if item.UserID == nil {
+			item.Status = data.ModerationItemNotFound
+		}
+	}
+
+	return items, nil
+}
+
+// applyBatch fills in the status of every pending item in batch.
+func (s *BulkModerationService) applyBatch(actor AuditActor, input BulkBlockInput, batch []*data.ModerationJobItem) {
+	pending := make([]*data.ModerationJobItem, 0, len(batch))
+	for _, item := range batch {
+		if item.Status == "" {
+			pending = append(pending, item)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	if err := s.apply(actor, input, pending); err == nil {
+		return
+	}
+
+	for _, item := range pending {
+		if err := s.apply(actor, input, []*data.ModerationJobItem{item}); err != nil {
+			setItemError(item, err)
+		}
+	}
+}
+
+// apply moderates items in one transaction and records their outcomes.
+// Nothing is recorded when it fails.
+func (s *BulkModerationService) apply(actor AuditActor, input BulkBlockInput, items []*data.ModerationJobItem) error {
+	if input.Mode != BulkModeBan {
+		return s.applyRestrictions(actor, input, items)
+	}
+
+	inputs := make([]BlockInput, len(items))
+	for i, item := range items {
+		notes := input.Notes
+		if item.Notes != nil {
+			notes = *item.Notes
+		}
+		inputs[i] = BlockInput{
+			UserID:         *item.UserID,
+			ReasonCode:     input.ReasonCode,
+			Notes:          notes,
+			Enforcement:    input.Enforcement,
+			ExpiresAt:      input.ExpiresAt,
+			Permanent:      input.Permanent,
+			Override:       input.Override,
+			OverrideReason: input.OverrideReason,
+			BlockedBy:      actor.AdminID,
+		}
+	}
+
+	results, err := s.blockedService.BlockUsers(inputs)
+	if err != nil {
+		return err
+	}
+
+	for i, result := range results {
+		items[i].RecordID = &result.Blocked.ID
+		if !result.Applied {
+			items[i].Status = data.ModerationItemAlreadyBlocked
+			continue
+		}
+
+		items[i].Status = data.ModerationItemApplied
+		id := strconv.FormatInt(result.Blocked.ID, 10)
+		s.auditService.Record(actor, AuditActionBlockedCreate, AuditTargetBlocked, id, nil, result.Blocked)
+		if result.Blocked.PolicyOverride {
+			s.auditService.Record(actor, AuditActionBlockedOverride, AuditTargetBlocked, id, result.Proposal, result.Blocked)
+		}
+	}
+
+	return nil
+}
+
+func (s *BulkModerationService) applyRestrictions(actor AuditActor, input BulkBlockInput, items []*data.ModerationJobItem) error {
+	inputs := make([]RestrictInput, len(items))
+	for i, item := range items {
+		inputs[i] = RestrictInput{
+			UserID:     *item.UserID,
+			Mode:       input.Mode,
+			ReasonCode: input.ReasonCode,
+			Notes:      input.Notes,
+			ExpiresAt:  input.ExpiresAt,
+			Permanent:  input.Permanent,
+			CreatedBy:  actor.AdminID,
+		}
+	}
+
+	results, err := s.restrictionService.RestrictUsers(inputs)
+	if err != nil {
+		return err
+	}
+
+	for i, result := range results {
+		items[i].RecordID = &result.Restriction.ID
+		if !result.Applied {
+			items[i].Status = data.ModerationItemAlreadyBlocked
+			continue
+		}
+
+		items[i].Status = data.ModerationItemApplied
+		s.auditService.Record(actor, AuditActionRestrictionCreate, AuditTargetRestriction,
+			strconv.FormatInt(result.Restriction.ID, 10), nil, result.Restriction)
+	}
+
+	return nil
+}
+
+// setItemError records why a single item could not be applied. A user
+// deleted since the targets were resolved counts as not_found.
+func setItemError(item *data.ModerationJobItem, err error) {
+	var appErr *errors.AppError
+	if stdErrors.As(err, &appErr) && appErr.Type == errors.ErrorTypeNotFound {
+		item.Status = data.ModerationItemNotFound
+		return
+	}
+
+	message := "failed to moderate user"
+	if appErr != nil {
+		message = appErr.Message
+	}
+	item.Status = data.ModerationItemFailed
+	item.Error = &message
+}
+
+// runJob starts a queued bulk job, or carries on with a running one after
+// the items it has already recorded.
+func (s *BulkModerationService) runJob(actor AuditActor, input BulkBlockInput, job *data.ModerationJob) {
+	if job.Status == data.ModerationJobStatusQueued {
+		if err := s.jobRepo.Start(job.ID); err != nil {
+			s.failJob(job.ID, err, "failed to start job")
+			return
+		}
+	}
+
+	items, err := s.resolve(input)
+	if err != nil {
+		s.failJob(job.ID, err, "failed to look up users")
+		return
+	}
+
+	// Items are recorded in position order, so the first processed of them
+	// are done.
+	s.processJob(actor, input, job.ID, items[min(job.Processed, len(items)):], s.jobRepo.RecordItems)
+}
+
+// processJob applies items batch by batch, storing each batch's outcomes
+// with record as it goes so progress can be polled, and then finishes the
+// job.
+func (s *BulkModerationService) processJob(actor AuditActor, input BulkBlockInput, jobID int64, items []*data.ModerationJobItem,
+	record func(jobID int64, items []*data.ModerationJobItem) error) {
+	for start := 0; start < len(items); start += s.config.BatchSize {
+		if s.stopped() {
+			s.release(jobID)
+			return
+		}
+
+		batch := items[start:min(start+s.config.BatchSize, len(items))]
+		s.applyBatch(actor, input, batch)
+		if err := record(jobID, batch); err != nil {
+			s.failJob(jobID, err, "failed to record progress after "+strconv.Itoa(start)+" items")
+			return
+		}
+	}
+
+	if err := s.jobRepo.Finish(jobID, data.ModerationJobStatusCompleted, nil); err != nil {
+		log.Error().Err(err).Int64("job_id", jobID).Msg("Failed to mark moderation job completed")
+	}
+}
+
+// spawn runs a job in the background. Once shutdown has begun the job is
+// released instead, for whichever process recovers jobs next.
+func (s *BulkModerationService) spawn(jobID int64, run func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopping {
+		s.release(jobID)
+		return
+	}
+
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		run()
+	}()
+}
+
+func (s *BulkModerationService) stopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopping
+}
+
+func (s *BulkModerationService) release(jobID int64) {
+	if err := s.jobRepo.Release(jobID); err != nil {
+		log.Error().Err(err).Int64("job_id", jobID).Msg("Failed to release moderation job")
+		return
+	}
+	log.Info().Int64("job_id", jobID).Msg("Released moderation job for recovery")
+}
+
+func (s *BulkModerationService) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopping = true
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+const (
+	// jobHeartbeatStale is how long a job can go without progress before
+	// its process is taken to have stopped. It must stay well above the
+	// time one batch takes.
+	jobHeartbeatStale   = 5 * time.Minute
+	jobRecoveryInterval = time.Minute
+	jobRecoveryBatch    = 10
+)
+
+func (s *BulkModerationService) RunJobRecovery(ctx context.Context) {
+	ticker := time.NewTicker(jobRecoveryInterval)
+	defer ticker.Stop()
+
+	for {
+		s.recoverJobs()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// recoverJobs claims stale jobs and resumes each in the background.
+func (s *BulkModerationService) recoverJobs() {
+	for !s.stopped() {
+		jobs, err := s.jobRepo.ClaimStale(jobHeartbeatStale, jobRecoveryBatch)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to claim unfinished moderation jobs")
+			return
+		}
+
+		for _, job := range jobs {
+			s.resumeJob(job)
+		}
+		if len(jobs) < jobRecoveryBatch {
+			return
+		}
+	}
+}
+
+// resumeJob picks a claimed job up where it stopped, acting for the admin
+// who started or confirmed it.
+func (s *BulkModerationService) resumeJob(job *data.ModerationJob) {
+	log.Info().Int64("job_id", job.ID).Str("kind", job.Kind).Int("processed", job.Processed).Msg("Resuming moderation job")
+
+	switch job.Kind {
+	case data.ModerationJobKindBulkBlock:
+		var input BulkBlockInput
+		if err := json.Unmarshal(job.Params, &input); err != nil {
+			s.failJob(job.ID, err, "failed to read job settings")
+			return
+		}
+		actor := AuditActor{AdminID: job.CreatedBy}
+		s.spawn(job.ID, func() { s.runJob(actor, input, job) })
+
+	case data.ModerationJobKindBanImport:
+		var params banImportParams
+		if err := json.Unmarshal(job.Params, &params); err != nil {
+			s.failJob(job.ID, err, "failed to read import settings")
+			return
+		}
+		actor := AuditActor{AdminID: job.ConfirmedBy}
+		s.spawn(job.ID, func() {
+			// Rows still ready are the ones the stopped run did not reach.
+			items, err := s.jobRepo.FindItemsByStatus(job.ID, data.ModerationItemReady)
+			if err != nil {
+				s.failJob(job.ID, err, "failed to load import rows")
+				return
+			}
+			for _, item := range items {
+				item.Status = ""
+			}
+			s.processJob(actor, params.Ban, job.ID, items, s.jobRepo.UpdateItems)
+		})
+
+	default:
+		s.failJob(job.ID, stdErrors.New("unknown job kind "+job.Kind), "unknown job kind")
+	}
+}
+
+func (s *BulkModerationService) failJob(jobID int64, err error, message string) {
+	log.Error().Err(err).Int64("job_id", jobID).Msg("Moderation job failed")
+	if err := s.jobRepo.Finish(jobID, data.ModerationJobStatusFailed, &message); err != nil {
+		log.Error().Err(err).Int64("job_id", jobID).Msg("Failed to mark moderation job failed")
+	}
+}
+
+func (s *BulkModerationService) GetJob(kind string, id int64) (*data.ModerationJob, error) {
+	job, err := s.jobRepo.FindById(id)
+	if err != nil {
+		if stdErrors.Is(err, sql.ErrNoRows) {
+			return nil, jobNotFound(kind)
+		}
+		return nil, errors.NewInternalError("failed to load job")
+	}
+	if job.Kind != kind {
+		return nil, jobNotFound(kind)
+	}
+
+	return job, nil
+}
+
+// findJob loads a job of any kind.
+func (s *BulkModerationService) findJob(id int64) (*data.ModerationJob, error) {
+	job, err := s.jobRepo.FindById(id)
+	if err != nil {
+		if stdErrors.Is(err, sql.ErrNoRows) {
+			return nil, errors.NewNotFoundError("job not found")
+		}
+		return nil, errors.NewInternalError("failed to load job")
+	}
+
+	return job, nil
+}
+
+func jobNotFound(kind string) error {
+	if kind == data.ModerationJobKindBanImport {
+		return errors.NewNotFoundError("import not found")
+	}
+	return errors.NewNotFoundError("job not found")
+}
+
+func (s *BulkModerationService) GetJobItems(kind string, id int64, page int64, limit int64, status string) (*data.ModerationJobItemPagination, error) {
+	job, err := s.GetJob(kind, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != "" && !data.ValidModerationItemStatus(job.Kind, status) {
+		return nil, errors.NewValidationError("unknown status for a " + job.Kind + " job: " + status)
+	}
+
+	items, err := s.jobRepo.FindItems(id, page, limit, status)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to load job items")
+	}
+
+	return items, nil
+}
+
+// PreviewBanImport is the dry run of a ban list import. Every row is
+// checked against users and stored as ready, already_blocked or rejected on
+// a previewed job; nothing is banned until ConfirmBanImport.
+func (s *BulkModerationService) PreviewBanImport(actor AuditActor, input BanImportInput) (*data.ModerationJob, error) {
+	format, err := banImportFormat(input.Format, input.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	input.Ban.Mode = BulkModeBan
+	input.Ban.UserIDs, input.Ban.Usernames = nil, nil
+	if err := s.validateEnforcement(&input.Ban); err != nil {
+		return nil, err
+	}
+
+	rows, err := parseBanImport(input.Data, format, input.Mapping, s.config.MaxItems)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.checkImportRows(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := json.Marshal(banImportParams{
+		Filename: input.Filename,
+		Format:   format,
+		Mapping:  input.Mapping,
+		Ban:      input.Ban,
+	})
+	if err != nil {
+		return nil, errors.NewInternalError("failed to store import")
+	}
+
+	// Rows the confirmed run will skip count as processed from the start.
+	job := &data.ModerationJob{
+		Kind:      data.ModerationJobKindBanImport,
+		Status:    data.ModerationJobStatusPreviewed,
+		Params:    params,
+		Total:     len(items),
+		CreatedBy: actor.AdminID,
+	}
+	for _, item := range items {
+		if item.Status != data.ModerationItemReady {
+			job.Processed++
+		}
+	}
+
+	if err := s.jobRepo.CreateWithItems(job, items); err != nil {
+		return nil, errors.NewInternalError("failed to store import")
+	}
+
+	return s.findJob(job.ID)
+}
+
+// checkImportRows resolves each row to a user and sorts it into ready,
+// already_blocked or rejected. A user listed twice is rejected the second
+// time.
+func (s *BulkModerationService) checkImportRows(rows []*banImportRow) ([]*data.ModerationJobItem, error) {
+	ids := make([]int64, 0, len(rows))
+	usernames := make([]string, 0, len(rows))
+	parsed := make([]int64, len(rows))
+	for i, row := range rows {
+		if row.err != "" || row.userID == "" {
+			if row.err == "" && row.username != "" {
+				usernames = append(usernames, row.username)
+			}
+			continue
+		}
+		id, err := strconv.ParseInt(row.userID, 10, 64)
+		if err != nil || id <= 0 {
+			row.err = "invalid user_id: " + row.userID
+			continue
+		}
+		parsed[i] = id
+		ids = append(ids, id)
+	}
+
+	existing, err := s.userRepo.FindExistingIDs(ids)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to look up users")
+	}
+	byUsername, err := s.userRepo.FindIDsByUsername(usernames)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to look up users")
+	}
+
+	items := make([]*data.ModerationJobItem, len(rows))
+	found := make([]int64, 0, len(rows))
+	firstLine := make(map[int64]int)
+	for i, row := range rows {
+		item := &data.ModerationJobItem{Position: row.line, Target: row.userID}
+		if row.notes != "" {
+			item.Notes = &row.notes
+		}
+		items[i] = item
+
+		switch {
+		case row.err != "":
+		case parsed[i] != 0:
+			if existing[parsed[i]] {
+				item.UserID = &parsed[i]
+			}
+		case row.username != "":
+			item.Target = row.username
+			if id, ok := byUsername[row.username]; ok {
+				item.UserID = &id
+			}
+		default:
+			row.err = "row has neither a user_id nor a username"
+		}
+
+		if row.err == "" && item.UserID == nil {
+			row.err = "user not found"
+		}
+		if item.UserID != nil {
+			if line, ok := firstLine[*item.UserID]; ok {
+				row.err = "same user as line " + strconv.Itoa(line)
+				item.UserID = nil
+			} else {
+				firstLine[*item.UserID] = row.line
+				found = append(found, *item.UserID)
+			}
+		}
+
+		if row.err != "" {
+			item.Status = data.ModerationItemRejected
+			item.Error = &row.err
+		}
+	}
+
+	banned, err := s.blockedRepo.FindBannedUserIDs(found)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to look up bans")
+	}
+	for _, item := range items {
+		switch {
+		case item.Status != "":
+		case banned[*item.UserID]:
+			item.Status = data.ModerationItemAlreadyBlocked
+		default:
+			item.Status = data.ModerationItemReady
+		}
+	}
+
+	return items, nil
+}
+
+// ConfirmBanImport bans the ready rows of a previewed import through the
+// ban service, with the settings given at upload. Like BulkBlock it runs
+// inline up to the sync limit and in the background beyond it, in which
+// case the job returned is still running.
+func (s *BulkModerationService) ConfirmBanImport(actor AuditActor, id int64) (*data.ModerationJob, error) {
+	job, err := s.GetJob(data.ModerationJobKindBanImport, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != data.ModerationJobStatusPreviewed {
+		return nil, errors.NewValidationError("import has already been confirmed")
+	}
+
+	var params banImportParams
+	if err := json.Unmarshal(job.Params, &params); err != nil {
+		return nil, errors.NewInternalError("failed to read import settings")
+	}
+	if err := s.validateEnforcement(&params.Ban); err != nil {
+		return nil, err
+	}
+
+	items, err := s.jobRepo.FindItemsByStatus(id, data.ModerationItemReady)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to load import rows")
+	}
+	for _, item := range items {
+		item.Status = ""
+	}
+
+	confirmed, err := s.jobRepo.Confirm(id, actor.AdminID)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to confirm import")
+	}
+	if !confirmed {
+		return nil, errors.NewValidationError("import has already been confirmed")
+	}
+
+	if len(items) > s.config.SyncLimit {
+		s.spawn(id, func() { s.processJob(actor, params.Ban, id, items, s.jobRepo.UpdateItems) })
+	} else {
+		s.processJob(actor, params.Ban, id, items, s.jobRepo.UpdateItems)
+	}
+
+	return s.findJob(id)
+}
+
+func (s *BulkModerationService) GetBanImports(page int64, limit int64) (*data.ModerationJobPagination, error) {
+	jobs, err := s.jobRepo.FindAll(data.ModerationJobKindBanImport, page, limit)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to load imports")
+	}
+
+	return jobs, nil
+}
+
+func countItems(items []*data.ModerationJobItem) map[string]int64 {
+	counts := map[string]int64{
+		data.ModerationItemApplied:        0,
+		data.ModerationItemAlreadyBlocked: 0,
+		data.ModerationItemNotFound:       0,
+		data.ModerationItemFailed:         0,
+	}
+	for _, item := range items {
+		counts[item.Status]++
+	}
+	return counts
+}
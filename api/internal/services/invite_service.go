@@ -0,0 +1,155 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	stdErrors "errors"
+	"strings"
+	"time"
+
+	"github.com/valu/vemeet-admin-api/internal/auth"
+	"github.com/valu/vemeet-admin-api/internal/data"
+	"github.com/valu/vemeet-admin-api/internal/errors"
+)
+
+const inviteTTL = 72 * time.Hour
+
+type InviteService struct {
+	inviteRepo data.InviteRepositoryInterface
+	adminRepo  data.AdminRepositoryInterface
+	policy     *PasswordPolicy
+}
+
+type InviteServiceInterface interface {
+	CreateInvite(email, role string, invitedBy int64) (*data.Invite, string, error)
+	ListInvites() ([]*data.Invite, error)
+	RevokeInvite(id int64) error
+	RedeemInvite(token, name, password, ip string) (*data.Admin, error)
+}
+
+func NewInviteService(
+	inviteRepo data.InviteRepositoryInterface,
+	adminRepo data.AdminRepositoryInterface,
+	policy *PasswordPolicy,
+) InviteServiceInterface {
+	return &InviteService{inviteRepo, adminRepo, policy}
+}
+
+func (s *InviteService) CreateInvite(email, role string, invitedBy int64) (*data.Invite, string, error) {
+	email = strings.TrimSpace(strings.ToLower(email))
+	if email == "" {
+		return nil, "", errors.NewValidationError("email is required")
+	}
+
+	if role == "" {
+		role = string(auth.RoleViewer)
+	}
+	if !auth.Role(role).Valid() {
+		return nil, "", errors.NewValidationError("invalid role")
+	}
+
+	if _, err := s.adminRepo.FindByEmail(email); err == nil {
+		return nil, "", errors.NewValidationError("email already exists")
+	}
+
+	token, err := GenerateOpaqueToken()
+	if err != nil {
+		return nil, "", errors.NewInternalError("failed to generate invite token")
+	}
+
+	invite := &data.Invite{
+		Email:     email,
+		Role:      role,
+		TokenHash: HashOpaqueToken(token),
+		InvitedBy: invitedBy,
+		ExpiresAt: time.Now().Add(inviteTTL),
+	}
+	if err := s.inviteRepo.Create(invite); err != nil {
+		return nil, "", errors.NewInternalError("failed to create invite")
+	}
+
+	return invite, token, nil
+}
+
+func (s *InviteService) ListInvites() ([]*data.Invite, error) {
+	invites, err := s.inviteRepo.FindAll()
+	if err != nil {
+		return nil, errors.NewInternalError("failed to get invites")
+	}
+
+	return invites, nil
+}
+
+func (s *InviteService) RevokeInvite(id int64) error {
+	if _, err := s.inviteRepo.FindById(id); err != nil {
+		return errors.NewNotFoundError("invite not found")
+	}
+
+	revoked, err := s.inviteRepo.Revoke(id)
+	if err != nil {
+		return errors.NewInternalError("failed to revoke invite")
+	}
+	if !revoked {
+		return errors.NewValidationError("invite is already revoked or redeemed")
+	}
+
+	return nil
+}
+
+func (s *InviteService) RedeemInvite(token, name, password, ip string) (*data.Admin, error) {
+	if token == "" || name == "" || password == "" {
+		return nil, errors.NewValidationError("token, name and password are required")
+	}
+
+	invite, err := s.inviteRepo.FindByTokenHash(HashOpaqueToken(token))
+	if err != nil {
+		return nil, errors.NewNotFoundError("invite not found")
+	}
+
+	if _, err := s.adminRepo.FindByEmail(invite.Email); err == nil {
+		return nil, errors.NewValidationError("email already exists")
+	}
+
+	if err := s.policy.Validate(password, nil); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := HashPassword(password)
+	if err != nil {
+		return nil, errors.NewInternalError("password not hashed")
+	}
+
+	admin := &data.Admin{
+		Email:    invite.Email,
+		Password: hashedPassword,
+		Name:     name,
+		Role:     invite.Role,
+	}
+
+	err = s.inviteRepo.Redeem(invite.ID, admin, ip)
+	if stdErrors.Is(err, data.ErrInviteNotRedeemable) {
+		return nil, errors.NewValidationError("invite is expired, revoked or already redeemed")
+	}
+	if err != nil {
+		return nil, errors.NewInternalError("failed to redeem invite")
+	}
+
+	return admin, nil
+}
+
+// GenerateOpaqueToken returns a random URL-safe token. Only its hash is
+// ever persisted.
+func GenerateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func HashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
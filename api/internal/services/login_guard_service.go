@@ -0,0 +1,210 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/valu/vemeet-admin-api/internal/data"
+	"github.com/valu/vemeet-admin-api/internal/errors"
+)
+
+const (
+	LoginFailureUnknownEmail = "unknown_email"
+	LoginFailureUnverified   = "unverified"
+	LoginFailureDeactivated  = "deactivated"
+	LoginFailureBadPassword  = "bad_password"
+	LoginFailureBadMFA       = "bad_mfa"
+	LoginFailureThrottled    = "throttled"
+
+	loginBackoffBase = time.Second
+	loginBackoffMax  = 5 * time.Minute
+)
+
+type LoginGuardConfig struct {
+	MaxFailedAttempts      int
+	MaxFailedAttemptsPerIP int
+	LockoutDuration        time.Duration
+	// Password reset requests are limited per email and per client IP
+	// within ResetWindow.
+	MaxResetsPerEmail int
+	MaxResetsPerIP    int
+	ResetWindow       time.Duration
+}
+
+type LoginGuardService struct {
+	attemptRepo data.LoginAttemptRepositoryInterface
+	adminRepo   data.AdminRepositoryInterface
+	cfg         LoginGuardConfig
+}
+
+type LoginGuardServiceInterface interface {
+	Check(email, ip string) error
+	RecordFailure(email string, adminID *int64, ip, userAgent, reason string)
+	RecordSuccess(email string, adminID int64, ip, userAgent string)
+	CheckResetRequest(email, ip string) error
+	Unlock(adminID int64) error
+	ListAttempts(page int64, limit int64, email, ip string) (*data.LoginAttemptPagination, error)
+}
+
+func NewLoginGuardService(
+	attemptRepo data.LoginAttemptRepositoryInterface,
+	adminRepo data.AdminRepositoryInterface,
+	cfg LoginGuardConfig,
+) LoginGuardServiceInterface {
+	return &LoginGuardService{attemptRepo, adminRepo, cfg}
+}
+
+func accountThrottleKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Check rejects the attempt when either the account or the client IP is
+// locked out or still inside its exponential backoff window. Accounts are
+// keyed by email, so unknown emails are throttled exactly like real ones.
+func (s *LoginGuardService) Check(email, ip string) error {
+	now := time.Now()
+	for _, key := range []string{accountThrottleKey(email), ipThrottleKey(ip)} {
+		throttle, err := s.attemptRepo.GetThrottle(key)
+		if err != nil {
+			return errors.NewInternalError("failed to check login throttle")
+		}
+		if throttle == nil {
+			continue
+		}
+
+		if throttle.LockedUntil != nil && throttle.LockedUntil.After(now) {
+			return errors.NewRateLimitError("too many failed login attempts, try again later")
+		}
+
+		if now.Before(throttle.LastFailureAt.Add(loginBackoff(throttle.Failures))) &&
+			now.Before(throttle.LastFailureAt.Add(s.cfg.LockoutDuration)) {
+			return errors.NewRateLimitError("too many failed login attempts, try again later")
+		}
+	}
+
+	return nil
+}
+
+func (s *LoginGuardService) RecordFailure(email string, adminID *int64, ip, userAgent, reason string) {
+	s.record(email, adminID, ip, userAgent, false, &reason)
+
+	if reason == LoginFailureThrottled {
+		return
+	}
+
+	s.registerFailure(accountThrottleKey(email), s.cfg.MaxFailedAttempts, email)
+	s.registerFailure(ipThrottleKey(ip), s.cfg.MaxFailedAttemptsPerIP, email)
+}
+
+func (s *LoginGuardService) registerFailure(key string, threshold int, email string) {
+	throttle, err := s.attemptRepo.RegisterFailure(key, s.cfg.LockoutDuration)
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to register login failure")
+		return
+	}
+
+	if threshold > 0 && throttle.Failures >= threshold {
+		until := time.Now().Add(s.cfg.LockoutDuration)
+		if err := s.attemptRepo.Lock(key, until); err != nil {
+			log.Error().Err(err).Str("key", key).Msg("Failed to lock login")
+			return
+		}
+		log.Warn().Str("key", key).Str("email", email).Time("locked_until", until).Msg("Login locked after repeated failures")
+	}
+}
+
+// CheckResetRequest counts a password reset request against both the email
+// and the client IP, and rejects it once either has asked too often within
+// the reset window. Unknown emails count the same as real ones.
+func (s *LoginGuardService) CheckResetRequest(email, ip string) error {
+	limits := []struct {
+		key string
+		max int
+	}{
+		{"reset:" + accountThrottleKey(email), s.cfg.MaxResetsPerEmail},
+		{"reset:" + ipThrottleKey(ip), s.cfg.MaxResetsPerIP},
+	}
+
+	limited := false
+	for _, limit := range limits {
+		throttle, err := s.attemptRepo.RegisterFailure(limit.key, s.cfg.ResetWindow)
+		if err != nil {
+			return errors.NewInternalError("failed to check reset throttle")
+		}
+		if limit.max > 0 && throttle.Failures > limit.max {
+			limited = true
+		}
+	}
+
+	if limited {
+		return errors.NewRateLimitError("too many password reset requests, try again later")
+	}
+	return nil
+}
+
+func (s *LoginGuardService) RecordSuccess(email string, adminID int64, ip, userAgent string) {
+	s.record(email, &adminID, ip, userAgent, true, nil)
+
+	if err := s.attemptRepo.ClearThrottle(accountThrottleKey(email)); err != nil {
+		log.Error().Err(err).Msg("Failed to clear login throttle")
+	}
+}
+
+func (s *LoginGuardService) record(email string, adminID *int64, ip, userAgent string, success bool, reason *string) {
+	attempt := &data.LoginAttempt{
+		Email:         strings.ToLower(strings.TrimSpace(email)),
+		AdminID:       adminID,
+		IPAddress:     ip,
+		UserAgent:     userAgent,
+		Success:       success,
+		FailureReason: reason,
+	}
+	if err := s.attemptRepo.Record(attempt); err != nil {
+		log.Error().Err(err).Msg("Failed to record login attempt")
+	}
+}
+
+func (s *LoginGuardService) Unlock(adminID int64) error {
+	admin, err := s.adminRepo.FindById(adminID)
+	if err != nil {
+		return errors.NewNotFoundError("admin not found")
+	}
+
+	if err := s.attemptRepo.ClearThrottle(accountThrottleKey(admin.Email)); err != nil {
+		return errors.NewInternalError("failed to unlock admin")
+	}
+
+	return nil
+}
+
+func (s *LoginGuardService) ListAttempts(page int64, limit int64, email, ip string) (*data.LoginAttemptPagination, error) {
+	attempts, err := s.attemptRepo.FindAll(page, limit, strings.ToLower(strings.TrimSpace(email)), ip)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to get login attempts")
+	}
+
+	return attempts, nil
+}
+
+// loginBackoff is the minimum wait after the n-th consecutive failure. The
+// first failure is free so a single typo does not slow anyone down.
+func loginBackoff(failures int) time.Duration {
+	if failures <= 1 {
+		return 0
+	}
+
+	backoff := loginBackoffBase
+	for i := 2; i < failures; i++ {
+		backoff *= 2
+		if backoff >= loginBackoffMax {
+			return loginBackoffMax
+		}
+	}
+
+	return backoff
+}
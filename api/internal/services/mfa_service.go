@@ -0,0 +1,169 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"github.com/valu/vemeet-admin-api/internal/auth"
+	"github.com/valu/vemeet-admin-api/internal/data"
+	"github.com/valu/vemeet-admin-api/internal/errors"
+)
+
+const (
+	totpIssuer        = "Vemeet Admin"
+	recoveryCodeCount = 10
+)
+
+type MfaService struct {
+	mfaRepo   data.MfaRepositoryInterface
+	adminRepo data.AdminRepositoryInterface
+}
+
+type MfaServiceInterface interface {
+	SetupTOTP(adminID int64) (string, string, error)
+	ConfirmTOTP(adminID int64, code string) ([]string, error)
+	VerifySecondFactor(adminID int64, code, recoveryCode string) error
+}
+
+func NewMfaService(mfaRepo data.MfaRepositoryInterface, adminRepo data.AdminRepositoryInterface) MfaServiceInterface {
+	return &MfaService{mfaRepo, adminRepo}
+}
+
+// SetupTOTP generates a new pending secret and returns it together with its
+// otpauth:// provisioning URI. The secret only takes effect after ConfirmTOTP.
+func (s *MfaService) SetupTOTP(adminID int64) (string, string, error) {
+	admin, err := s.adminRepo.FindById(adminID)
+	if err != nil {
+		return "", "", errors.NewNotFoundError("admin not found")
+	}
+
+	if admin.TOTPEnabled {
+		return "", "", errors.NewValidationError("two-factor authentication is already enabled")
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", errors.NewInternalError("failed to generate totp secret")
+	}
+
+	if err := s.mfaRepo.SetPendingTOTPSecret(adminID, secret); err != nil {
+		return "", "", errors.NewInternalError("failed to store totp secret")
+	}
+
+	return secret, auth.TOTPProvisioningURI(totpIssuer, admin.Email, secret), nil
+}
+
+func (s *MfaService) ConfirmTOTP(adminID int64, code string) ([]string, error) {
+	state, err := s.mfaRepo.GetTOTP(adminID)
+	if err != nil {
+		return nil, errors.NewNotFoundError("admin not found")
+	}
+
+	if state.Enabled {
+		return nil, errors.NewValidationError("two-factor authentication is already enabled")
+	}
+	if state.Secret == "" {
+		return nil, errors.NewValidationError("two-factor setup has not been started")
+	}
+
+	step, ok := auth.ValidateTOTP(state.Secret, code, time.Now())
+	if !ok {
+		return nil, errors.NewValidationError("invalid authentication code")
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		recoveryCode, err := generateRecoveryCode()
+		if err != nil {
+			return nil, errors.NewInternalError("failed to generate recovery codes")
+		}
+		hash, err := HashPassword(recoveryCode)
+		if err != nil {
+			return nil, errors.NewInternalError("failed to hash recovery codes")
+		}
+		codes = append(codes, recoveryCode)
+		hashes = append(hashes, hash)
+	}
+
+	enabled, err := s.mfaRepo.EnableTOTP(adminID, state.Secret, step, hashes)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to enable two-factor authentication")
+	}
+	if !enabled {
+		return nil, errors.NewConflictError("two-factor setup changed while confirming, check its status and try again")
+	}
+
+	return codes, nil
+}
+
+// VerifySecondFactor accepts either a current TOTP code or one unused
+// recovery code, which is burned on success.
+func (s *MfaService) VerifySecondFactor(adminID int64, code, recoveryCode string) error {
+	state, err := s.mfaRepo.GetTOTP(adminID)
+	if err != nil || !state.Enabled {
+		return errors.NewAuthenticationError("invalid authentication code")
+	}
+
+	if code != "" {
+		step, ok := auth.ValidateTOTP(state.Secret, code, time.Now())
+		if !ok {
+			return errors.NewAuthenticationError("invalid authentication code")
+		}
+
+		fresh, err := s.mfaRepo.ConsumeTOTPStep(adminID, step)
+		if err != nil {
+			return errors.NewInternalError("failed to verify authentication code")
+		}
+		if !fresh {
+			return errors.NewAuthenticationError("authentication code already used")
+		}
+
+		return nil
+	}
+
+	if recoveryCode != "" {
+		candidates, err := s.mfaRepo.FindUnusedRecoveryCodes(adminID)
+		if err != nil {
+			return errors.NewInternalError("failed to verify recovery code")
+		}
+
+		normalized := normalizeRecoveryCode(recoveryCode)
+		for _, candidate := range candidates {
+			if ComparePassword(candidate.CodeHash, normalized) != nil {
+				continue
+			}
+
+			used, err := s.mfaRepo.UseRecoveryCode(candidate.ID)
+			if err != nil {
+				return errors.NewInternalError("failed to verify recovery code")
+			}
+			if used {
+				return nil
+			}
+		}
+	}
+
+	return errors.NewAuthenticationError("invalid authentication code")
+}
+
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))
+	return code[:5] + "-" + code[5:10], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != 10 {
+		return code
+	}
+	return code[:5] + "-" + code[5:]
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valu/vemeet-admin-api/internal/auth"
+	"github.com/valu/vemeet-admin-api/internal/data"
+)
+
+// testMfaRepo keeps one admin's second factor in memory, with the same
+// step and recovery code rules as the database.
+type testMfaRepo struct {
+	data.MfaRepositoryInterface
+	state *data.TOTPState
+	codes []*data.RecoveryCode
+	used  map[int64]bool
+}
+
+func (r *testMfaRepo) GetTOTP(adminID int64) (*data.TOTPState, error) {
+	copied := *r.state
+	return &copied, nil
+}
+
+func (r *testMfaRepo) EnableTOTP(adminID int64, secret string, step int64, hashes []string) (bool, error) {
+	if r.state.Enabled || r.state.Secret != secret {
+		return false, nil
+	}
+	r.state.Enabled = true
+	r.state.LastStep = step
+	for i, hash := range hashes {
+		r.codes = append(r.codes, &data.RecoveryCode{ID: int64(i + 1), AdminID: adminID, CodeHash: hash})
+	}
+	return true, nil
+}
+
+func (r *testMfaRepo) ConsumeTOTPStep(adminID int64, step int64) (bool, error) {
+	if r.state.LastStep >= step {
+		return false, nil
+	}
+	r.state.LastStep = step
+	return true, nil
+}
+
+func (r *testMfaRepo) FindUnusedRecoveryCodes(adminID int64) ([]*data.RecoveryCode, error) {
+	unused := make([]*data.RecoveryCode, 0, len(r.codes))
+	for _, code := range r.codes {
+		if !r.used[code.ID] {
+			unused = append(unused, code)
+		}
+	}
+	return unused, nil
+}
+
+func (r *testMfaRepo) UseRecoveryCode(id int64) (bool, error) {
+	if r.used[id] {
+		return false, nil
+	}
+	r.used[id] = true
+	return true, nil
+}
+
+// currentTOTPCode computes the RFC 6238 code for the current step.
+func currentTOTPCode(t *testing.T, secret string) string {
+	t.Helper()
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(time.Now().Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+func TestVerifySecondFactor(t *testing.T) {
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &testMfaRepo{state: &data.TOTPState{Secret: secret}, used: make(map[int64]bool)}
+	service := NewMfaService(repo, nil)
+
+	code := currentTOTPCode(t, secret)
+	recoveryCodes, err := service.ConfirmTOTP(1, code)
+	if err != nil {
+		t.Fatalf("ConfirmTOTP: %v", err)
+	}
+	if len(recoveryCodes) != recoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(recoveryCodes), recoveryCodeCount)
+	}
+	for i, stored := range repo.codes {
+		if stored.CodeHash == recoveryCodes[i] || ComparePassword(stored.CodeHash, recoveryCodes[i]) != nil {
+			t.Fatalf("recovery code %d is not stored as its hash", i)
+		}
+	}
+
+	first := recoveryCodes[0]
+	tests := []struct {
+		name         string
+		code         string
+		recoveryCode string
+		wantOK       bool
+	}{
+		{name: "code used to confirm setup is a replay", code: code},
+		{name: "wrong code", code: "000000"},
+		{name: "neither code nor recovery code"},
+		{name: "unknown recovery code", recoveryCode: "aaaaa-aaaaa"},
+		{name: "recovery code typed without dash in upper case", recoveryCode: strings.ToUpper(strings.ReplaceAll(first, "-", "")), wantOK: true},
+		{name: "recovery code used twice", recoveryCode: first},
+		{name: "another recovery code with spaces", recoveryCode: " " + recoveryCodes[1] + " ", wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.VerifySecondFactor(1, tt.code, tt.recoveryCode)
+			if (err == nil) != tt.wantOK {
+				t.Errorf("error = %v, want ok %v", err, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"abcde-fghij", "abcde-fghij"},
+		{"ABCDEFGHIJ", "abcde-fghij"},
+		{" abcde fghij ", "abcde-fghij"},
+		{"ab-cde-fgh-ij", "abcde-fghij"},
+		{"abc", "abc"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeRecoveryCode(tt.code); got != tt.want {
+			t.Errorf("normalizeRecoveryCode(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
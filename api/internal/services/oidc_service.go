@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/valu/vemeet-admin-api/internal/auth"
+	"github.com/valu/vemeet-admin-api/internal/data"
+	"github.com/valu/vemeet-admin-api/internal/errors"
+	"github.com/valu/vemeet-admin-api/internal/oidc"
+)
+
+// OidcStateTTL is how long a started SSO login can be completed for.
+const OidcStateTTL = 10 * time.Minute
+
+// OidcRoleRule maps either an email domain or a group claim value to a role.
+type OidcRoleRule struct {
+	Kind  string
+	Value string
+	Role  auth.Role
+}
+
+// ParseOidcRoleRules parses "domain:vemeet.com=viewer,group:trust-safety=moderator".
+func ParseOidcRoleRules(spec string) ([]OidcRoleRule, error) {
+	rules := make([]OidcRoleRule, 0)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		matcher, role, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid oidc role rule %q", entry)
+		}
+		kind, value, found := strings.Cut(matcher, ":")
+		if !found || (kind != "domain" && kind != "group") || value == "" {
+			return nil, fmt.Errorf("invalid oidc role rule %q", entry)
+		}
+		if !auth.Role(role).Valid() {
+			return nil, fmt.Errorf("invalid role in oidc role rule %q", entry)
+		}
+
+		rules = append(rules, OidcRoleRule{Kind: kind, Value: strings.ToLower(value), Role: auth.Role(role)})
+	}
+
+	return rules, nil
+}
+
+type OidcServiceConfig struct {
+	Rules         []OidcRoleRule
+	GroupsClaim   string
+	AutoProvision bool
+}
+
+type OidcService struct {
+	provider    *oidc.Provider
+	stateRepo   data.OidcStateRepositoryInterface
+	adminRepo   data.AdminRepositoryInterface
+	authService AuthServiceInterface
+	cfg         OidcServiceConfig
+}
+
+type OidcServiceInterface interface {
+	// Start returns the URL to send the browser to and the state the
+	// caller must bind to that browser.
+	Start() (string, string, error)
+	// Callback completes the login. browserState is the state bound to the
+	// browser posting the callback and must match state.
+	Callback(code, state, browserState, ip, userAgent string) (*LoginResult, error)
+}
+
+func NewOidcService(
+	provider *oidc.Provider,
+	stateRepo data.OidcStateRepositoryInterface,
+	adminRepo data.AdminRepositoryInterface,
+	authService AuthServiceInterface,
+	cfg OidcServiceConfig,
+) OidcServiceInterface {
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	return &OidcService{provider, stateRepo, adminRepo, authService, cfg}
+}
+
+// Start creates the state, nonce and PKCE verifier for a new login and
+// returns the URL to send the browser to along with the state.
+func (s *OidcService) Start() (string, string, error) {
+	state, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", "", errors.NewInternalError("failed to start sso login")
+	}
+	nonce, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", "", errors.NewInternalError("failed to start sso login")
+	}
+	verifier, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", "", errors.NewInternalError("failed to start sso login")
+	}
+
+	err = s.stateRepo.Create(&data.OidcState{
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(OidcStateTTL),
+	})
+	if err != nil {
+		return "", "", errors.NewInternalError("failed to start sso login")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	authURL, err := s.provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		log.Error().Err(err).Msg("OIDC discovery failed")
+		return "", "", errors.NewInternalError("identity provider unavailable")
+	}
+
+	return authURL, state, nil
+}
+
+func (s *OidcService) Callback(code, state, browserState, ip, userAgent string) (*LoginResult, error) {
+	if code == "" || state == "" {
+		return nil, errors.NewValidationError("code and state are required")
+	}
+
+	// A login finished in a different browser from the one that started
+	// it could sign the victim in as whoever started it.
+	if subtle.ConstantTimeCompare([]byte(state), []byte(browserState)) != 1 {
+		return nil, errors.NewAuthenticationError("sso login was started in another browser, start again")
+	}
+
+	loginState, err := s.stateRepo.Consume(state)
+	if err != nil {
+		return nil, errors.NewAuthenticationError("sso login expired, start again")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	claims, err := s.provider.Exchange(ctx, code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		log.Warn().Err(err).Msg("OIDC code exchange failed")
+		return nil, errors.NewAuthenticationError("sso login failed")
+	}
+
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+	if email == "" || (claims.EmailVerified != nil && !*claims.EmailVerified) {
+		return nil, errors.NewForbiddenError("identity provider did not return a verified email")
+	}
+
+	admin, err := s.matchAdmin(email, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.authService.IssueSession(admin, ip, userAgent)
+}
+
+// matchAdmin finds the admin with the same email or provisions one with the
+// mapped role. Existing admins keep the role assigned in the dashboard.
+func (s *OidcService) matchAdmin(email string, claims *oidc.IDTokenClaims) (*data.Admin, error) {
+	admin, err := s.adminRepo.FindByEmail(email)
+	if err == nil {
+		if !admin.Active() {
+			return nil, errors.NewForbiddenError("admin account is not active")
+		}
+		return admin, nil
+	}
+
+	role, ok := s.mapRole(email, claims.Strings(s.cfg.GroupsClaim))
+	if !ok || !s.cfg.AutoProvision {
+		return nil, errors.NewForbiddenError("no admin account for this identity")
+	}
+
+	// SSO admins never log in with a password, so store an unguessable one.
+	randomPassword, err := GenerateOpaqueToken()
+	if err != nil {
+		return nil, errors.NewInternalError("failed to provision admin")
+	}
+	hashedPassword, err := HashPassword(randomPassword)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to provision admin")
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = email
+	}
+
+	admin = &data.Admin{
+		Email:    email,
+		Password: hashedPassword,
+		Name:     name,
+		Role:     string(role),
+		Verified: true,
+	}
+	if err := s.adminRepo.InserAdmin(admin); err != nil {
+		return nil, errors.NewInternalError("failed to provision admin")
+	}
+
+	admin, err = s.adminRepo.FindByEmail(email)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to provision admin")
+	}
+
+	log.Info().Str("email", email).Str("role", admin.Role).Msg("Provisioned admin from SSO")
+
+	return admin, nil
+}
+
+// mapRole returns the highest role granted by any matching rule.
+func (s *OidcService) mapRole(email string, groups []string) (auth.Role, bool) {
+	_, domain, _ := strings.Cut(email, "@")
+
+	groupSet := make(map[string]struct{}, len(groups))
+	for _, group := range groups {
+		groupSet[strings.ToLower(group)] = struct{}{}
+	}
+
+	var best auth.Role
+	matched := false
+	for _, rule := range s.cfg.Rules {
+		hit := false
+		switch rule.Kind {
+		case "domain":
+			hit = domain == rule.Value
+		case "group":
+			_, hit = groupSet[rule.Value]
+		}
+
+		if hit && (!matched || rule.Role.Outranks(best)) {
+			best = rule.Role
+			matched = true
+		}
+	}
+
+	return best, matched
+}
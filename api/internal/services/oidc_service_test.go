@@ -0,0 +1,68 @@
+package services
+
+import (
+	"database/sql"
+	stdErrors "errors"
+	"testing"
+
+	"github.com/valu/vemeet-admin-api/internal/data"
+	"github.com/valu/vemeet-admin-api/internal/errors"
+	"github.com/valu/vemeet-admin-api/internal/oidc"
+)
+
+// testOidcStateRepo hands out every stored state once, like the DELETE ...
+// RETURNING in the real repository.
+type testOidcStateRepo struct {
+	states map[string]*data.OidcState
+}
+
+func (r *testOidcStateRepo) Create(state *data.OidcState) error {
+	r.states[state.State] = state
+	return nil
+}
+
+func (r *testOidcStateRepo) Consume(state string) (*data.OidcState, error) {
+	s, ok := r.states[state]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	delete(r.states, state)
+	return s, nil
+}
+
+func TestOidcCallbackState(t *testing.T) {
+	repo := &testOidcStateRepo{states: map[string]*data.OidcState{
+		"state-1": {State: "state-1", Nonce: "nonce-1", CodeVerifier: "verifier-1"},
+	}}
+	// Nothing listens on the issuer, so a callback that gets past the state
+	// checks fails at the code exchange.
+	provider := oidc.NewProvider(oidc.Config{IssuerURL: "http://127.0.0.1:1", ClientID: "admin-dashboard"})
+	service := NewOidcService(provider, repo, nil, nil, OidcServiceConfig{})
+
+	tests := []struct {
+		name         string
+		code         string
+		state        string
+		browserState string
+		wantMessage  string
+	}{
+		{name: "missing state", code: "code", wantMessage: "code and state are required"},
+		{name: "missing code", state: "state-1", browserState: "state-1", wantMessage: "code and state are required"},
+		{name: "no browser state", code: "code", state: "state-1", wantMessage: "sso login was started in another browser, start again"},
+		{name: "another browser's state", code: "code", state: "state-1", browserState: "state-2", wantMessage: "sso login was started in another browser, start again"},
+		{name: "unknown state", code: "code", state: "state-2", browserState: "state-2", wantMessage: "sso login expired, start again"},
+		{name: "matching state", code: "code", state: "state-1", browserState: "state-1", wantMessage: "sso login failed"},
+		{name: "state used twice", code: "code", state: "state-1", browserState: "state-1", wantMessage: "sso login expired, start again"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Callback(tt.code, tt.state, tt.browserState, "127.0.0.1", "test")
+
+			var appErr *errors.AppError
+			if !stdErrors.As(err, &appErr) || appErr.Message != tt.wantMessage {
+				t.Fatalf("error = %v, want %q", err, tt.wantMessage)
+			}
+		})
+	}
+}
@@ -1,9 +1,24 @@
 package services
 
 import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/valu/vemeet-admin-api/internal/data"
+	"github.com/valu/vemeet-admin-api/internal/errors"
+	"github.com/valu/vemeet-admin-api/internal/notifier"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	passwordResetTTL          = 30 * time.Minute
+	sessionRevokedReasonReset = "password_reset"
+)
+
 func HashPassword(password string) (string, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -15,3 +30,237 @@ func HashPassword(password string) (string, error) {
 func ComparePassword(hashedPassword string, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
+
+type PasswordPolicy struct {
+	MinLength   int
+	HistorySize int
+	denylist    map[string]struct{}
+}
+
+// NewPasswordPolicy loads the breached-password denylist, one password per
+// line, from denylistPath. An empty path disables the denylist.
+func NewPasswordPolicy(minLength, historySize int, denylistPath string) (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{
+		MinLength:   minLength,
+		HistorySize: historySize,
+		denylist:    make(map[string]struct{}),
+	}
+
+	if denylistPath == "" {
+		return policy, nil
+	}
+
+	f, err := os.Open(denylistPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open password denylist: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		policy.denylist[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read password denylist: %w", err)
+	}
+
+	return policy, nil
+}
+
+// Validate checks password against the policy. previousHashes holds the
+// hashes the new password must not match.
+func (p *PasswordPolicy) Validate(password string, previousHashes []string) error {
+	if len([]rune(password)) < p.MinLength {
+		return errors.NewValidationError(fmt.Sprintf("password must be at least %d characters", p.MinLength))
+	}
+
+	if _, found := p.denylist[strings.ToLower(password)]; found {
+		return errors.NewValidationError("password appears in a list of breached passwords")
+	}
+
+	for _, hash := range previousHashes {
+		if ComparePassword(hash, password) == nil {
+			return errors.NewValidationError("password was used recently")
+		}
+	}
+
+	return nil
+}
+
+type PasswordService struct {
+	passwordRepo   data.PasswordRepositoryInterface
+	adminRepo      data.AdminRepositoryInterface
+	sessionService SessionServiceInterface
+	loginGuard     LoginGuardServiceInterface
+	notifier       notifier.Notifier
+	policy         *PasswordPolicy
+}
+
+type PasswordServiceInterface interface {
+	ChangePassword(adminID int64, currentSessionID, currentPassword, newPassword string) error
+	RequestReset(email, ip string) error
+	// ResetPassword returns the ID of the admin whose password was reset.
+	ResetPassword(token, newPassword string) (int64, error)
+}
+
+func NewPasswordService(
+	passwordRepo data.PasswordRepositoryInterface,
+	adminRepo data.AdminRepositoryInterface,
+	sessionService SessionServiceInterface,
+	loginGuard LoginGuardServiceInterface,
+	notifier notifier.Notifier,
+	policy *PasswordPolicy,
+) PasswordServiceInterface {
+	return &PasswordService{passwordRepo, adminRepo, sessionService, loginGuard, notifier, policy}
+}
+
+func (s *PasswordService) ChangePassword(adminID int64, currentSessionID, currentPassword, newPassword string) error {
+	admin, err := s.adminRepo.FindById(adminID)
+	if err != nil {
+		return errors.NewNotFoundError("admin not found")
+	}
+
+	if ComparePassword(admin.Password, currentPassword) != nil {
+		return errors.NewAuthenticationError("current password is incorrect")
+	}
+
+	if err := s.setPassword(admin, newPassword); err != nil {
+		return err
+	}
+
+	if _, err := s.sessionService.RevokeOtherSessions(adminID, currentSessionID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RequestReset issues a reset token and hands it to the notifier. It
+// succeeds silently for unknown emails so the endpoint cannot be used to
+// discover accounts. Requests are throttled per email and per IP so the
+// endpoint cannot be used to flood anyone's inbox.
+func (s *PasswordService) RequestReset(email, ip string) error {
+	if err := s.loginGuard.CheckResetRequest(email, ip); err != nil {
+		return err
+	}
+
+	admin, err := s.adminRepo.FindByEmail(strings.ToLower(strings.TrimSpace(email)))
+	if err != nil || !admin.Active() {
+		return nil
+	}
+
+	token, err := GenerateOpaqueToken()
+	if err != nil {
+		return errors.NewInternalError("failed to generate reset token")
+	}
+
+	reset := &data.PasswordReset{
+		AdminID:     admin.ID,
+		TokenHash:   HashOpaqueToken(token),
+		RequestedIP: ip,
+		ExpiresAt:   time.Now().Add(passwordResetTTL),
+	}
+	if err := s.passwordRepo.CreateReset(reset); err != nil {
+		return errors.NewInternalError("failed to create reset token")
+	}
+
+	err = s.notifier.Send(notifier.Message{
+		To:      admin.Email,
+		Subject: "Reset your Vemeet admin password",
+		Body:    "Use the reset token to choose a new password. It expires in 30 minutes.",
+		Data: map[string]string{
+			"token":      token,
+			"expires_at": reset.ExpiresAt.Format(time.RFC3339),
+		},
+	})
+	if err != nil {
+		log.Error().Err(err).Int64("admin_id", admin.ID).Msg("Failed to deliver password reset")
+	}
+
+	return nil
+}
+
+func (s *PasswordService) ResetPassword(token, newPassword string) (int64, error) {
+	reset, err := s.passwordRepo.FindResetByTokenHash(HashOpaqueToken(token))
+	if err != nil || reset.UsedAt != nil || reset.ExpiresAt.Before(time.Now()) {
+		return 0, errors.NewValidationError("reset token is invalid or expired")
+	}
+
+	admin, err := s.adminRepo.FindById(reset.AdminID)
+	if err != nil {
+		return 0, errors.NewValidationError("reset token is invalid or expired")
+	}
+
+	previous, err := s.previousHashes(admin)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.policy.Validate(newPassword, previous); err != nil {
+		return 0, err
+	}
+
+	hashedPassword, err := HashPassword(newPassword)
+	if err != nil {
+		return 0, errors.NewInternalError("password not hashed")
+	}
+
+	// The token is only used up together with the password change, so a
+	// failed store leaves it valid for another try.
+	consumed, err := s.passwordRepo.ResetPassword(reset.ID, admin.ID, hashedPassword)
+	if err != nil {
+		return 0, errors.NewInternalError("failed to reset password")
+	}
+	if !consumed {
+		return 0, errors.NewValidationError("reset token is invalid or expired")
+	}
+
+	if _, err := s.sessionService.RevokeAllSessions(admin.ID, sessionRevokedReasonReset); err != nil {
+		return 0, err
+	}
+
+	return admin.ID, nil
+}
+
+func (s *PasswordService) setPassword(admin *data.Admin, password string) error {
+	previous, err := s.previousHashes(admin)
+	if err != nil {
+		return err
+	}
+
+	if err := s.policy.Validate(password, previous); err != nil {
+		return err
+	}
+
+	return s.storePassword(admin.ID, password)
+}
+
+func (s *PasswordService) storePassword(adminID int64, password string) error {
+	hashedPassword, err := HashPassword(password)
+	if err != nil {
+		return errors.NewInternalError("password not hashed")
+	}
+
+	if err := s.passwordRepo.SetPassword(adminID, hashedPassword); err != nil {
+		return errors.NewInternalError("failed to update password")
+	}
+
+	return nil
+}
+
+func (s *PasswordService) previousHashes(admin *data.Admin) ([]string, error) {
+	hashes := []string{admin.Password}
+	if s.policy.HistorySize <= 0 {
+		return hashes, nil
+	}
+
+	history, err := s.passwordRepo.FindHistory(admin.ID, s.policy.HistorySize)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to check password history")
+	}
+
+	return append(hashes, history...), nil
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"database/sql"
+	stdErrors "errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/valu/vemeet-admin-api/internal/data"
+	"github.com/valu/vemeet-admin-api/internal/errors"
+)
+
+const maxReportImages = 10
+
+type ReportService struct {
+	reportRepo     data.ReportRepositoryInterface
+	userRepo       data.UserRepositoryInterface
+	blockedService BlockedServiceInterface
+	uow            data.UnitOfWorkInterface
+}
+
+type ReportInput struct {
+	ReporterID     int64
+	ReportedUserID int64
+	Category       string
+	Details        string
+	ImageIDs       []int64
+}
+
+// ResolveReportInput closes a report. With IncludeDuplicates every pending
+// report against the same user is closed too. Block, when set, bans the
+// reported user in the same transaction; its UserID is filled in from the
+// report.
+type ResolveReportInput struct {
+	ReportID          int64
+	Outcome           string
+	Note              string
+	ResolvedBy        *int64
+	IncludeDuplicates bool
+	Block             *BlockInput
+}
+
+// ReportResolution lists the reports a resolution closed and, when it
+// banned the user, the outcome of that ban.
+type ReportResolution struct {
+	Reports []*data.Report
+	Block   *BlockResult
+}
+
+type ReportServiceInterface interface {
+	SubmitReport(input ReportInput) (*data.Report, error)
+	GetReport(id int64) (*data.Report, error)
+	GetReports(page int64, limit int64, sort, order string, filter data.ReportFilter) (*data.ReportPagination, error)
+	GetQueue(page int64, limit int64, sort, order, category string) (*data.ReportQueuePagination, error)
+	StartReview(id int64, reviewerID int64) (*data.Report, error)
+	ResolveReport(input ResolveReportInput) (*ReportResolution, error)
+}
+
+func NewReportService(
+	reportRepo data.ReportRepositoryInterface,
+	userRepo data.UserRepositoryInterface,
+	blockedService BlockedServiceInterface,
+	uow data.UnitOfWorkInterface,
+) ReportServiceInterface {
+	return &ReportService{reportRepo, userRepo, blockedService, uow}
+}
+
+func (s *ReportService) SubmitReport(input ReportInput) (*data.Report, error) {
+	if !slices.Contains(data.ReportCategories, input.Category) {
+		return nil, errors.NewValidationError("category must be one of " + strings.Join(data.ReportCategories, ", "))
+	}
+	if input.ReporterID == input.ReportedUserID {
+		return nil, errors.NewValidationError("users cannot report themselves")
+	}
+
+	if _, err := s.userRepo.FindById(input.ReporterID); err != nil {
+		return nil, errors.NewNotFoundError("reporter not found")
+	}
+	if _, err := s.userRepo.FindById(input.ReportedUserID); err != nil {
+		return nil, errors.NewNotFoundError("reported user not found")
+	}
+
+	imageIDs := slices.Compact(slices.Sorted(slices.Values(input.ImageIDs)))
+	if len(imageIDs) > maxReportImages {
+		return nil, errors.NewValidationError("a report can reference at most " + strconv.Itoa(maxReportImages) + " images")
+	}
+
+	report := &data.Report{
+		ReporterID:     input.ReporterID,
+		ReportedUserID: input.ReportedUserID,
+		Category:       input.Category,
+		Details:        strings.TrimSpace(input.Details),
+	}
+	if err := s.reportRepo.Create(report, imageIDs); err != nil {
+		if stdErrors.Is(err, data.ErrReportImageNotFound) {
+			return nil, errors.NewValidationError("image_ids reference an image that does not exist")
+		}
+		return nil, errors.NewInternalError("failed to submit report")
+	}
+
+	return report, nil
+}
+
+// GetReport returns a report with its evidence images and the reporter's
+// current trust score.
+func (s *ReportService) GetReport(id int64) (*data.Report, error) {
+	report, err := s.findReport(id)
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := s.reportRepo.FindImages(id)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to get report images")
+	}
+	report.Images = images
+
+	trust, err := s.reportRepo.ReporterTrust(report.ReporterID)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to get reporter trust")
+	}
+	report.ReporterTrust = &trust
+
+	return report, nil
+}
+
+func (s *ReportService) GetReports(page int64, limit int64, sort, order string, filter data.ReportFilter) (*data.ReportPagination, error) {
+	switch filter.Status {
+	case "", data.ReportStatusOpen, data.ReportStatusInReview, data.ReportStatusActioned, data.ReportStatusDismissed:
+	default:
+		return nil, errors.NewValidationError("status must be open, in_review, actioned or dismissed")
+	}
+
+	reports, err := s.reportRepo.FindAll(page, limit, sort, order, filter)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to get reports")
+	}
+
+	return reports, nil
+}
+
+// GetQueue lists pending reports grouped by reported user, highest
+// priority first by default.
+func (s *ReportService) GetQueue(page int64, limit int64, sort, order, category string) (*data.ReportQueuePagination, error) {
+	queue, err := s.reportRepo.FindQueue(page, limit, sort, order, category)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to get report queue")
+	}
+
+	return queue, nil
+}
+
+func (s *ReportService) StartReview(id int64, reviewerID int64) (*data.Report, error) {
+	if _, err := s.findReport(id); err != nil {
+		return nil, err
+	}
+
+	report, err := s.reportRepo.StartReview(id, reviewerID)
+	if err != nil {
+		if stdErrors.Is(err, sql.ErrNoRows) {
+			return nil, errors.NewValidationError("report is already resolved")
+		}
+		return nil, errors.NewInternalError("failed to start review")
+	}
+
+	return report, nil
+}
+
+// ResolveReport marks a report actioned or dismissed. When input.Block is
+// set the reported user is banned through the ban service and the reports
+// are closed in the same transaction, linked to the resulting ban.
+func (s *ReportService) ResolveReport(input ResolveReportInput) (*ReportResolution, error) {
+	if input.Outcome != data.ReportStatusActioned && input.Outcome != data.ReportStatusDismissed {
+		return nil, errors.NewValidationError("outcome must be actioned or dismissed")
+	}
+	if input.Block != nil && input.Outcome != data.ReportStatusActioned {
+		return nil, errors.NewValidationError("only an actioned report can block the user")
+	}
+
+	note := strings.TrimSpace(input.Note)
+	if note == "" {
+		return nil, errors.NewValidationError("note is required")
+	}
+
+	report, err := s.findReport(input.ReportID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReportResolution{}
+	resolve := func(repos *data.TxRepositories, blockedID *int64) error {
+		locked, err := repos.Reports.FindById(report.ID)
+		if err != nil {
+			return err
+		}
+		if locked.Status != data.ReportStatusOpen && locked.Status != data.ReportStatusInReview {
+			return errors.NewValidationError("report is already resolved")
+		}
+
+		ids := []int64{report.ID}
+		if input.IncludeDuplicates {
+			pending, err := repos.Reports.FindPendingByUser(report.ReportedUserID)
+			if err != nil {
+				return err
+			}
+			for _, duplicate := range pending {
+				if duplicate.ID != report.ID {
+					ids = append(ids, duplicate.ID)
+				}
+			}
+		}
+
+		result.Reports, err = repos.Reports.Resolve(ids, input.Outcome, input.ResolvedBy, note, blockedID)
+		return err
+	}
+
+	if input.Block == nil {
+		err := s.uow.Do(func(repos *data.TxRepositories) error {
+			return resolve(repos, nil)
+		})
+		if err != nil {
+			return nil, asServiceError(err, "failed to resolve report")
+		}
+		return result, nil
+	}
+
+	block := *input.Block
+	block.UserID = report.ReportedUserID
+	block.BlockedBy = input.ResolvedBy
+	result.Block, err = s.blockedService.BlockUserWith(block, func(repos *data.TxRepositories, ban *BlockResult) error {
+		return resolve(repos, &ban.Blocked.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *ReportService) findReport(id int64) (*data.Report, error) {
+	report, err := s.reportRepo.FindById(id)
+	if err != nil {
+		if stdErrors.Is(err, sql.ErrNoRows) {
+			return nil, errors.NewNotFoundError("report not found")
+		}
+		return nil, errors.NewInternalError("failed to get report")
+	}
+	return report, nil
+}
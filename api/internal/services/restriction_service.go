@@ -0,0 +1,225 @@
+package services
+
+import (
+	"database/sql"
+	stdErrors "errors"
+	"strings"
+	"time"
+
+	"github.com/valu/vemeet-admin-api/internal/data"
+	"github.com/valu/vemeet-admin-api/internal/errors"
+)
+
+type RestrictionService struct {
+	restrictionRepo data.RestrictionRepositoryInterface
+	banReasonRepo   data.BanReasonRepositoryInterface
+	uow             data.UnitOfWorkInterface
+}
+
+// RestrictInput describes a new restriction. When ExpiresAt is nil the
+// reason code's default duration applies, unless Permanent is set.
+type RestrictInput struct {
+	UserID     int64
+	Mode       string
+	ReasonCode string
+	Notes      string
+	ExpiresAt  *time.Time
+	Permanent  bool
+	CreatedBy  *int64
+}
+
+// RestrictionResult reports whether a restriction changed. Applied is false
+// when Restrict found one of the same mode already in force, or when
+// LiftRestriction found it already closed.
+type RestrictionResult struct {
+	Restriction *data.UserRestriction
+	Applied     bool
+}
+
+type RestrictionServiceInterface interface {
+	Restrict(input RestrictInput) (*RestrictionResult, error)
+	RestrictUsers(inputs []RestrictInput) ([]*RestrictionResult, error)
+	LiftRestriction(id int64, liftedBy *int64, reason string) (*RestrictionResult, error)
+	GetUserRestrictions(userID int64) ([]*data.UserRestriction, error)
+}
+
+func NewRestrictionService(
+	restrictionRepo data.RestrictionRepositoryInterface,
+	banReasonRepo data.BanReasonRepositoryInterface,
+	uow data.UnitOfWorkInterface,
+) RestrictionServiceInterface {
+	return &RestrictionService{restrictionRepo, banReasonRepo, uow}
+}
+
+// Restrict applies an enforcement mode short of a ban and forces the users
+// flag it controls. Like BlockUser it is idempotent: a restriction of the
+// same mode already in force is returned instead of stacking a second one.
+func (s *RestrictionService) Restrict(input RestrictInput) (*RestrictionResult, error) {
+	results, err := s.RestrictUsers([]RestrictInput{input})
+	if err != nil {
+		return nil, err
+	}
+
+	return results[0], nil
+}
+
+// RestrictUsers applies several restrictions in one transaction, following
+// the same rules as Restrict for each. Any failure rolls back the whole
+// batch.
+func (s *RestrictionService) RestrictUsers(inputs []RestrictInput) ([]*RestrictionResult, error) {
+	now := time.Now()
+	plans := make([]*restrictPlan, len(inputs))
+	for i, input := range inputs {
+		plan, err := s.planRestrict(input, now)
+		if err != nil {
+			return nil, err
+		}
+		plans[i] = plan
+	}
+
+	results := make([]*RestrictionResult, len(inputs))
+	err := s.uow.Do(func(repos *data.TxRepositories) error {
+		for i, plan := range plans {
+			results[i] = &RestrictionResult{}
+			if err := plan.apply(repos, now, results[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, asServiceError(err, "failed to restrict user")
+	}
+
+	return results, nil
+}
+
+// restrictPlan is a validated restriction request, ready to apply inside a
+// unit of work.
+type restrictPlan struct {
+	input     RestrictInput
+	reason    *data.BanReason
+	expiresAt *time.Time
+}
+
+func (s *RestrictionService) planRestrict(input RestrictInput, now time.Time) (*restrictPlan, error) {
+	if !data.ValidRestrictionMode(input.Mode) {
+		return nil, errors.NewValidationError("mode must be shadow_ban, inbox_lock or swiper_restriction")
+	}
+
+	reason, err := activeBanReason(s.banReasonRepo, input.ReasonCode)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := input.ExpiresAt
+	switch {
+	case input.Permanent:
+		expiresAt = nil
+	case expiresAt == nil:
+		expiresAt = reason.DefaultExpiry(now)
+	case !expiresAt.After(now):
+		return nil, errors.NewValidationError("expires_at must be in the future")
+	}
+
+	return &restrictPlan{input, reason, expiresAt}, nil
+}
+
+func (p *restrictPlan) apply(repos *data.TxRepositories, now time.Time, result *RestrictionResult) error {
+	input := p.input
+
+	current, err := repos.Restrictions.LockFlag(input.UserID, input.Mode)
+	if err != nil {
+		if stdErrors.Is(err, sql.ErrNoRows) {
+			return errors.NewNotFoundError("user not found")
+		}
+		return err
+	}
+
+	open, err := repos.Restrictions.FindOpen(input.UserID, input.Mode)
+	if err != nil {
+		return err
+	}
+	for _, restriction := range open {
+		if restriction.ExpiresAt == nil || restriction.ExpiresAt.After(now) {
+			result.Restriction = restriction
+			return nil
+		}
+	}
+
+	// Expired restrictions the sweep has not closed yet still hold the
+	// flag, so the value to restore is the one they recorded.
+	previous := current
+	if len(open) > 0 {
+		previous = open[0].PreviousValue
+	}
+
+	result.Restriction = &data.UserRestriction{
+		UserID:        input.UserID,
+		Mode:          input.Mode,
+		ReasonCode:    &p.reason.Code,
+		Reason:        strings.TrimSpace(input.Notes),
+		PreviousValue: previous,
+		CreatedBy:     input.CreatedBy,
+		ExpiresAt:     p.expiresAt,
+	}
+	result.Applied = true
+	return repos.Restrictions.Insert(result.Restriction)
+}
+
+// LiftRestriction ends a restriction early. The users flag goes back to
+// its previous value once no other restriction of the mode is open.
+func (s *RestrictionService) LiftRestriction(id int64, liftedBy *int64, reason string) (*RestrictionResult, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.NewValidationError("reason is required")
+	}
+
+	result := &RestrictionResult{}
+	err := s.uow.Do(func(repos *data.TxRepositories) error {
+		restriction, err := repos.Restrictions.FindById(id)
+		if err != nil {
+			if stdErrors.Is(err, sql.ErrNoRows) {
+				return errors.NewNotFoundError("restriction not found")
+			}
+			return err
+		}
+		if restriction.LiftedAt != nil {
+			result.Restriction = restriction
+			return nil
+		}
+
+		// The user row is locked before the restriction row, the order
+		// Restrict and the expiry sweep use as well.
+		if _, err := repos.Restrictions.LockFlag(restriction.UserID, restriction.Mode); err != nil {
+			return err
+		}
+
+		lifted, err := repos.Restrictions.Lift(id, liftedBy, reason)
+		if stdErrors.Is(err, sql.ErrNoRows) {
+			// The sweep lifted it while this waited for the user row.
+			result.Restriction, err = repos.Restrictions.FindById(id)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		result.Restriction = lifted
+		result.Applied = true
+		return nil
+	})
+	if err != nil {
+		return nil, asServiceError(err, "failed to lift restriction")
+	}
+
+	return result, nil
+}
+
+func (s *RestrictionService) GetUserRestrictions(userID int64) ([]*data.UserRestriction, error) {
+	restrictions, err := s.restrictionRepo.FindByUser(userID)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to get restriction history")
+	}
+
+	return restrictions, nil
+}
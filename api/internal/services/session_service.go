@@ -0,0 +1,98 @@
+package services
+
+import (
+	"time"
+
+	"github.com/valu/vemeet-admin-api/internal/auth"
+	"github.com/valu/vemeet-admin-api/internal/data"
+	"github.com/valu/vemeet-admin-api/internal/errors"
+)
+
+const revocationCacheTTL = 30 * time.Second
+
+type SessionService struct {
+	sessionRepo data.SessionRepositoryInterface
+	cache       *auth.RevocationCache
+}
+
+type SessionServiceInterface interface {
+	ListSessions(adminID int64) ([]*data.Session, error)
+	RevokeSession(adminID int64, sessionID string) error
+	RevokeOtherSessions(adminID int64, currentSessionID string) (int, error)
+	RevokeAllSessions(adminID int64, reason string) (int, error)
+	IsSessionActive(sessionID string) bool
+	MarkRevoked(sessionIDs ...string)
+}
+
+func NewSessionService(sessionRepo data.SessionRepositoryInterface) SessionServiceInterface {
+	return &SessionService{
+		sessionRepo: sessionRepo,
+		cache:       auth.NewRevocationCache(revocationCacheTTL),
+	}
+}
+
+func (s *SessionService) ListSessions(adminID int64) ([]*data.Session, error) {
+	sessions, err := s.sessionRepo.FindActiveByAdminId(adminID)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to get sessions")
+	}
+
+	return sessions, nil
+}
+
+func (s *SessionService) RevokeSession(adminID int64, sessionID string) error {
+	session, err := s.sessionRepo.FindById(sessionID)
+	if err != nil || session.AdminID != adminID {
+		return errors.NewNotFoundError("session not found")
+	}
+
+	if err := s.sessionRepo.Revoke(sessionID, data.SessionRevokedReasonLogout); err != nil {
+		return errors.NewInternalError("failed to revoke session")
+	}
+	s.cache.Set(sessionID, true)
+
+	return nil
+}
+
+func (s *SessionService) RevokeOtherSessions(adminID int64, currentSessionID string) (int, error) {
+	return s.revokeAll(adminID, currentSessionID, data.SessionRevokedReasonLogoutOthers)
+}
+
+func (s *SessionService) RevokeAllSessions(adminID int64, reason string) (int, error) {
+	return s.revokeAll(adminID, "", reason)
+}
+
+func (s *SessionService) revokeAll(adminID int64, exceptID, reason string) (int, error) {
+	ids, err := s.sessionRepo.RevokeAllForAdmin(adminID, exceptID, reason)
+	if err != nil {
+		return 0, errors.NewInternalError("failed to revoke sessions")
+	}
+
+	s.MarkRevoked(ids...)
+
+	return len(ids), nil
+}
+
+// MarkRevoked records sessions revoked outside this service, so their access
+// tokens are rejected right away instead of once the cache entry expires.
+func (s *SessionService) MarkRevoked(sessionIDs ...string) {
+	for _, id := range sessionIDs {
+		s.cache.Set(id, true)
+	}
+}
+
+func (s *SessionService) IsSessionActive(sessionID string) bool {
+	if revoked, ok := s.cache.Get(sessionID); ok {
+		return !revoked
+	}
+
+	session, err := s.sessionRepo.FindById(sessionID)
+	if err != nil {
+		return false
+	}
+
+	active := session.RevokedAt == nil && session.ExpiresAt.After(time.Now())
+	s.cache.Set(sessionID, !active)
+
+	return active
+}
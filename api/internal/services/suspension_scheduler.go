@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/valu/vemeet-admin-api/internal/data"
+)
+
+const suspensionSweepBatchSize = 100
+
+// SuspensionScheduler periodically lifts temporary bans and restrictions
+// whose expiry has passed. Every replica runs one; the repository takes a Postgres advisory
+// lock so only one of them sweeps at a time.
+type SuspensionScheduler struct {
+	blockedRepo     data.BlockedRepositoryInterface
+	restrictionRepo data.RestrictionRepositoryInterface
+	auditService    AuditServiceInterface
+	interval        time.Duration
+}
+
+func NewSuspensionScheduler(
+	blockedRepo data.BlockedRepositoryInterface,
+	restrictionRepo data.RestrictionRepositoryInterface,
+	auditService AuditServiceInterface,
+	interval time.Duration,
+) *SuspensionScheduler {
+	return &SuspensionScheduler{blockedRepo, restrictionRepo, auditService, interval}
+}
+
+// Run sweeps on every tick until ctx is cancelled.
+func (s *SuspensionScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	log.Info().Dur("interval", s.interval).Msg("Suspension scheduler started")
+
+	for {
+		s.Sweep()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep lifts expired bans and restrictions.
+func (s *SuspensionScheduler) Sweep() {
+	s.sweepBans()
+	s.sweepRestrictions()
+}
+
+// sweepBans lifts expired bans in batches until none are left.
+func (s *SuspensionScheduler) sweepBans() {
+	for {
+		lifted, err := s.blockedRepo.LiftExpired(suspensionSweepBatchSize)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to lift expired suspensions")
+			return
+		}
+
+		for _, blocked := range lifted {
+			before := *blocked
+			before.LiftedAt = nil
+			before.LiftReason = nil
+
+			s.auditService.Record(AuditActor{RequestID: "suspension-scheduler"}, AuditActionBlockedExpire,
+				AuditTargetBlocked, strconv.FormatInt(blocked.ID, 10), before, blocked)
+		}
+
+		if len(lifted) > 0 {
+			log.Info().Int("count", len(lifted)).Msg("Lifted expired suspensions")
+		}
+		if len(lifted) < suspensionSweepBatchSize {
+			return
+		}
+	}
+}
+
+// sweepRestrictions lifts expired restrictions in batches until none are
+// left.
+func (s *SuspensionScheduler) sweepRestrictions() {
+	for {
+		lifted, err := s.restrictionRepo.LiftExpired(suspensionSweepBatchSize)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to lift expired restrictions")
+			return
+		}
+
+		for _, restriction := range lifted {
+			before := *restriction
+			before.LiftedAt = nil
+			before.LiftReason = nil
+
+			s.auditService.Record(AuditActor{RequestID: "suspension-scheduler"}, AuditActionRestrictionExpire,
+				AuditTargetRestriction, strconv.FormatInt(restriction.ID, 10), before, restriction)
+		}
+
+		if len(lifted) > 0 {
+			log.Info().Int("count", len(lifted)).Msg("Lifted expired restrictions")
+		}
+		if len(lifted) < suspensionSweepBatchSize {
+			return
+		}
+	}
+}
@@ -1,23 +1,72 @@
 package services
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/valu/vemeet-admin-api/internal/data"
 	"github.com/valu/vemeet-admin-api/internal/errors"
 )
 
 type UserService struct {
-	userRepo data.UserRepositoryInterface
+	userRepo        data.UserRepositoryInterface
+	blockedRepo     data.BlockedRepositoryInterface
+	restrictionRepo data.RestrictionRepositoryInterface
 }
 
 type UserServiceInterface interface {
 	GetUserByUsername(username string) (*data.User, error)
 	GetUserById(id int64) (*data.User, error)
-	GetUsers(page int64, limit int64, sort, order, search string) (*data.UserPagination, error)
-	ToggleBlockUser(id int64) (bool, error)
+	GetUsers(page int64, limit int64, sort, order string, filter data.UserFilter) (*data.UserPagination, error)
+	// ExportUsersGeoJSON places up to limit matching users with city
+	// coordinates on a map.
+	ExportUsersGeoJSON(sort, order string, filter data.UserFilter, limit int64) (*UserFeatureCollection, error)
+}
+
+// MaxUserGeoExport bounds the features in one GeoJSON export.
+const MaxUserGeoExport = 5000
+
+// UserFeatureCollection is a GeoJSON FeatureCollection of users placed at
+// their city. Truncated is set when more users matched than were exported.
+type UserFeatureCollection struct {
+	Type      string         `json:"type"`
+	Features  []*UserFeature `json:"features"`
+	Truncated bool           `json:"truncated"`
 }
 
-func NewUserService(userRepo data.UserRepositoryInterface) UserServiceInterface {
-	return &UserService{userRepo}
+type UserFeature struct {
+	Type       string                `json:"type"`
+	ID         int64                 `json:"id"`
+	Geometry   GeoJSONPoint          `json:"geometry"`
+	Properties UserFeatureProperties `json:"properties"`
+}
+
+// GeoJSONPoint holds its coordinates as longitude, latitude.
+type GeoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+type UserFeatureProperties struct {
+	ID             int64    `json:"id"`
+	Username       string   `json:"username"`
+	Name           string   `json:"name,omitempty"`
+	Gender         string   `json:"gender,omitempty"`
+	CountryIsoCode string   `json:"country_iso_code,omitempty"`
+	CityName       string   `json:"city_name,omitempty"`
+	Verified       bool     `json:"verified"`
+	Blocked        bool     `json:"blocked"`
+	ShadowBanned   bool     `json:"shadow_banned"`
+	CreatedAt      string   `json:"created_at"`
+	DistanceKm     *float64 `json:"distance_km,omitempty"`
+}
+
+func NewUserService(
+	userRepo data.UserRepositoryInterface,
+	blockedRepo data.BlockedRepositoryInterface,
+	restrictionRepo data.RestrictionRepositoryInterface,
+) UserServiceInterface {
+	return &UserService{userRepo, blockedRepo, restrictionRepo}
 }
 
 func (s *UserService) GetUserByUsername(username string) (*data.User, error) {
@@ -29,17 +78,35 @@ func (s *UserService) GetUserByUsername(username string) (*data.User, error) {
 	return user, nil
 }
 
+// GetUserById returns the user together with every ban and restriction
+// currently in force against them.
 func (s *UserService) GetUserById(id int64) (*data.User, error) {
 	user, err := s.userRepo.FindById(id)
 	if err != nil {
 		return nil, errors.NewNotFoundError("user not found")
 	}
 
+	bans, err := s.blockedRepo.FindActiveByUser(id)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to get active bans")
+	}
+
+	restrictions, err := s.restrictionRepo.FindActiveByUser(id)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to get active restrictions")
+	}
+
+	user.Enforcement = &data.UserEnforcement{Bans: bans, Restrictions: restrictions}
 	return user, nil
 }
 
-func (s *UserService) GetUsers(page int64, limit int64, sort, order, search string) (*data.UserPagination, error) {
-	users, err := s.userRepo.FindAll(page, limit, sort, order, search)
+func (s *UserService) GetUsers(page int64, limit int64, sort, order string, filter data.UserFilter) (*data.UserPagination, error) {
+	filter, err := normalizeUserFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := s.userRepo.FindAll(page, limit, sort, order, filter)
 	if err != nil {
 		return nil, errors.NewInternalError("failed to get users")
 	}
@@ -47,11 +114,127 @@ func (s *UserService) GetUsers(page int64, limit int64, sort, order, search stri
 	return users, nil
 }
 
-func (s *UserService) ToggleBlockUser(id int64) (bool, error) {
-	isBlocked, err := s.userRepo.ToggleUserBlock(id)
+func (s *UserService) ExportUsersGeoJSON(sort, order string, filter data.UserFilter, limit int64) (*UserFeatureCollection, error) {
+	if limit < 1 || limit > MaxUserGeoExport {
+		return nil, errors.NewValidationError("limit must be between 1 and " + strconv.Itoa(MaxUserGeoExport))
+	}
+
+	filter, err := normalizeUserFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// One extra row tells whether the export was cut short.
+	users, err := s.userRepo.FindLocated(sort, order, filter, limit+1)
 	if err != nil {
-		return false, errors.NewInternalError("failed to toggle block user")
+		return nil, errors.NewInternalError("failed to export users")
+	}
+
+	collection := &UserFeatureCollection{Type: "FeatureCollection", Features: make([]*UserFeature, 0, len(users))}
+	if int64(len(users)) > limit {
+		users = users[:limit]
+		collection.Truncated = true
 	}
 
-	return isBlocked, nil
+	for _, user := range users {
+		collection.Features = append(collection.Features, &UserFeature{
+			Type:     "Feature",
+			ID:       user.ID,
+			Geometry: GeoJSONPoint{Type: "Point", Coordinates: [2]float64{user.CityLng, user.CityLat}},
+			Properties: UserFeatureProperties{
+				ID:             user.ID,
+				Username:       user.Username,
+				Name:           user.Name,
+				Gender:         user.Gender,
+				CountryIsoCode: user.CountryIsoCode,
+				CityName:       user.CityName,
+				Verified:       user.Verified,
+				Blocked:        user.Blocked,
+				ShadowBanned:   user.ShadowBanned,
+				CreatedAt:      user.CreatedAt,
+				DistanceKm:     user.DistanceKm,
+			},
+		})
+	}
+
+	return collection, nil
+}
+
+// maxUserAge bounds the age filters; anything above it is a typo.
+const maxUserAge = 150
+
+// normalizeUserFilter checks the filter's ranges and brings genders and
+// country codes to the case they are matched in.
+func normalizeUserFilter(filter data.UserFilter) (data.UserFilter, error) {
+	for _, age := range []*int{filter.MinAge, filter.MaxAge} {
+		if age != nil && (*age < 0 || *age > maxUserAge) {
+			return filter, errors.NewValidationError("ages must be between 0 and " + strconv.Itoa(maxUserAge))
+		}
+	}
+	if filter.MinAge != nil && filter.MaxAge != nil && *filter.MinAge > *filter.MaxAge {
+		return filter, errors.NewValidationError("min_age must not be greater than max_age")
+	}
+
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && !filter.CreatedFrom.Before(*filter.CreatedTo) {
+		return filter, errors.NewValidationError("created_from must be before created_to")
+	}
+
+	genders := make([]string, len(filter.Genders))
+	for i, gender := range filter.Genders {
+		genders[i] = strings.ToLower(gender)
+	}
+	filter.Genders = genders
+
+	countries := make([]string, len(filter.CountryIsoCodes))
+	for i, code := range filter.CountryIsoCodes {
+		if len(code) != 2 || !isLetters(code) {
+			return filter, errors.NewValidationError("invalid country_iso_code " + code + ", expected two letters")
+		}
+		countries[i] = strings.ToUpper(code)
+	}
+	filter.CountryIsoCodes = countries
+
+	filter.CityName = strings.TrimSpace(filter.CityName)
+
+	if err := validateGeoFilter(filter); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// validateGeoFilter checks coordinates are on the globe. The comparisons
+// are written so that NaN fails them.
+func validateGeoFilter(filter data.UserFilter) error {
+	validLat := func(lat float64) bool { return lat >= -90 && lat <= 90 }
+	validLng := func(lng float64) bool { return lng >= -180 && lng <= 180 }
+
+	if radius := filter.Radius; radius != nil {
+		if !validLat(radius.Center.Lat) || !validLng(radius.Center.Lng) {
+			return errors.NewValidationError("lat must be between -90 and 90 and lng between -180 and 180")
+		}
+		if !(radius.Km > 0 && radius.Km <= data.MaxGeoRadiusKm) {
+			return errors.NewValidationError("radius_km must be greater than 0 and at most " +
+				strconv.FormatFloat(data.MaxGeoRadiusKm, 'f', 0, 64))
+		}
+	}
+
+	if box := filter.Box; box != nil {
+		if !validLat(box.MinLat) || !validLat(box.MaxLat) || !validLng(box.MinLng) || !validLng(box.MaxLng) {
+			return errors.NewValidationError("bbox latitudes must be between -90 and 90 and longitudes between -180 and 180")
+		}
+		if box.MinLat > box.MaxLat {
+			return errors.NewValidationError("bbox min_lat must not be greater than max_lat")
+		}
+	}
+
+	return nil
+}
+
+func isLetters(value string) bool {
+	for _, r := range value {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
 }
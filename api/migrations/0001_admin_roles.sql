@@ -0,0 +1,6 @@
+ALTER TABLE admin_users
+    ADD COLUMN IF NOT EXISTS role VARCHAR(32) NOT NULL DEFAULT 'viewer';
+
+ALTER TABLE admin_users
+    ADD CONSTRAINT admin_users_role_check
+    CHECK (role IN ('viewer', 'moderator', 'senior_moderator', 'superadmin'));
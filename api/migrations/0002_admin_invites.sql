@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS admin_invites (
+    id                BIGSERIAL PRIMARY KEY,
+    email             VARCHAR(255) NOT NULL,
+    role              VARCHAR(32) NOT NULL,
+    token_hash        CHAR(64) NOT NULL UNIQUE,
+    invited_by        BIGINT NOT NULL REFERENCES admin_users (id),
+    expires_at        TIMESTAMPTZ NOT NULL,
+    revoked_at        TIMESTAMPTZ,
+    redeemed_at       TIMESTAMPTZ,
+    redeemed_admin_id BIGINT REFERENCES admin_users (id),
+    redeemed_ip       VARCHAR(64),
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS admin_invites_email_idx ON admin_invites (email);
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS admin_sessions (
+    id             VARCHAR(32) PRIMARY KEY,
+    admin_id       BIGINT NOT NULL REFERENCES admin_users (id) ON DELETE CASCADE,
+    ip_address     VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent     TEXT NOT NULL DEFAULT '',
+    expires_at     TIMESTAMPTZ NOT NULL,
+    last_used_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at     TIMESTAMPTZ,
+    revoked_reason VARCHAR(64),
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS admin_sessions_admin_id_idx ON admin_sessions (admin_id);
+
+CREATE TABLE IF NOT EXISTS admin_refresh_tokens (
+    id          VARCHAR(32) PRIMARY KEY,
+    session_id  VARCHAR(32) NOT NULL REFERENCES admin_sessions (id) ON DELETE CASCADE,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    rotated_at  TIMESTAMPTZ,
+    replaced_by VARCHAR(32),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS admin_refresh_tokens_session_id_idx ON admin_refresh_tokens (session_id);
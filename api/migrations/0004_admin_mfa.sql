@@ -0,0 +1,14 @@
+ALTER TABLE admin_users
+    ADD COLUMN IF NOT EXISTS totp_secret    VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS totp_enabled   BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS admin_recovery_codes (
+    id         BIGSERIAL PRIMARY KEY,
+    admin_id   BIGINT NOT NULL REFERENCES admin_users (id) ON DELETE CASCADE,
+    code_hash  VARCHAR(72) NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS admin_recovery_codes_admin_id_idx ON admin_recovery_codes (admin_id);
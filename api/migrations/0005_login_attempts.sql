@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS admin_login_attempts (
+    id             BIGSERIAL PRIMARY KEY,
+    email          VARCHAR(255) NOT NULL,
+    admin_id       BIGINT REFERENCES admin_users (id) ON DELETE SET NULL,
+    ip_address     VARCHAR(64) NOT NULL,
+    user_agent     TEXT NOT NULL DEFAULT '',
+    success        BOOLEAN NOT NULL,
+    failure_reason VARCHAR(32),
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS admin_login_attempts_email_idx ON admin_login_attempts (email, created_at DESC);
+CREATE INDEX IF NOT EXISTS admin_login_attempts_ip_idx ON admin_login_attempts (ip_address, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS login_throttles (
+    key             VARCHAR(320) PRIMARY KEY,
+    failures        INT NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ NOT NULL,
+    locked_until    TIMESTAMPTZ
+);
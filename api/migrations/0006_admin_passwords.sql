@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS admin_password_history (
+    id            BIGSERIAL PRIMARY KEY,
+    admin_id      BIGINT NOT NULL REFERENCES admin_users (id) ON DELETE CASCADE,
+    password_hash VARCHAR(72) NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS admin_password_history_admin_id_idx ON admin_password_history (admin_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS admin_password_resets (
+    id           BIGSERIAL PRIMARY KEY,
+    admin_id     BIGINT NOT NULL REFERENCES admin_users (id) ON DELETE CASCADE,
+    token_hash   CHAR(64) NOT NULL UNIQUE,
+    requested_ip VARCHAR(64) NOT NULL DEFAULT '',
+    expires_at   TIMESTAMPTZ NOT NULL,
+    used_at      TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
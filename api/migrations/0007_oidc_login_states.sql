@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS oidc_login_states (
+    state         VARCHAR(64) PRIMARY KEY,
+    nonce         VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    expires_at    TIMESTAMPTZ NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
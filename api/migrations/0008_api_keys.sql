@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS admin_api_keys (
+    id           BIGSERIAL PRIMARY KEY,
+    admin_id     BIGINT NOT NULL REFERENCES admin_users (id) ON DELETE CASCADE,
+    name         VARCHAR(255) NOT NULL,
+    key_prefix   VARCHAR(16) NOT NULL,
+    key_hash     CHAR(64) NOT NULL UNIQUE,
+    scopes       TEXT[] NOT NULL,
+    allowed_ips  TEXT[] NOT NULL DEFAULT '{}',
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    last_used_ip VARCHAR(64),
+    revoked_at   TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS admin_api_keys_admin_id_idx ON admin_api_keys (admin_id);
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id          BIGSERIAL PRIMARY KEY,
+    admin_id    BIGINT REFERENCES admin_users (id),
+    api_key_id  BIGINT REFERENCES admin_api_keys (id),
+    action      VARCHAR(64) NOT NULL,
+    target_type VARCHAR(64) NOT NULL,
+    target_id   VARCHAR(64) NOT NULL,
+    before      JSON,
+    after       JSON,
+    request_id  VARCHAR(64) NOT NULL,
+    ip_address  VARCHAR(64) NOT NULL,
+    prev_hash   CHAR(64) NOT NULL,
+    hash        CHAR(64) NOT NULL UNIQUE,
+    created_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_admin_id_idx ON audit_log (admin_id, created_at);
+CREATE INDEX IF NOT EXISTS audit_log_target_idx ON audit_log (target_type, target_id, created_at);
+CREATE INDEX IF NOT EXISTS audit_log_action_idx ON audit_log (action, created_at);
+
+-- The log is append-only. Snapshots are stored as JSON rather than JSONB so
+-- the exact text that was hashed is preserved.
+CREATE OR REPLACE FUNCTION audit_log_immutable() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_immutable ON audit_log;
+CREATE TRIGGER audit_log_immutable
+    BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_immutable();
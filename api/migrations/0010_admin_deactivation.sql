@@ -0,0 +1,2 @@
+ALTER TABLE admin_users
+    ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ;
@@ -0,0 +1,11 @@
+ALTER TABLE blockeds
+    ADD COLUMN IF NOT EXISTS expires_at  TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS lifted_at   TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS lifted_by   BIGINT REFERENCES admin_users (id),
+    ADD COLUMN IF NOT EXISTS lift_reason TEXT;
+
+CREATE INDEX IF NOT EXISTS blockeds_pending_expiry_idx
+    ON blockeds (expires_at)
+    WHERE lifted_at IS NULL AND expires_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS blockeds_user_id_idx ON blockeds (user_id);
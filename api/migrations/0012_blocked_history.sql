@@ -0,0 +1,4 @@
+ALTER TABLE blockeds
+    ADD COLUMN IF NOT EXISTS blocked_by BIGINT REFERENCES admin_users (id);
+
+CREATE INDEX IF NOT EXISTS blockeds_user_history_idx ON blockeds (user_id, created_at);
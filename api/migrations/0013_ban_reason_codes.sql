@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS ban_reason_codes (
+    code                     VARCHAR(64) PRIMARY KEY,
+    label                    VARCHAR(255) NOT NULL,
+    description              TEXT NOT NULL DEFAULT '',
+    severity                 VARCHAR(16) NOT NULL,
+    default_duration_seconds BIGINT,
+    created_by               BIGINT REFERENCES admin_users (id),
+    retired_at               TIMESTAMPTZ,
+    created_at               TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT ban_reason_codes_severity_check CHECK (severity IN ('low', 'medium', 'high', 'critical')),
+    CONSTRAINT ban_reason_codes_duration_check CHECK (default_duration_seconds IS NULL OR default_duration_seconds > 0)
+);
+
+-- A NULL default duration means the ban is permanent.
+INSERT INTO ban_reason_codes (code, label, description, severity, default_duration_seconds) VALUES
+    ('spam', 'Spam', 'Unsolicited promotional or repetitive content', 'low', 86400),
+    ('harassment', 'Harassment', 'Abusive, threatening or bullying behaviour towards other users', 'high', 604800),
+    ('underage', 'Underage', 'User is below the minimum age', 'critical', NULL),
+    ('fake_profile', 'Fake profile', 'Impersonation or a profile that does not represent a real person', 'medium', 604800),
+    ('scam', 'Scam', 'Fraud, phishing or requests for money', 'critical', NULL),
+    ('inappropriate_content', 'Inappropriate content', 'Nudity, violence or other content against the guidelines', 'medium', 259200)
+ON CONFLICT (code) DO NOTHING;
+
+ALTER TABLE blockeds
+    ADD COLUMN IF NOT EXISTS reason_code VARCHAR(64) REFERENCES ban_reason_codes (code);
+
+CREATE INDEX IF NOT EXISTS blockeds_reason_code_idx ON blockeds (reason_code);
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS ban_escalation_steps (
+    reason_code      VARCHAR(64) NOT NULL REFERENCES ban_reason_codes (code),
+    step             INT NOT NULL,
+    action           VARCHAR(16) NOT NULL,
+    duration_seconds BIGINT,
+    PRIMARY KEY (reason_code, step),
+    CONSTRAINT ban_escalation_steps_action_check CHECK (action IN ('warning', 'suspension', 'permanent')),
+    CONSTRAINT ban_escalation_steps_duration_check CHECK (
+        (action = 'suspension' AND duration_seconds > 0) OR (action <> 'suspension' AND duration_seconds IS NULL)
+    )
+);
+
+INSERT INTO ban_escalation_steps (reason_code, step, action, duration_seconds) VALUES
+    ('spam', 1, 'warning', NULL),
+    ('spam', 2, 'suspension', 86400),
+    ('spam', 3, 'suspension', 604800),
+    ('spam', 4, 'permanent', NULL),
+    ('harassment', 1, 'suspension', 86400),
+    ('harassment', 2, 'suspension', 604800),
+    ('harassment', 3, 'permanent', NULL),
+    ('fake_profile', 1, 'suspension', 604800),
+    ('fake_profile', 2, 'permanent', NULL)
+ON CONFLICT (reason_code, step) DO NOTHING;
+
+-- Warnings are stored as bans that are closed on creation, so they show in
+-- the user's history without blocking the account.
+ALTER TABLE blockeds
+    ADD COLUMN IF NOT EXISTS enforcement     VARCHAR(16) NOT NULL DEFAULT 'suspension',
+    ADD COLUMN IF NOT EXISTS policy_step     INT,
+    ADD COLUMN IF NOT EXISTS policy_override BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS override_reason TEXT;
+
+UPDATE blockeds SET enforcement = 'permanent' WHERE expires_at IS NULL AND enforcement = 'suspension';
+
+CREATE INDEX IF NOT EXISTS blockeds_user_reason_idx ON blockeds (user_id, reason_code);
@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS ban_appeals (
+    id                BIGSERIAL PRIMARY KEY,
+    blocked_id        BIGINT NOT NULL REFERENCES blockeds (id),
+    user_id           BIGINT NOT NULL,
+    status            VARCHAR(16) NOT NULL DEFAULT 'open',
+    statement         TEXT NOT NULL,
+    -- user when relayed from the app through an API key, admin when filed
+    -- by a moderator on the user's behalf.
+    submitted_via     VARCHAR(16) NOT NULL,
+    submitted_by      BIGINT REFERENCES admin_users (id),
+    submitted_api_key BIGINT REFERENCES admin_api_keys (id),
+    reviewer_id       BIGINT REFERENCES admin_users (id),
+    decided_by        BIGINT REFERENCES admin_users (id),
+    decision_note     TEXT,
+    decided_at        TIMESTAMPTZ,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT ban_appeals_status_check CHECK (status IN ('open', 'under_review', 'upheld', 'overturned')),
+    CONSTRAINT ban_appeals_submitted_via_check CHECK (submitted_via IN ('user', 'admin'))
+);
+
+-- A ban can only have one appeal in progress at a time.
+CREATE UNIQUE INDEX IF NOT EXISTS ban_appeals_pending_idx ON ban_appeals (blocked_id)
+    WHERE status IN ('open', 'under_review');
+CREATE INDEX IF NOT EXISTS ban_appeals_status_idx ON ban_appeals (status, created_at);
+CREATE INDEX IF NOT EXISTS ban_appeals_user_idx ON ban_appeals (user_id);
+
+CREATE TABLE IF NOT EXISTS ban_appeal_comments (
+    id          BIGSERIAL PRIMARY KEY,
+    appeal_id   BIGINT NOT NULL REFERENCES ban_appeals (id),
+    author_type VARCHAR(16) NOT NULL,
+    admin_id    BIGINT REFERENCES admin_users (id),
+    body        TEXT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT ban_appeal_comments_author_type_check CHECK (author_type IN ('user', 'admin'))
+);
+
+CREATE INDEX IF NOT EXISTS ban_appeal_comments_appeal_idx ON ban_appeal_comments (appeal_id, created_at);
@@ -0,0 +1,32 @@
+-- Reports users file against each other in the app, relayed here by the app
+-- backend for moderation.
+CREATE TABLE IF NOT EXISTS user_reports (
+    id               BIGSERIAL PRIMARY KEY,
+    reporter_id      BIGINT NOT NULL REFERENCES users (id),
+    reported_user_id BIGINT NOT NULL REFERENCES users (id),
+    category         VARCHAR(32) NOT NULL,
+    details          TEXT NOT NULL DEFAULT '',
+    status           VARCHAR(16) NOT NULL DEFAULT 'open',
+    reviewer_id      BIGINT REFERENCES admin_users (id),
+    resolved_by      BIGINT REFERENCES admin_users (id),
+    resolution_note  TEXT,
+    blocked_id       BIGINT REFERENCES blockeds (id),
+    resolved_at      TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT user_reports_status_check CHECK (status IN ('open', 'in_review', 'actioned', 'dismissed')),
+    CONSTRAINT user_reports_category_check CHECK (category IN (
+        'spam', 'harassment', 'fake_profile', 'scam', 'inappropriate_content', 'underage', 'other'
+    )),
+    CONSTRAINT user_reports_self_check CHECK (reporter_id <> reported_user_id)
+);
+
+CREATE INDEX IF NOT EXISTS user_reports_pending_idx ON user_reports (reported_user_id)
+    WHERE status IN ('open', 'in_review');
+CREATE INDEX IF NOT EXISTS user_reports_reporter_idx ON user_reports (reporter_id, status);
+
+CREATE TABLE IF NOT EXISTS user_report_images (
+    report_id BIGINT NOT NULL REFERENCES user_reports (id),
+    image_id  BIGINT NOT NULL REFERENCES images (id),
+    PRIMARY KEY (report_id, image_id)
+);
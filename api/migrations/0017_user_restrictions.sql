@@ -0,0 +1,28 @@
+-- The app hides shadow-banned users from discovery and swiping without
+-- telling them.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS shadow_banned BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Restrictions are enforcement short of a ban. Each one overrides a users
+-- flag (shadow_banned, inbox_locked or swiper_mode) until it ends; the flag
+-- is restored to previous_value once no restriction of that mode is left.
+CREATE TABLE IF NOT EXISTS user_restrictions (
+    id             BIGSERIAL PRIMARY KEY,
+    user_id        BIGINT NOT NULL REFERENCES users (id),
+    mode           VARCHAR(32) NOT NULL,
+    reason_code    VARCHAR(64) REFERENCES ban_reason_codes (code),
+    reason         TEXT NOT NULL DEFAULT '',
+    previous_value BOOLEAN NOT NULL,
+    created_by     BIGINT REFERENCES admin_users (id),
+    expires_at     TIMESTAMPTZ,
+    lifted_at      TIMESTAMPTZ,
+    lifted_by      BIGINT REFERENCES admin_users (id),
+    lift_reason    TEXT,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT user_restrictions_mode_check CHECK (mode IN ('shadow_ban', 'inbox_lock', 'swiper_restriction'))
+);
+
+CREATE INDEX IF NOT EXISTS user_restrictions_user_idx ON user_restrictions (user_id, mode)
+    WHERE lifted_at IS NULL;
+CREATE INDEX IF NOT EXISTS user_restrictions_expiry_idx ON user_restrictions (expires_at)
+    WHERE lifted_at IS NULL AND expires_at IS NOT NULL;
@@ -0,0 +1,31 @@
+-- Long-running moderation work, such as bulk bans, runs as a job the admin
+-- tool polls for progress. Each job keeps one outcome row per item.
+CREATE TABLE IF NOT EXISTS moderation_jobs (
+    id          BIGSERIAL PRIMARY KEY,
+    kind        VARCHAR(32) NOT NULL,
+    status      VARCHAR(16) NOT NULL DEFAULT 'queued',
+    params      JSON NOT NULL,
+    total       INT NOT NULL,
+    processed   INT NOT NULL DEFAULT 0,
+    error       TEXT,
+    created_by  BIGINT REFERENCES admin_users (id),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at  TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+    CONSTRAINT moderation_jobs_status_check CHECK (status IN ('queued', 'running', 'completed', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS moderation_jobs_created_idx ON moderation_jobs (kind, created_at);
+
+CREATE TABLE IF NOT EXISTS moderation_job_items (
+    job_id    BIGINT NOT NULL REFERENCES moderation_jobs (id),
+    position  INT NOT NULL,
+    target    TEXT NOT NULL,
+    user_id   BIGINT,
+    status    VARCHAR(16) NOT NULL,
+    error     TEXT,
+    -- The ban or restriction the item produced or found already in force.
+    record_id BIGINT,
+    PRIMARY KEY (job_id, position),
+    CONSTRAINT moderation_job_items_status_check CHECK (status IN ('applied', 'already_blocked', 'not_found', 'failed'))
+);
@@ -0,0 +1,14 @@
+-- Ban list imports reuse moderation jobs. An upload is validated into a
+-- previewed job whose items are the file's rows; confirming it applies the
+-- ready rows and records the outcome on the same items.
+ALTER TABLE moderation_jobs DROP CONSTRAINT IF EXISTS moderation_jobs_status_check;
+ALTER TABLE moderation_jobs ADD CONSTRAINT moderation_jobs_status_check
+    CHECK (status IN ('previewed', 'queued', 'running', 'completed', 'failed'));
+
+ALTER TABLE moderation_jobs ADD COLUMN IF NOT EXISTS confirmed_by BIGINT REFERENCES admin_users (id);
+
+ALTER TABLE moderation_job_items ADD COLUMN IF NOT EXISTS notes TEXT;
+
+ALTER TABLE moderation_job_items DROP CONSTRAINT IF EXISTS moderation_job_items_status_check;
+ALTER TABLE moderation_job_items ADD CONSTRAINT moderation_job_items_status_check
+    CHECK (status IN ('ready', 'rejected', 'applied', 'already_blocked', 'not_found', 'failed'));
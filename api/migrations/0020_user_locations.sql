@@ -0,0 +1,6 @@
+-- Geo searches on users match city_lat/city_lng. A GiST index over the
+-- built-in point type answers the bounding boxes that radius and box
+-- searches start from without needing PostGIS; the exact haversine
+-- distance is then only worked out for the rows inside them. Queries must
+-- use the same point(city_lng, city_lat) expression for it to apply.
+CREATE INDEX IF NOT EXISTS users_city_location_idx ON users USING gist (point(city_lng, city_lat));
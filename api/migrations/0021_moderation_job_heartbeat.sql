@@ -0,0 +1,9 @@
+-- Jobs run in the process that started them. heartbeat_at is bumped as a
+-- job makes progress; a queued or running job whose heartbeat has gone
+-- stale was left behind by a process that stopped, and any replica may
+-- claim and resume it. A NULL heartbeat marks a job released on shutdown.
+ALTER TABLE moderation_jobs
+    ADD COLUMN IF NOT EXISTS heartbeat_at TIMESTAMPTZ DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS moderation_jobs_unfinished_idx ON moderation_jobs (heartbeat_at)
+    WHERE status IN ('queued', 'running');